@@ -0,0 +1,288 @@
+// Package client calls the json API of Smart Docs, described by the OpenAPI document served at /api/v1/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// Client calls a Smart Docs server. Annotations and status changes are made by the user owning ApiKey, they are
+// anonymous with the shared key of the server.
+type Client struct {
+	BaseUrl    string
+	ApiKey     string
+	HttpClient *http.Client
+}
+
+// New returns a client of the server at baseUrl, such as "http://localhost:8080". The API key may be empty when the
+// server has none.
+func New(baseUrl string, apiKey string) *Client {
+	return &Client{
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		ApiKey:     apiKey,
+		HttpClient: http.DefaultClient,
+	}
+}
+
+// ListDocuments lists documents, the most recently uploaded first.
+func (c *Client) ListDocuments(ctx context.Context, options ListOptions) (DocumentList, error) {
+	query := url.Values{}
+	setQuery(query, "status", options.Status)
+	setQuery(query, "mode", options.Mode)
+	setQuery(query, "project", options.Project)
+	if !options.From.IsZero() {
+		query.Set("from", options.From.Format(time.RFC3339))
+	}
+	if !options.To.IsZero() {
+		query.Set("to", options.To.Format(time.RFC3339))
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Offset > 0 {
+		query.Set("offset", strconv.Itoa(options.Offset))
+	}
+	var list DocumentList
+	err := c.call(ctx, http.MethodGet, "/documents", query, nil, &list)
+	return list, err
+}
+
+// UploadDocument uploads a pdf. It is processed in the background, WaitForDocument returns once it is done.
+func (c *Client) UploadDocument(ctx context.Context, name string, pdf io.Reader, options UploadOptions) (Document, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := map[string]string{"project": options.Project, "mode": options.Mode}
+	if options.Ocr {
+		fields["ocr"] = "on"
+	}
+	for field, value := range fields {
+		if value == "" {
+			continue
+		}
+		err := form.WriteField(field, value)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+	file, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return Document{}, err
+	}
+	_, err = io.Copy(file, pdf)
+	if err != nil {
+		return Document{}, err
+	}
+	err = form.Close()
+	if err != nil {
+		return Document{}, err
+	}
+
+	request, err := c.newRequest(ctx, http.MethodPost, "/documents", nil, &body)
+	if err != nil {
+		return Document{}, err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	var doc Document
+	err = c.send(request, &doc)
+	return doc, err
+}
+
+func (c *Client) GetDocument(ctx context.Context, docId int64) (Document, error) {
+	var doc Document
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/documents/%d", docId), nil, nil, &doc)
+	return doc, err
+}
+
+// WaitForDocument polls a document every interval until it is no longer processing, or the context is done.
+func (c *Client) WaitForDocument(ctx context.Context, docId int64, interval time.Duration) (Document, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		doc, err := c.GetDocument(ctx, docId)
+		if err != nil || doc.Status != DocumentProcessing {
+			return doc, err
+		}
+		select {
+		case <-ctx.Done():
+			return doc, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) DeleteDocument(ctx context.Context, docId int64) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("/documents/%d", docId), nil, nil, nil)
+}
+
+// RetryDocument detects again the pages of a document which are not reviewed or validated yet.
+func (c *Client) RetryDocument(ctx context.Context, docId int64) (Document, error) {
+	var doc Document
+	err := c.call(ctx, http.MethodPost, fmt.Sprintf("/documents/%d/retry", docId), nil, nil, &doc)
+	return doc, err
+}
+
+func (c *Client) ListPages(ctx context.Context, docId int64) ([]Page, error) {
+	var pages []Page
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/documents/%d/pages", docId), nil, nil, &pages)
+	return pages, err
+}
+
+// GetPage returns a page with its rendered html and markdown.
+func (c *Client) GetPage(ctx context.Context, docId int64, pageNum int) (Page, error) {
+	var page Page
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/documents/%d/pages/%d", docId, pageNum), nil, nil, &page)
+	return page, err
+}
+
+func (c *Client) GetPredictions(ctx context.Context, docId int64, pageNum int) ([]Prediction, error) {
+	var predictions []Prediction
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/documents/%d/pages/%d/predictions", docId, pageNum), nil, nil, &predictions)
+	return predictions, err
+}
+
+// SetPredictions replaces the annotations of a page. With fix, boxes are normalised and clamped to the page before
+// they are validated. Rejected annotations are returned as an *Error listing their problems.
+func (c *Client) SetPredictions(ctx context.Context, docId int64, pageNum int, predictions []Prediction, fix bool) ([]Prediction, error) {
+	query := url.Values{}
+	if fix {
+		query.Set("fix", "true")
+	}
+	var stored []Prediction
+	err := c.call(ctx, http.MethodPut, fmt.Sprintf("/documents/%d/pages/%d/predictions", docId, pageNum), query, predictions, &stored)
+	return stored, err
+}
+
+// ChangeStatus moves a page to another status of the review workflow, rejecting or reopening a page needs a reason.
+func (c *Client) ChangeStatus(ctx context.Context, docId int64, pageNum int, status string, reason string) (Page, error) {
+	change := struct {
+		Status string `json:"status"`
+		Reason string `json:"reason,omitempty"`
+	}{Status: status, Reason: reason}
+	var page Page
+	err := c.call(ctx, http.MethodPost, fmt.Sprintf("/documents/%d/pages/%d/status", docId, pageNum), nil, change, &page)
+	return page, err
+}
+
+func (c *Client) ListTransitions(ctx context.Context, docId int64, pageNum int) ([]StatusChange, error) {
+	var transitions []StatusChange
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/documents/%d/pages/%d/transitions", docId, pageNum), nil, nil, &transitions)
+	return transitions, err
+}
+
+// ExportDocument downloads a document as "json", "markdown", "html", "tables", "csv" or "bundle". The caller closes
+// the export.
+func (c *Client) ExportDocument(ctx context.Context, docId int64, format string) (io.ReadCloser, error) {
+	return c.download(ctx, fmt.Sprintf("/documents/%d/export/%s", docId, url.PathEscape(format)), nil)
+}
+
+// ExportDataset downloads the "coco", "yolo", "ocr" or "entities" dataset of a project as a zip archive. The caller
+// closes the archive.
+func (c *Client) ExportDataset(ctx context.Context, format string, options DatasetOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	setQuery(query, "project", options.Project)
+	if options.Train > 0 {
+		query.Set("train", strconv.Itoa(options.Train))
+	}
+	if options.Val > 0 {
+		query.Set("val", strconv.Itoa(options.Val))
+	}
+	if options.Tables {
+		query.Set("dataset", "tables")
+	}
+	return c.download(ctx, "/exports/"+url.PathEscape(format), query)
+}
+
+// call sends a request with an optional json body and decodes the json response into result, when it is not nil.
+func (c *Client) call(ctx context.Context, method string, path string, query url.Values, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := c.newRequest(ctx, method, path, query, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(request, result)
+}
+
+func (c *Client) download(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+		return nil, readError(response)
+	}
+	return response.Body, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := c.BaseUrl + apiPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.ApiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.ApiKey)
+	}
+	return request, nil
+}
+
+func (c *Client) send(request *http.Request, result any) error {
+	response, err := c.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return readError(response)
+	}
+	if result == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// readError reads the error body of the api, or keeps the start of the body when the server answered otherwise.
+func readError(response *http.Response) error {
+	content, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	apiErr := &Error{}
+	if json.Unmarshal(content, apiErr) != nil || apiErr.Message == "" {
+		apiErr = &Error{Message: strings.TrimSpace(string(content))}
+	}
+	apiErr.Status = response.StatusCode
+	return apiErr
+}
+
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server answers like the api would, checking the key of the client.
+func server(t *testing.T, routes map[string]http.HandlerFunc) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"status":401,"error":"a valid API key is required"}`))
+				return
+			}
+			handler(w, r)
+		})
+	}
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return New(s.URL+"/", "key")
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestUploadDocument(t *testing.T) {
+	c := server(t, map[string]http.HandlerFunc{
+		"POST /api/v1/documents": func(w http.ResponseWriter, r *http.Request) {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			content, _ := io.ReadAll(file)
+			if header.Filename != "invoice.pdf" || string(content) != "%PDF" || r.FormValue("project") != "invoices" || r.FormValue("ocr") != "on" {
+				http.Error(w, "unexpected upload", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Location", "/api/v1/documents/7")
+			writeJson(w, http.StatusCreated, Document{Id: 7, Name: header.Filename, Status: DocumentProcessing})
+		},
+	})
+	doc, err := c.UploadDocument(context.Background(), "invoice.pdf", strings.NewReader("%PDF"), UploadOptions{Project: "invoices", Ocr: true})
+	if err != nil {
+		t.Fatalf("UploadDocument() error = %v", err)
+	}
+	if doc.Id != 7 || doc.Name != "invoice.pdf" || doc.Status != DocumentProcessing {
+		t.Errorf("UploadDocument() = %+v", doc)
+	}
+}
+
+func TestWaitForDocument(t *testing.T) {
+	var polls atomic.Int32
+	c := server(t, map[string]http.HandlerFunc{
+		"GET /api/v1/documents/7": func(w http.ResponseWriter, r *http.Request) {
+			status := DocumentProcessing
+			if polls.Add(1) >= 3 {
+				status = DocumentDone
+			}
+			writeJson(w, http.StatusOK, Document{Id: 7, Status: status})
+		},
+	})
+	doc, err := c.WaitForDocument(context.Background(), 7, time.Millisecond)
+	if err != nil || doc.Status != DocumentDone || polls.Load() != 3 {
+		t.Errorf("WaitForDocument() = %+v, %v after %d polls, want %s after 3", doc, err, polls.Load(), DocumentDone)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	polls.Store(-1000)
+	if _, err := c.WaitForDocument(ctx, 7, time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForDocument() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSetPredictionsProblems(t *testing.T) {
+	c := server(t, map[string]http.HandlerFunc{
+		"PUT /api/v1/documents/7/pages/0/predictions": func(w http.ResponseWriter, r *http.Request) {
+			var predictions []Prediction
+			if err := json.NewDecoder(r.Body).Decode(&predictions); err != nil || len(predictions) != 1 || r.URL.Query().Get("fix") != "" {
+				http.Error(w, "unexpected predictions", http.StatusBadRequest)
+				return
+			}
+			cell := 0
+			writeJson(w, http.StatusUnprocessableEntity, Error{
+				Status:  http.StatusUnprocessableEntity,
+				Message: "invalid annotations",
+				Problems: []Problem{
+					{Box: 0, Field: "label", Message: `unknown label "figure"`},
+					{Box: 0, Cell: &cell, Field: "x1", Message: "x1 60.0 is outside of 0-50"},
+				},
+			})
+		},
+	})
+	_, err := c.SetPredictions(context.Background(), 7, 0, []Prediction{{X1: 10, Y1: 10, Label: "figure"}}, false)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("SetPredictions() error = %v, want an *Error", err)
+	}
+	if apiErr.Status != http.StatusUnprocessableEntity || apiErr.Message != "invalid annotations" {
+		t.Errorf("SetPredictions() error = %d %q", apiErr.Status, apiErr.Message)
+	}
+	fields := []string{}
+	for _, problem := range apiErr.Problems {
+		fields = append(fields, problem.Field)
+	}
+	if !slices.Equal(fields, []string{"label", "x1"}) || apiErr.Problems[1].Cell == nil || *apiErr.Problems[1].Cell != 0 {
+		t.Errorf("SetPredictions() problems = %+v", apiErr.Problems)
+	}
+}
+
+func TestExportDocument(t *testing.T) {
+	c := server(t, map[string]http.HandlerFunc{
+		"GET /api/v1/documents/7/export/{format}": func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("format") != "markdown" {
+				writeJson(w, http.StatusBadRequest, Error{Status: http.StatusBadRequest, Message: "unknown format"})
+				return
+			}
+			w.Header().Set("Content-Type", "text/markdown")
+			_, _ = w.Write([]byte("# Invoice\n"))
+		},
+	})
+	export, err := c.ExportDocument(context.Background(), 7, "markdown")
+	if err != nil {
+		t.Fatalf("ExportDocument() error = %v", err)
+	}
+	defer export.Close()
+	if content, _ := io.ReadAll(export); string(content) != "# Invoice\n" {
+		t.Errorf("ExportDocument() = %q", content)
+	}
+
+	_, err = c.ExportDocument(context.Background(), 7, "docx")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Message != "unknown format" {
+		t.Errorf("ExportDocument() error = %v, want 400 unknown format", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	c := server(t, map[string]http.HandlerFunc{
+		"GET /api/v1/documents/7": func(w http.ResponseWriter, r *http.Request) {
+			writeJson(w, http.StatusOK, Document{Id: 7})
+		},
+	})
+	c.ApiKey = "wrong"
+	_, err := c.GetDocument(context.Background(), 7)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("GetDocument() error = %v, want 401", err)
+	}
+}
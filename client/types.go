@@ -0,0 +1,141 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Statuses of documents.
+const (
+	DocumentProcessing = "PROCESSING"
+	DocumentDone       = "DONE"
+	DocumentFailed     = "FAILED"
+)
+
+// Statuses of pages in the review workflow.
+const (
+	PagePrediction = "PREDICTION"
+	PageTraining   = "TRAINING"
+	PageReview     = "REVIEW"
+	PageValidation = "VALIDATION"
+)
+
+type Document struct {
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Mode       string    `json:"mode"`
+	Project    string    `json:"project"`
+	UploadDate time.Time `json:"uploadDate"`
+	PageCount  int       `json:"pageCount"`
+	// InProgress pages are in annotation or review
+	InProgress int `json:"inProgress"`
+	Validated  int `json:"validated"`
+}
+
+type DocumentList struct {
+	Documents []Document `json:"documents"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+// Page is a page of a document. Html and Markdown are only filled when a single page is fetched.
+type Page struct {
+	DocumentId  int64        `json:"documentId"`
+	PageNum     int          `json:"pageNum"`
+	Status      string       `json:"status"`
+	Width       int          `json:"width"`
+	Height      int          `json:"height"`
+	Html        string       `json:"html,omitempty"`
+	Markdown    string       `json:"markdown,omitempty"`
+	Transitions []Transition `json:"transitions,omitempty"`
+}
+
+// Transition is a status change available to the user.
+type Transition struct {
+	To             string `json:"to"`
+	Name           string `json:"name"`
+	RequiresReason bool   `json:"requiresReason"`
+	Shortcut       string `json:"shortcut,omitempty"`
+}
+
+// StatusChange is a recorded move of a page between statuses.
+type StatusChange struct {
+	Id         int64     `json:"id"`
+	DocumentId int64     `json:"documentId"`
+	PageNum    int       `json:"pageNum"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	User       string    `json:"user"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Prediction is an annotated box of a page. Cells of tables are relative to the table box, and Grid keeps the
+// structure of a table edited in the application; send it back unchanged.
+type Prediction struct {
+	X0    float32         `json:"x0"`
+	X1    float32         `json:"x1"`
+	Y0    float32         `json:"y0"`
+	Y1    float32         `json:"y1"`
+	Score float32         `json:"score"`
+	Label string          `json:"label"`
+	Table []Prediction    `json:"table"`
+	Grid  json.RawMessage `json:"grid,omitempty"`
+}
+
+// Problem is a reason annotations were rejected, by box and cell index.
+type Problem struct {
+	Box     int    `json:"box"`
+	Cell    *int   `json:"cell,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is returned for requests the server did not accept.
+type Error struct {
+	Status   int       `json:"status"`
+	Message  string    `json:"error"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("smart docs: %d %s", e.Status, e.Message)
+	for _, problem := range e.Problems {
+		message += fmt.Sprintf("; box %d", problem.Box)
+		if problem.Cell != nil {
+			message += fmt.Sprintf(" cell %d", *problem.Cell)
+		}
+		message += ": " + problem.Message
+	}
+	return message
+}
+
+// ListOptions filter a document listing, zero values match every document.
+type ListOptions struct {
+	Status  string
+	Mode    string
+	Project string
+	// Documents uploaded from this time on and before To
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// UploadOptions are the settings of an uploaded document, the default project is used when none is set.
+type UploadOptions struct {
+	Project string
+	Mode    string
+	Ocr     bool
+}
+
+// DatasetOptions choose the project of a dataset export, and the splits and kind of a YOLO dataset.
+type DatasetOptions struct {
+	Project string
+	// Percentages of pages used for training and validation, the server defaults are used when zero
+	Train  int
+	Val    int
+	Tables bool
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Events sent to webhooks.
+const (
+	EventDocumentProcessed = "document.processed"
+	EventDocumentFailed    = "document.failed"
+	EventPageValidated     = "page.validated"
+	EventAnnotationChanged = "annotation.changed"
+)
+
+// Headers of webhook deliveries.
+const (
+	EventHeader     = "X-Smart-Docs-Event"
+	DeliveryHeader  = "X-Smart-Docs-Delivery"
+	TimestampHeader = "X-Smart-Docs-Timestamp"
+	SignatureHeader = "X-Smart-Docs-Signature"
+)
+
+// Webhook is an endpoint notified of events. Secret is only returned when the webhook is created.
+type Webhook struct {
+	Id        int64     `json:"id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Delivery is an event sent to a webhook, with the response to its last attempt.
+type Delivery struct {
+	Id             int64           `json:"id"`
+	WebhookId      int64           `json:"webhookId"`
+	Url            string          `json:"url"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// WebhookEvent is the body posted to webhooks, Data holds the fields of the event.
+type WebhookEvent struct {
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := c.call(ctx, http.MethodGet, "/webhooks", nil, nil, &webhooks)
+	return webhooks, err
+}
+
+// CreateWebhook registers a webhook for events. The server generates a secret when it is empty, it is only returned
+// here.
+func (c *Client) CreateWebhook(ctx context.Context, webhookUrl string, events []string, secret string) (Webhook, error) {
+	var webhook Webhook
+	err := c.call(ctx, http.MethodPost, "/webhooks", nil, Webhook{Url: webhookUrl, Events: events, Secret: secret}, &webhook)
+	return webhook, err
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId int64) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhookId), nil, nil, nil)
+}
+
+// ListDeliveries returns the latest deliveries of a webhook, the newest first. The server default is used when limit
+// is zero.
+func (c *Client) ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]Delivery, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var deliveries []Delivery
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries", webhookId), query, nil, &deliveries)
+	return deliveries, err
+}
+
+// Redeliver sends the payload of a delivery again, as a new delivery.
+func (c *Client) Redeliver(ctx context.Context, webhookId int64, deliveryId int64) (Delivery, error) {
+	var delivery Delivery
+	err := c.call(ctx, http.MethodPost, fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", webhookId, deliveryId), nil, nil, &delivery)
+	return delivery, err
+}
+
+// VerifySignature checks the signature of a delivery received by a webhook, given the values of its timestamp and
+// signature headers. Deliveries sent longer than tolerance ago are rejected, a zero tolerance accepts any age.
+func VerifySignature(secret string, timestamp string, signature string, body []byte, tolerance time.Duration) bool {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance > 0 && time.Since(time.Unix(sent, 0)).Abs() > tolerance {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
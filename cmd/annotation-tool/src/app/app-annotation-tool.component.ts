@@ -1,5 +1,5 @@
 import {ChangeDetectorRef, Component, ElementRef, HostListener, inject, OnInit, ViewChild} from '@angular/core';
-import {HttpClient} from "@angular/common/http";
+import {HttpClient, HttpErrorResponse} from "@angular/common/http";
 import {Subject, takeUntil} from "rxjs";
 import {AnnotationBox, AppAnnotationComponent, Handle} from "./app-annotation.component";
 
@@ -17,6 +17,33 @@ export interface Annotation {
     table: Annotation[]
 }
 
+export interface Label {
+    name: string,
+    color: string,
+    parents: string[],
+    renderer: string
+}
+
+export interface ValidationProblem {
+    box: number,
+    cell?: number,
+    field?: string,
+    message: string
+}
+
+export interface LabelSchema {
+    project: string,
+    labels: Label[]
+}
+
+const PAGE_PARENT = 'page';
+const RENDERER_ICONS: { [renderer: string]: string } = {
+    paragraph: '/assets/icons/editor/paragraph.svg',
+    heading: '/assets/icons/editor/heading.svg',
+    table: '/assets/icons/editor/table.svg',
+    image: '/assets/icons/editor/image.svg',
+}
+
 const MIN_SIZE = 5;
 
 @Component({
@@ -55,6 +82,7 @@ const MIN_SIZE = 5;
                                    [sizeLimitX]="selectedTable.x1 - selectedTable.x0"
                                    [sizeLimitY]="selectedTable.y1 - selectedTable.y0"
                                    [segment]="segment"
+                                   [color]="colorOf(segment.label)"
                                    [rootEl]="rootEl.nativeElement"
                                    [viewPortEl]="viewportEl.nativeElement"
                                    (mouseover)="highlightedSegment = segment"
@@ -73,6 +101,8 @@ const MIN_SIZE = 5;
                             <svg:g app-annotation [id]="index"
                                    [attr.id]="'el-' + index"
                                    [segment]="segment"
+                                   [color]="colorOf(segment.label)"
+                                   [isTable]="isTable(segment)"
                                    [rootEl]="rootEl.nativeElement"
                                    [viewPortEl]="viewportEl.nativeElement"
                                    (rightClicked)="delete(segment)"
@@ -127,38 +157,22 @@ const MIN_SIZE = 5;
                 
             } @else {
                 <div class="btn-row">
-                    <button [class.active]="activeTool == 'DRAW_P'" appTooltip="Draw Paragraph" (click)="activeTool ? activeTool = undefined : activeTool = 'DRAW_P'">
-                        <img src="/assets/icons/editor/paragraph.svg" alt="Draw Paragraph">
-                        @if (activeTool && activeTool == 'DRAW_P') {
-                            <span>(ESC)</span>
-                        } @else {
-                            <span>(1)</span>
-                        }
-                    </button>
-                    <button [class.active]="activeTool == 'DRAW_HEADER'" appTooltip="Draw Header" (click)="activeTool ? activeTool = undefined : activeTool = 'DRAW_HEADER'">
-                        <img src="/assets/icons/editor/heading.svg" alt="Draw Header">
-                        @if (activeTool && activeTool == 'DRAW_HEADER') {
-                            <span>(ESC)</span>
-                        } @else {
-                            <span>(2)</span>
-                        }
-                    </button>
-                    <button [class.active]="activeTool == 'DRAW_TABLE'" appTooltip="Draw Table" (click)="activeTool ? activeTool = undefined : activeTool = 'DRAW_TABLE'">
-                        <img src="/assets/icons/editor/table.svg" alt="Draw Table">
-                        @if (activeTool && activeTool == 'DRAW_TABLE') {
-                            <span>(ESC)</span>
-                        } @else {
-                            <span>(3)</span>
-                        }
-                    </button>
-                    <button [class.active]="activeTool == 'DRAW_IMAGE'" appTooltip="Draw Image" (click)="activeTool ? activeTool = undefined : activeTool = 'DRAW_IMAGE'">
-                        <img src="/assets/icons/editor/image.svg" alt="Draw Image">
-                        @if (activeTool && activeTool == 'DRAW_IMAGE') {
-                            <span>(ESC)</span>
-                        } @else {
-                            <span>(4)</span>
-                        }
-                    </button>
+                    @for (label of pageLabels; track label.name; let index = $index) {
+                        <button [class.active]="activeTool == 'DRAW' && activeLabel == label.name"
+                                [appTooltip]="'Draw ' + label.name"
+                                (click)="toggleDrawTool(label.name)">
+                            @if (iconOf(label)) {
+                                <img [src]="iconOf(label)" [alt]="'Draw ' + label.name">
+                            } @else {
+                                <span class="label-swatch" [style.background-color]="label.color">{{ label.name }}</span>
+                            }
+                            @if (activeTool == 'DRAW' && activeLabel == label.name) {
+                                <span>(ESC)</span>
+                            } @else if (index < 9) {
+                                <span>({{ index + 1 }})</span>
+                            }
+                        </button>
+                    }
                 </div>
             }
         }
@@ -217,6 +231,14 @@ const MIN_SIZE = 5;
             width: auto;
         }
         
+        .btn-row button span.label-swatch {
+            position: static;
+            padding: 2px 4px;
+            border-radius: 2px;
+            font-size: 11px;
+            color: #fff;
+        }
+
         .btn-row button span {
             position: absolute;
             top: 0;
@@ -236,6 +258,8 @@ export class AppAnnotationToolComponent implements OnInit {
     pageNumber: string = '';
     imageUrl?: string
     annotations: Annotation[] = [];
+    labels: Label[] = [];
+    activeLabel?: string
     history: Annotation[][] = []
     selectedTable?:Annotation
 
@@ -251,7 +275,7 @@ export class AppAnnotationToolComponent implements OnInit {
     private _rect?: SVGRectElement;
     private _line?: SVGLineElement;
 
-    private _activeTool?: ('DRAW_P' | 'DRAW_HEADER' | 'DRAW_TABLE' | 'DRAW_IMAGE' | 'SPLIT_ROWS' | 'SPLIT_COLS' | 'MERGE')
+    private _activeTool?: ('DRAW' | 'SPLIT_ROWS' | 'SPLIT_COLS' | 'MERGE')
     private _drawStartPoint: { x: number; y: number } = {x: 0, y: 0};
     private _onDestroy$ = new Subject<void>();
     private _shiftPressed: boolean = false;
@@ -310,19 +334,12 @@ export class AppAnnotationToolComponent implements OnInit {
                 this.activeTool = 'MERGE'
             }
         } else {
-            // Handling element drawing keys
-            if (event.key === '1') {
+            // Handling element drawing keys, labels of the schema are bound to 1-9 in order
+            const label = this.pageLabels[+event.key - 1]
+            if (label) {
                 event.stopImmediatePropagation();
-                this.activeTool = 'DRAW_P'
-            } else  if (event.key === '2') {
-                event.stopImmediatePropagation();
-                this.activeTool = 'DRAW_HEADER'
-            } else if (event.key === '3') {
-                event.stopImmediatePropagation();
-                this.activeTool = 'DRAW_TABLE'
-            } else if (event.key === '4') {
-                event.stopImmediatePropagation();
-                this.activeTool = 'DRAW_IMAGE'
+                this.activeLabel = label.name
+                this.activeTool = 'DRAW'
             }
         }
     }
@@ -340,7 +357,8 @@ export class AppAnnotationToolComponent implements OnInit {
             })
             .map(a => {
                 if (a.table?.length > 0) {
-                    a.table = a.table.map(t => ({...t, label: "cell"}))
+                    const allowed = this.labelsIn(a.label).map(l => l.name)
+                    a.table = a.table.map(t => allowed.includes(t.label) ? t : {...t, label: this.cellLabel(a)})
                 }
                 return a
             })
@@ -366,10 +384,7 @@ export class AppAnnotationToolComponent implements OnInit {
             this._zoomable?.disablePan()
 
             switch (this._activeTool) {
-                case "DRAW_P":
-                case "DRAW_HEADER":
-                case "DRAW_TABLE":
-                case "DRAW_IMAGE":
+                case "DRAW":
                     this.rootEl.nativeElement.addEventListener('mousedown', this.drawRectToolStart, { passive: true });
                     this.rootEl.nativeElement.addEventListener('mousemove', this.drawRectToolMove, { passive: true });
                     this.rootEl.nativeElement.addEventListener('mouseup', this.drawRectToolEnd, { passive: true });
@@ -401,18 +416,37 @@ export class AppAnnotationToolComponent implements OnInit {
         return this._activeTool;
     }
 
-    get activeLabel() {
-        switch (this.activeTool) {
-            case "DRAW_P":
-                return "paragraph";
-            case "DRAW_TABLE":
-                return "table";
-            case "DRAW_HEADER":
-                return "header";
-            case "DRAW_IMAGE":
-                return "illustration";
-            default:
-                return "other"
+    get pageLabels() {
+        return this.labelsIn(PAGE_PARENT)
+    }
+
+    labelsIn(parent: string) {
+        return this.labels.filter(l => l.parents.includes(parent))
+    }
+
+    // cellLabel is the label given to new cells of a table, the first label the schema allows inside it
+    cellLabel(table: Annotation) {
+        return this.labelsIn(table.label)[0]?.name ?? 'cell'
+    }
+
+    colorOf(label: string) {
+        return this.labels.find(l => l.name === label)?.color ?? '#ff6600'
+    }
+
+    isTable(annotation: Annotation) {
+        return this.labels.find(l => l.name === annotation.label)?.renderer === 'table'
+    }
+
+    iconOf(label: Label) {
+        return RENDERER_ICONS[label.renderer]
+    }
+
+    toggleDrawTool(label: string) {
+        if (this.activeTool) {
+            this.activeTool = undefined
+        } else {
+            this.activeLabel = label
+            this.activeTool = 'DRAW'
         }
     }
 
@@ -426,6 +460,15 @@ export class AppAnnotationToolComponent implements OnInit {
     }
 
     ngOnInit() {
+        this.http
+            .get<LabelSchema>(`/document/${this.documentId}/labels`)
+            .pipe(
+                takeUntil(this._onDestroy$)
+            )
+            .subscribe(schema => {
+                this.labels = schema.labels
+                this._cd.markForCheck()
+            })
         this.http
             .get<Annotation[]>(`/document/${this.documentId}/${this.pageNumber}/predictions`)
             .pipe(
@@ -521,16 +564,13 @@ export class AppAnnotationToolComponent implements OnInit {
             const height = +this._rect.getAttribute("height")!
             if (width > MIN_SIZE && height > MIN_SIZE) {
                 switch (this.activeTool) {
-                    case "DRAW_P":
-                    case "DRAW_HEADER":
-                    case "DRAW_TABLE":
-                    case "DRAW_IMAGE":
+                    case "DRAW":
                         this.annotations.push({
                             x0: +this._rect.getAttribute("x")!,
                             y0: +this._rect.getAttribute("y")!,
                             x1: +this._rect.getAttribute("x")! + +this._rect.getAttribute("width")!,
                             y1: +this._rect.getAttribute("y")! + +this._rect.getAttribute("height")!,
-                            label: this.activeLabel,
+                            label: this.activeLabel!,
                             table: [],
                             score: 1.0
                         })
@@ -564,7 +604,7 @@ export class AppAnnotationToolComponent implements OnInit {
                             y0: minY,
                             x1: maxX,
                             y1: maxY,
-                            label: "cell",
+                            label: this.cellLabel(this.selectedTable!),
                             table: [],
                             score: 1.0
                         })
@@ -668,7 +708,7 @@ export class AppAnnotationToolComponent implements OnInit {
             y1: this.selectedTable!.y1 - this.selectedTable!.y0,
             table: [],
             score: 1.0,
-            label: 'cell'
+            label: this.cellLabel(this.selectedTable!)
         }]
         this._syncAnnotations()
     }
@@ -739,23 +779,33 @@ export class AppAnnotationToolComponent implements OnInit {
     private _syncAnnotations() {
         this.loading = true;
         this.http
-            .post<Annotation[]>(`/document/${this.documentId}/${this.pageNumber}/predictions`, this.cleanedAnnotations)
+            // Boxes dragged past the page edge are clamped by the server instead of rejected
+            .post<Annotation[]>(`/document/${this.documentId}/${this.pageNumber}/predictions?fix=true`, this.cleanedAnnotations)
             .pipe(
                 takeUntil(this._onDestroy$)
             )
-            .subscribe(annotations => {
-                this.annotations = annotations
-                this.history.push(this.annotations)
-                if (this.selectedTable) {
-                    // We need to lookup the table again since after updating annotations, it's different set of objects.
-                    this.selectedTable = this.annotations.find(a => a.x0 === this.selectedTable!.x0
-                        && a.x1 === this.selectedTable!.x1
-                        && a.y0 === this.selectedTable!.y0
-                        && a.y1 === this.selectedTable!.y1
-                    )
+            .subscribe({
+                next: annotations => {
+                    this.annotations = annotations
+                    this.history.push(this.annotations)
+                    if (this.selectedTable) {
+                        // We need to lookup the table again since after updating annotations, it's different set of objects.
+                        this.selectedTable = this.annotations.find(a => a.x0 === this.selectedTable!.x0
+                            && a.x1 === this.selectedTable!.x1
+                            && a.y0 === this.selectedTable!.y0
+                            && a.y1 === this.selectedTable!.y1
+                        )
+                    }
+                    this.loading = false;
+                    this._cd.markForCheck()
+                },
+                error: (response: HttpErrorResponse) => {
+                    const problems: ValidationProblem[] = response.error?.problems ?? []
+                    const details = problems.map(p => `box ${p.box}${p.cell !== undefined ? ` cell ${p.cell}` : ''}: ${p.message}`)
+                    alert(`Annotations were not saved:\n${details.length > 0 ? details.join('\n') : response.message}`)
+                    this.loading = false;
+                    this._cd.markForCheck()
                 }
-                this.loading = false;
-                this._cd.markForCheck()
             })
     }
 
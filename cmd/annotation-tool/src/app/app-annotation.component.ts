@@ -39,7 +39,7 @@ export enum Handle {
              [attr.height]="segment.y1 - segment.y0"
              (dblclick)="selectIfTable()">
 
-            @if (segment.label === 'table') {
+            @if (isTable) {
                 <g>
                     @for (cell of segment.table; track cell) {
                         <rect [attr.x]="cell.x0"
@@ -124,6 +124,9 @@ export class AppAnnotationComponent implements OnInit, AfterViewInit {
     @Input({alias: "rootEl", required: true}) root!: SVGSVGElement;
     @Input({alias: "viewPortEl", required: true}) viewport!: SVGGElement;
     @Input({required: true}) segment!:Annotation;
+    // color and isTable come from the label schema of the project
+    @Input() color = "#ff6600"
+    @Input() isTable = false
 
     @Output() tableSelected = new EventEmitter<MouseEvent>();
     @Output() rightClicked = new EventEmitter<void>();
@@ -157,20 +160,7 @@ export class AppAnnotationComponent implements OnInit, AfterViewInit {
     }
 
     get fill() {
-        // color scheme taken from: https://teenage.engineering/guides/od-11
-        switch (this.segment?.label) {
-            case "table":
-            case "cell":
-                return "#fbb03b"
-            case "header":
-                return "#c1272d"
-            case "paragraph":
-                return "#12446e"
-            case "illustration":
-                return "#ff6600" // TODO: Change
-            default:
-                return "#ff6600"
-        }
+        return this.color
     }
 
     resizeStart = (event:MouseEvent, handle:Handle) => {
@@ -264,7 +254,7 @@ export class AppAnnotationComponent implements OnInit, AfterViewInit {
     }
 
     selectIfTable() {
-        if (this.segment?.label === 'table') {
+        if (this.isTable) {
             console.log("Selecting table...")
             this.tableSelected.emit()
         }
@@ -0,0 +1,98 @@
+package agreement
+
+import (
+	"cmp"
+	"slices"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+// Variant is the box one annotator drew for an object.
+type Variant struct {
+	Annotator  string
+	Prediction models.Prediction
+}
+
+// Cluster groups the boxes annotators drew for the same object, at most one per annotator.
+// Choice is the variant kept by default: the majority label when most annotators drew the box, -1 to drop it otherwise.
+type Cluster struct {
+	Variants []Variant
+	Choice   int
+}
+
+// Clusters groups boxes of the annotation sets of a page by overlap, ordered top to bottom.
+func Clusters(sets []models.AnnotationSet) []Cluster {
+	var clusters []Cluster
+	for _, set := range sets {
+		for _, prediction := range set.Predictions {
+			best := -1
+			var bestIoU float32 = minIoU
+			for c, cluster := range clusters {
+				if hasAnnotator(cluster, set.Annotator) {
+					continue
+				}
+				if iou := pipeline.IoU(prediction.Rect, cluster.Variants[0].Prediction.Rect); iou >= bestIoU {
+					best = c
+					bestIoU = iou
+				}
+			}
+			variant := Variant{Annotator: set.Annotator, Prediction: prediction}
+			if best < 0 {
+				clusters = append(clusters, Cluster{Variants: []Variant{variant}})
+			} else {
+				clusters[best].Variants = append(clusters[best].Variants, variant)
+			}
+		}
+	}
+
+	for c := range clusters {
+		clusters[c].Choice = defaultChoice(clusters[c], len(sets))
+	}
+	slices.SortStableFunc(clusters, func(a, b Cluster) int {
+		return cmp.Or(
+			cmp.Compare(a.Variants[0].Prediction.Y0, b.Variants[0].Prediction.Y0),
+			cmp.Compare(a.Variants[0].Prediction.X0, b.Variants[0].Prediction.X0),
+		)
+	})
+	return clusters
+}
+
+// Merge builds the final annotation from the chosen variant of each cluster, a negative choice drops the cluster.
+func Merge(clusters []Cluster, choices []int) []models.Prediction {
+	predictions := []models.Prediction{}
+	for c, cluster := range clusters {
+		if c >= len(choices) || choices[c] < 0 || choices[c] >= len(cluster.Variants) {
+			continue
+		}
+		prediction := cluster.Variants[choices[c]].Prediction
+		prediction.Table = slices.Clone(prediction.Table)
+		predictions = append(predictions, prediction)
+	}
+	return predictions
+}
+
+func hasAnnotator(cluster Cluster, annotator string) bool {
+	for _, variant := range cluster.Variants {
+		if variant.Annotator == annotator {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultChoice(cluster Cluster, annotators int) int {
+	if 2*len(cluster.Variants) < annotators {
+		return -1
+	}
+	votes := map[string]int{}
+	for _, variant := range cluster.Variants {
+		votes[variant.Prediction.Label]++
+	}
+	choice := 0
+	for i, variant := range cluster.Variants {
+		if votes[variant.Prediction.Label] > votes[cluster.Variants[choice].Prediction.Label] {
+			choice = i
+		}
+	}
+	return choice
+}
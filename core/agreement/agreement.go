@@ -0,0 +1,149 @@
+package agreement
+
+import (
+	"cmp"
+	"slices"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+// Boxes of two annotators overlapping at least this much are considered the same box.
+const minIoU = 0.5
+
+type pair struct {
+	a   int
+	b   int
+	iou float32
+}
+
+// matchBoxes pairs boxes of two annotation sets by overlap, the best overlapping pairs first, regardless of labels.
+func matchBoxes(a []models.Prediction, b []models.Prediction) []pair {
+	var candidates []pair
+	for i := range a {
+		for j := range b {
+			if iou := pipeline.IoU(a[i].Rect, b[j].Rect); iou >= minIoU {
+				candidates = append(candidates, pair{a: i, b: j, iou: iou})
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(x, y pair) int {
+		return cmp.Compare(y.iou, x.iou)
+	})
+
+	usedA := make([]bool, len(a))
+	usedB := make([]bool, len(b))
+	var pairs []pair
+	for _, p := range candidates {
+		if usedA[p.a] || usedB[p.b] {
+			continue
+		}
+		usedA[p.a] = true
+		usedB[p.b] = true
+		pairs = append(pairs, p)
+	}
+	return pairs
+}
+
+// tally sums up comparisons of annotation sets so agreement can be reported for any group of them.
+type tally struct {
+	boxes     int
+	matched   int
+	iou       float64
+	sameLabel int
+	pairs     int
+}
+
+func (t *tally) compare(a []models.Prediction, b []models.Prediction) {
+	t.pairs++
+	t.boxes += len(a) + len(b)
+	for _, p := range matchBoxes(a, b) {
+		t.matched++
+		t.iou += float64(p.iou)
+		if a[p.a].Label == b[p.b].Label {
+			t.sameLabel++
+		}
+	}
+}
+
+func (t *tally) add(other tally) {
+	t.boxes += other.boxes
+	t.matched += other.matched
+	t.iou += other.iou
+	t.sameLabel += other.sameLabel
+	t.pairs += other.pairs
+}
+
+// agreement of sets without any boxes is perfect, they agree there is nothing on the page.
+func (t tally) agreement() models.Agreement {
+	if t.boxes == 0 {
+		return models.Agreement{Boxes: 1, IoU: 1, Labels: 1}
+	}
+	result := models.Agreement{Boxes: 2 * float64(t.matched) / float64(t.boxes)}
+	if t.matched > 0 {
+		result.IoU = t.iou / float64(t.matched)
+		result.Labels = float64(t.sameLabel) / float64(t.matched)
+	}
+	return result
+}
+
+// Compare measures agreement between two annotations of the same page.
+func Compare(a []models.Prediction, b []models.Prediction) models.Agreement {
+	var t tally
+	t.compare(a, b)
+	return t.agreement()
+}
+
+// Report compares every pair of annotation sets of each page, and sums the comparisons per page, per annotator and overall.
+// Pages with fewer than two saved sets are listed without agreement.
+func Report(pages []models.AgreementPage) models.AgreementReport {
+	report := models.AgreementReport{
+		Pages:      []models.PageAgreement{},
+		Annotators: []models.AnnotatorAgreement{},
+	}
+	var overall tally
+	byAnnotator := map[string]*tally{}
+	annotatorPages := map[string]int{}
+	for _, page := range pages {
+		var pageTally tally
+		involved := map[string]bool{}
+		for i := range page.Sets {
+			for j := i + 1; j < len(page.Sets); j++ {
+				var t tally
+				t.compare(page.Sets[i].Predictions, page.Sets[j].Predictions)
+				pageTally.add(t)
+				for _, annotator := range []string{page.Sets[i].Annotator, page.Sets[j].Annotator} {
+					if byAnnotator[annotator] == nil {
+						byAnnotator[annotator] = &tally{}
+					}
+					byAnnotator[annotator].add(t)
+					involved[annotator] = true
+				}
+			}
+		}
+		for annotator := range involved {
+			annotatorPages[annotator]++
+		}
+		overall.add(pageTally)
+
+		result := models.PageAgreement{AgreementPage: page, Pairs: pageTally.pairs}
+		if pageTally.pairs > 0 {
+			result.Agreement = pageTally.agreement()
+		}
+		report.Pages = append(report.Pages, result)
+	}
+
+	if overall.pairs > 0 {
+		report.Overall = overall.agreement()
+	}
+	for annotator, t := range byAnnotator {
+		report.Annotators = append(report.Annotators, models.AnnotatorAgreement{
+			Annotator: annotator,
+			Agreement: t.agreement(),
+			Pages:     annotatorPages[annotator],
+		})
+	}
+	slices.SortFunc(report.Annotators, func(a, b models.AnnotatorAgreement) int {
+		return cmp.Compare(a.Annotator, b.Annotator)
+	})
+	return report
+}
@@ -0,0 +1,155 @@
+package agreement
+
+import (
+	"math"
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func box(label string, x0, y0, x1, y1 float32) models.Prediction {
+	return models.Prediction{Label: label, Rect: models.Rect{X0: x0, Y0: y0, X1: x1, Y1: y1}}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []models.Prediction
+		b    []models.Prediction
+		want models.Agreement
+	}{
+		{"both empty", nil, nil, models.Agreement{Boxes: 1, IoU: 1, Labels: 1}},
+		{"identical", []models.Prediction{box("text", 0, 0, 10, 10)}, []models.Prediction{box("text", 0, 0, 10, 10)}, models.Agreement{Boxes: 1, IoU: 1, Labels: 1}},
+		{"other label", []models.Prediction{box("text", 0, 0, 10, 10)}, []models.Prediction{box("title", 0, 0, 10, 10)}, models.Agreement{Boxes: 1, IoU: 1, Labels: 0}},
+		{"one side empty", []models.Prediction{box("text", 0, 0, 10, 10)}, nil, models.Agreement{}},
+		{"no overlap", []models.Prediction{box("text", 0, 0, 10, 10)}, []models.Prediction{box("text", 20, 0, 30, 10)}, models.Agreement{}},
+		{
+			name: "one of two boxes matched",
+			a:    []models.Prediction{box("text", 0, 0, 10, 10), box("table", 0, 20, 10, 30)},
+			b:    []models.Prediction{box("text", 0, 0, 10, 8)},
+			want: models.Agreement{Boxes: 2.0 / 3, IoU: 0.8, Labels: 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Compare(test.a, test.b)
+			if !near(got.Boxes, test.want.Boxes) || !near(got.IoU, test.want.IoU) || !near(got.Labels, test.want.Labels) {
+				t.Errorf("Compare() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClusters(t *testing.T) {
+	sets := []models.AnnotationSet{
+		{Annotator: "ana", Predictions: []models.Prediction{box("text", 0, 50, 100, 60), box("title", 0, 0, 100, 10)}},
+		{Annotator: "bob", Predictions: []models.Prediction{box("title", 0, 0, 100, 11), box("text", 0, 50, 100, 61), box("image", 0, 80, 50, 90)}},
+		{Annotator: "eve", Predictions: []models.Prediction{box("heading", 1, 0, 100, 10), box("text", 0, 49, 100, 60)}},
+	}
+	clusters := Clusters(sets)
+	if len(clusters) != 3 {
+		t.Fatalf("Clusters() returned %d clusters, want 3", len(clusters))
+	}
+
+	var annotators [][]string
+	var choices []int
+	for _, cluster := range clusters {
+		var names []string
+		for _, variant := range cluster.Variants {
+			names = append(names, variant.Annotator)
+		}
+		annotators = append(annotators, names)
+		choices = append(choices, cluster.Choice)
+	}
+	// Clusters are ordered top to bottom, the image drawn by one of three annotators is dropped by default
+	wantAnnotators := [][]string{{"ana", "bob", "eve"}, {"ana", "bob", "eve"}, {"bob"}}
+	if !slices.EqualFunc(annotators, wantAnnotators, slices.Equal[[]string]) {
+		t.Errorf("cluster annotators = %v, want %v", annotators, wantAnnotators)
+	}
+	if choices[2] != -1 {
+		t.Errorf("choice of the image = %d, want -1", choices[2])
+	}
+	if label := clusters[0].Variants[choices[0]].Prediction.Label; label != "title" {
+		t.Errorf("default label of the title = %q, want the majority label title", label)
+	}
+
+	merged := Merge(clusters, choices)
+	var labels []string
+	for _, prediction := range merged {
+		labels = append(labels, prediction.Label)
+	}
+	if !slices.Equal(labels, []string{"title", "text"}) {
+		t.Errorf("Merge() labels = %v, want [title text]", labels)
+	}
+}
+
+func TestClustersKeepOneBoxPerAnnotator(t *testing.T) {
+	sets := []models.AnnotationSet{
+		{Annotator: "ana", Predictions: []models.Prediction{box("text", 0, 0, 10, 10), box("text", 0, 0, 10, 10)}},
+	}
+	if clusters := Clusters(sets); len(clusters) != 2 {
+		t.Errorf("Clusters() returned %d clusters, want 2 for two boxes of the same annotator", len(clusters))
+	}
+}
+
+func TestMerge(t *testing.T) {
+	table := box("table", 0, 0, 10, 10)
+	table.Table = []models.Prediction{box("cell", 0, 0, 5, 5)}
+	clusters := []Cluster{
+		{Variants: []Variant{{Annotator: "ana", Prediction: table}, {Annotator: "bob", Prediction: box("image", 0, 0, 10, 10)}}},
+		{Variants: []Variant{{Annotator: "ana", Prediction: box("text", 0, 20, 10, 30)}}},
+		{Variants: []Variant{{Annotator: "bob", Prediction: box("text", 0, 40, 10, 50)}}},
+	}
+	tests := []struct {
+		name    string
+		choices []int
+		want    []string
+	}{
+		{"first variants", []int{0, 0, 0}, []string{"table", "text", "text"}},
+		{"other variant", []int{1, 0, 0}, []string{"image", "text", "text"}},
+		{"dropped", []int{-1, 0, -1}, []string{"text"}},
+		{"out of range", []int{2, 1}, []string{}},
+		{"nothing chosen", nil, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labels := []string{}
+			for _, prediction := range Merge(clusters, test.choices) {
+				labels = append(labels, prediction.Label)
+			}
+			if !slices.Equal(labels, test.want) {
+				t.Errorf("Merge() labels = %v, want %v", labels, test.want)
+			}
+		})
+	}
+
+	merged := Merge(clusters, []int{0})
+	merged[0].Table[0].Label = "header"
+	if table.Table[0].Label != "cell" {
+		t.Error("Merge() shares the cells of the chosen table with the annotation set")
+	}
+}
+
+func TestReport(t *testing.T) {
+	pages := []models.AgreementPage{
+		{Sets: []models.AnnotationSet{
+			{Annotator: "ana", Predictions: []models.Prediction{box("text", 0, 0, 10, 10)}},
+			{Annotator: "bob", Predictions: []models.Prediction{box("text", 0, 0, 10, 10)}},
+		}},
+		{Sets: []models.AnnotationSet{{Annotator: "ana"}}},
+	}
+	report := Report(pages)
+	if report.Pages[0].Pairs != 1 || report.Pages[1].Pairs != 0 {
+		t.Errorf("page pairs = %d and %d, want 1 and 0", report.Pages[0].Pairs, report.Pages[1].Pairs)
+	}
+	if report.Overall.Boxes != 1 {
+		t.Errorf("overall box agreement = %v, want 1", report.Overall.Boxes)
+	}
+	if len(report.Annotators) != 2 || report.Annotators[0].Annotator != "ana" || report.Annotators[0].Pages != 1 {
+		t.Errorf("annotators = %+v", report.Annotators)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -4,6 +4,8 @@ import (
 	"html/template"
 	"net/http"
 	"os"
+	"path"
+	"smart-docs/core/models"
 	"strings"
 
 	"github.com/gorilla/securecookie"
@@ -18,12 +20,37 @@ const (
 	apiKeyCookieName = "api_key"
 )
 
+// ValidateAPIKey accepts the shared API_KEY and the personal keys of USERS.
 func ValidateAPIKey(apiKey string) bool {
+	if apiKey == "" {
+		return false
+	}
+	if _, ok := userKeys()[apiKey]; ok {
+		return true
+	}
 	return apiKey == os.Getenv("API_KEY")
 }
 
 func IsSecured() bool {
-	return os.Getenv("API_KEY") != ""
+	return os.Getenv("API_KEY") != "" || os.Getenv("USERS") != ""
+}
+
+// HasUsers tells whether users sign in with personal keys from USERS, otherwise everybody is anonymous.
+func HasUsers() bool {
+	return len(userKeys()) > 0
+}
+
+// userKeys reads USERS, a comma separated list of "name:key" pairs, as the names keyed by their key.
+func userKeys() map[string]string {
+	users := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("USERS"), ",") {
+		name, key, ok := strings.Cut(entry, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if ok && name != "" && key != "" {
+			users[key] = name
+		}
+	}
+	return users
 }
 
 func SetAPICookie(w http.ResponseWriter, apiKey string) {
@@ -63,6 +90,66 @@ func GetAPICookie(r *http.Request) (string, bool) {
 	return apiKey, exists
 }
 
+// requestKey returns the key a request is made with, a bearer token or the key of the browser session.
+func requestKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	apiKey, _ := GetAPICookie(r)
+	return apiKey
+}
+
+// CurrentUser names who is making the request, used as the author of annotations. Users are only known by their
+// personal key from USERS, requests made with the shared API_KEY or without a key are anonymous.
+func CurrentUser(r *http.Request) string {
+	if user, ok := userKeys()[requestKey(r)]; ok {
+		return user
+	}
+	return models.AnonymousUser
+}
+
+// IsReviewer tells whether the user may approve and reject pages, only users listed in REVIEWERS may. Everybody may
+// when REVIEWERS is not set, so that servers without personal keys can still validate pages.
+func IsReviewer(user string) bool {
+	if strings.TrimSpace(os.Getenv("REVIEWERS")) == "" {
+		return true
+	}
+	return listed("REVIEWERS", user)
+}
+
+// IsAdmin tells whether the user may manage webhooks, only users listed in ADMINS may.
+func IsAdmin(user string) bool {
+	return listed("ADMINS", user)
+}
+
+// listed tells whether the user is in the comma separated list of the environment variable, anonymous users never are.
+func listed(key string, user string) bool {
+	if user == models.AnonymousUser {
+		return false
+	}
+	for _, name := range strings.Split(os.Getenv(key), ",") {
+		if strings.TrimSpace(name) == user {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublic tells whether a path is served without authentication: static assets, page images and the health check.
+// Images extracted from documents, under /images/{id}/assets, are only served through the authenticated document routes.
+func isPublic(p string) bool {
+	p = path.Clean(p)
+	segments := strings.Split(p, "/")
+	switch {
+	case len(segments) > 2 && segments[1] == "assets":
+		return true
+	case len(segments) > 2 && segments[1] == "images":
+		return len(segments) < 4 || segments[3] != "assets"
+	default:
+		return p == "/health" || p == "/api/v1/openapi.json"
+	}
+}
+
 func AuthMiddleware(tmpl *template.Template, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !IsSecured() {
@@ -70,10 +157,7 @@ func AuthMiddleware(tmpl *template.Template, next http.Handler) http.Handler {
 			return
 		}
 
-		// Skip authentication for static assets and health check
-		if strings.HasPrefix(r.URL.Path, "/assets/") ||
-			strings.HasPrefix(r.URL.Path, "/images/") ||
-			r.URL.Path == "/health" {
+		if isPublic(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -90,6 +174,18 @@ func AuthMiddleware(tmpl *template.Template, next http.Handler) http.Handler {
 		}
 
 		apiKey, exists := GetAPICookie(r)
+		// API clients send the key as a bearer token and are answered with json rather than the login page
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				apiKey, exists = token, true
+			}
+			if !exists || !ValidateAPIKey(apiKey) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"status":401,"error":"a valid API key is required"}`))
+				return
+			}
+		}
 		if !exists || !ValidateAPIKey(apiKey) {
 			tmpl.ExecuteTemplate(w, "login.go.html", nil)
 			return
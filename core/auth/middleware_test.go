@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestCurrentUser(t *testing.T) {
+	t.Setenv("API_KEY", "shared")
+	t.Setenv("USERS", "ana:key-ana, bob:key-bob,broken,:no-name,no-key:")
+	tests := []struct {
+		name    string
+		request func(r *http.Request)
+		want    string
+	}{
+		{"personal key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer key-ana") }, "ana"},
+		{"trimmed personal key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer key-bob") }, "bob"},
+		{"shared key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer shared") }, models.AnonymousUser},
+		{"unknown key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") }, models.AnonymousUser},
+		{"user header", func(r *http.Request) { r.Header.Set("X-User", "ana") }, models.AnonymousUser},
+		{"no key", func(r *http.Request) {}, models.AnonymousUser},
+		{"session", func(r *http.Request) {
+			recorder := httptest.NewRecorder()
+			SetAPICookie(recorder, "key-bob")
+			for _, cookie := range recorder.Result().Cookies() {
+				r.AddCookie(cookie)
+			}
+		}, "bob"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/documents", nil)
+			test.request(r)
+			if got := CurrentUser(r); got != test.want {
+				t.Errorf("CurrentUser() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	t.Setenv("API_KEY", "")
+	t.Setenv("USERS", "ana:key-ana")
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"key-ana", true},
+		{"", false},
+		{"ana", false},
+	}
+	for _, test := range tests {
+		if got := ValidateAPIKey(test.key); got != test.want {
+			t.Errorf("ValidateAPIKey(%q) = %v, want %v", test.key, got, test.want)
+		}
+	}
+	if !IsSecured() {
+		t.Error("IsSecured() = false with USERS set")
+	}
+}
+
+func TestIsReviewer(t *testing.T) {
+	tests := []struct {
+		reviewers string
+		user      string
+		want      bool
+	}{
+		{"ana, bob", "bob", true},
+		{"ana,bob", "eve", false},
+		{"", "ana", true},
+		{"", models.AnonymousUser, true},
+		{"anonymous", models.AnonymousUser, false},
+	}
+	for _, test := range tests {
+		t.Setenv("REVIEWERS", test.reviewers)
+		if got := IsReviewer(test.user); got != test.want {
+			t.Errorf("IsReviewer(%q) with REVIEWERS=%q = %v, want %v", test.user, test.reviewers, got, test.want)
+		}
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	t.Setenv("REVIEWERS", "bob")
+	tests := []struct {
+		admins string
+		user   string
+		want   bool
+	}{
+		{"ana", "ana", true},
+		{"ana", "bob", false},
+		{"", "ana", false},
+		{"anonymous", models.AnonymousUser, false},
+	}
+	for _, test := range tests {
+		t.Setenv("ADMINS", test.admins)
+		if got := IsAdmin(test.user); got != test.want {
+			t.Errorf("IsAdmin(%q) with ADMINS=%q = %v, want %v", test.user, test.admins, got, test.want)
+		}
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Setenv("API_KEY", "shared")
+	t.Setenv("USERS", "")
+	login := template.Must(template.New("login.go.html").Parse("login"))
+	handler := AuthMiddleware(login, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("served"))
+	}))
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/assets/app.js", true},
+		{"/health", true},
+		{"/api/v1/openapi.json", true},
+		{"/images/12/page-1.jpg", true},
+		{"/images/12/assets/figure.png", false},
+		{"/images/12/assets/", false},
+		{"/images/12/assets", false},
+		{"/images/12//assets", false},
+		{"/images/12/x/../assets", false},
+		{"/images/../documents", false},
+		{"/images", false},
+		{"/documents", false},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.URL.Path = test.path
+			handler.ServeHTTP(w, r)
+			if got := w.Body.String() == "served"; got != test.want {
+				t.Errorf("%s served without a key = %v, want %v", test.path, got, test.want)
+			}
+		})
+	}
+}
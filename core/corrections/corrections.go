@@ -0,0 +1,26 @@
+package corrections
+
+import (
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+// Correct applies an operation to the words of a page, records it and re-renders the page from the corrected words.
+// It fails with sql.ErrNoRows when the page does not exist or its words were corrected by someone else in the meantime.
+func Correct(docId int64, pageNum int, author string, operation Operation) ([]models.WordData, error) {
+	text, err := db.LoadPageText(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	words, edit, err := operation(text.Words)
+	if err != nil {
+		return nil, err
+	}
+	edit.Author = author
+	err = db.SaveWordEdit(text, words, edit)
+	if err != nil {
+		return nil, err
+	}
+	return words, pipeline.Rerender(docId, pageNum, author)
+}
@@ -0,0 +1,169 @@
+package corrections
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"smart-docs/core/models"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrWordNotFound = errors.New("word not found")
+	ErrInvalidEdit  = errors.New("invalid edit")
+)
+
+// Operation changes the word list of a page and describes the change for the audit log.
+type Operation func(words []models.WordData) ([]models.WordData, models.WordEdit, error)
+
+// EditWord replaces the text of a word and, when rect is set, its box.
+func EditWord(index int, text string, rect *models.Rect) Operation {
+	return func(words []models.WordData) ([]models.WordData, models.WordEdit, error) {
+		if index < 0 || index >= len(words) {
+			return nil, models.WordEdit{}, ErrWordNotFound
+		}
+		if strings.TrimSpace(text) == "" {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: text is empty, delete the word instead", ErrInvalidEdit)
+		}
+		word := models.WordData{Rect: words[index].Rect, Text: text}
+		if rect != nil {
+			if !validRect(*rect) {
+				return nil, models.WordEdit{}, fmt.Errorf("%w: box is empty or inverted", ErrInvalidEdit)
+			}
+			word.Rect = *rect
+		}
+		return replace(words, models.WordEditText, index, 1, word)
+	}
+}
+
+// MergeWords joins neighbouring words into one word. The box covers all merged words, the text is given or the merged
+// texts joined without a separator, as OCR tends to break numbers and words apart.
+func MergeWords(indices []int, text string) Operation {
+	return func(words []models.WordData) ([]models.WordData, models.WordEdit, error) {
+		if len(indices) < 2 {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: at least 2 words are required", ErrInvalidEdit)
+		}
+		sorted := slices.Clone(indices)
+		slices.Sort(sorted)
+		if len(slices.Compact(sorted)) != len(indices) {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: words are listed twice", ErrInvalidEdit)
+		}
+		for i := range sorted {
+			if sorted[i] != sorted[0]+i {
+				return nil, models.WordEdit{}, fmt.Errorf("%w: only neighbouring words can be merged", ErrInvalidEdit)
+			}
+		}
+		if sorted[0] < 0 || sorted[len(sorted)-1] >= len(words) {
+			return nil, models.WordEdit{}, ErrWordNotFound
+		}
+
+		merged := models.WordData{Rect: words[sorted[0]].Rect}
+		var texts []string
+		for _, word := range words[sorted[0] : sorted[0]+len(sorted)] {
+			merged.Rect = union(merged.Rect, word.Rect)
+			texts = append(texts, word.Text)
+		}
+		merged.Text = text
+		if strings.TrimSpace(merged.Text) == "" {
+			merged.Text = strings.Join(texts, "")
+		}
+		return replace(words, models.WordMerge, sorted[0], len(sorted), merged)
+	}
+}
+
+// SplitWord replaces a word by the given parts. The box is divided horizontally in proportion to the length of the parts.
+func SplitWord(index int, parts []string) Operation {
+	return func(words []models.WordData) ([]models.WordData, models.WordEdit, error) {
+		if index < 0 || index >= len(words) {
+			return nil, models.WordEdit{}, ErrWordNotFound
+		}
+		if len(parts) < 2 {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: at least 2 parts are required", ErrInvalidEdit)
+		}
+		total := 0
+		for _, part := range parts {
+			if strings.TrimSpace(part) == "" {
+				return nil, models.WordEdit{}, fmt.Errorf("%w: parts must not be empty", ErrInvalidEdit)
+			}
+			total += utf8.RuneCountInString(part)
+		}
+
+		word := words[index]
+		split := make([]models.WordData, len(parts))
+		x := word.X0
+		done := 0
+		for i, part := range parts {
+			done += utf8.RuneCountInString(part)
+			x1 := word.X0 + word.Width()*float32(done)/float32(total)
+			split[i] = models.WordData{
+				Rect: models.Rect{X0: x, Y0: word.Y0, X1: x1, Y1: word.Y1},
+				Text: part,
+			}
+			x = x1
+		}
+		return replace(words, models.WordSplit, index, 1, split...)
+	}
+}
+
+// AddWord inserts a word missed by the text extraction, in reading order.
+func AddWord(word models.WordData) Operation {
+	return func(words []models.WordData) ([]models.WordData, models.WordEdit, error) {
+		if strings.TrimSpace(word.Text) == "" {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: text is empty", ErrInvalidEdit)
+		}
+		if !validRect(word.Rect) {
+			return nil, models.WordEdit{}, fmt.Errorf("%w: box is empty or inverted", ErrInvalidEdit)
+		}
+		return replace(words, models.WordAdd, insertionIndex(words, word), 0, word)
+	}
+}
+
+func DeleteWord(index int) Operation {
+	return func(words []models.WordData) ([]models.WordData, models.WordEdit, error) {
+		if index < 0 || index >= len(words) {
+			return nil, models.WordEdit{}, ErrWordNotFound
+		}
+		return replace(words, models.WordDelete, index, 1)
+	}
+}
+
+// replace swaps count words at index for the given ones.
+func replace(words []models.WordData, operation string, index int, count int, with ...models.WordData) ([]models.WordData, models.WordEdit, error) {
+	edit := models.WordEdit{
+		Operation: operation,
+		Index:     index,
+		Before:    slices.Clone(words[index : index+count]),
+		After:     slices.Clone(with),
+	}
+	if edit.After == nil {
+		edit.After = []models.WordData{}
+	}
+	result := slices.Concat(words[:index], with, words[index+count:])
+	return result, edit, nil
+}
+
+// insertionIndex finds the position of a new word in reading order: before the first word on a line below it,
+// or on the same line and right of it.
+func insertionIndex(words []models.WordData, word models.WordData) int {
+	for i, other := range words {
+		sameLine := other.Y0 < word.Y1 && other.Y1 > word.Y0
+		if (sameLine && other.X0 > word.X0) || (!sameLine && other.Y0 >= word.Y1) {
+			return i
+		}
+	}
+	return len(words)
+}
+
+func union(a models.Rect, b models.Rect) models.Rect {
+	return models.Rect{
+		X0: min(a.X0, b.X0),
+		Y0: min(a.Y0, b.Y0),
+		X1: max(a.X1, b.X1),
+		Y1: max(a.Y1, b.Y1),
+	}
+}
+
+func validRect(r models.Rect) bool {
+	return r.X1 > r.X0 && r.Y1 > r.Y0
+}
@@ -0,0 +1,132 @@
+package corrections
+
+import (
+	"errors"
+	"reflect"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func word(text string, x0, y0, x1, y1 float32) models.WordData {
+	return models.WordData{Rect: models.Rect{X0: x0, Y0: y0, X1: x1, Y1: y1}, Text: text}
+}
+
+// page is a line "Total 1 250 EUR" followed by "Paid" on the next line.
+func page() []models.WordData {
+	return []models.WordData{
+		word("Total", 0, 0, 50, 10),
+		word("1", 60, 0, 70, 10),
+		word("250", 75, 0, 100, 10),
+		word("EUR", 110, 0, 140, 10),
+		word("Paid", 0, 20, 40, 30),
+	}
+}
+
+func TestOperations(t *testing.T) {
+	rect := models.Rect{X0: 60, Y0: 0, X1: 100, Y1: 10}
+	tests := []struct {
+		name      string
+		operation Operation
+		want      []models.WordData
+		edit      models.WordEdit
+	}{
+		{
+			"edit text",
+			EditWord(3, "USD", nil),
+			[]models.WordData{page()[0], page()[1], page()[2], word("USD", 110, 0, 140, 10), page()[4]},
+			models.WordEdit{Operation: models.WordEditText, Index: 3, Before: page()[3:4], After: []models.WordData{word("USD", 110, 0, 140, 10)}},
+		},
+		{
+			"edit text and box",
+			EditWord(1, "1250", &rect),
+			[]models.WordData{page()[0], word("1250", 60, 0, 100, 10), page()[2], page()[3], page()[4]},
+			models.WordEdit{Operation: models.WordEditText, Index: 1, Before: page()[1:2], After: []models.WordData{word("1250", 60, 0, 100, 10)}},
+		},
+		{
+			"merge",
+			MergeWords([]int{2, 1}, ""),
+			[]models.WordData{page()[0], word("1250", 60, 0, 100, 10), page()[3], page()[4]},
+			models.WordEdit{Operation: models.WordMerge, Index: 1, Before: page()[1:3], After: []models.WordData{word("1250", 60, 0, 100, 10)}},
+		},
+		{
+			"merge with text",
+			MergeWords([]int{1, 2, 3}, "1 250 EUR"),
+			[]models.WordData{page()[0], word("1 250 EUR", 60, 0, 140, 10), page()[4]},
+			models.WordEdit{Operation: models.WordMerge, Index: 1, Before: page()[1:4], After: []models.WordData{word("1 250 EUR", 60, 0, 140, 10)}},
+		},
+		{
+			"split",
+			SplitWord(0, []string{"To", "tal"}),
+			[]models.WordData{word("To", 0, 0, 20, 10), word("tal", 20, 0, 50, 10), page()[1], page()[2], page()[3], page()[4]},
+			models.WordEdit{Operation: models.WordSplit, Index: 0, Before: page()[0:1], After: []models.WordData{word("To", 0, 0, 20, 10), word("tal", 20, 0, 50, 10)}},
+		},
+		{
+			"add on a line",
+			AddWord(word("net", 55, 0, 58, 10)),
+			[]models.WordData{page()[0], word("net", 55, 0, 58, 10), page()[1], page()[2], page()[3], page()[4]},
+			models.WordEdit{Operation: models.WordAdd, Index: 1, Before: []models.WordData{}, After: []models.WordData{word("net", 55, 0, 58, 10)}},
+		},
+		{
+			"add at the end",
+			AddWord(word("today", 50, 20, 90, 30)),
+			append(page(), word("today", 50, 20, 90, 30)),
+			models.WordEdit{Operation: models.WordAdd, Index: 5, Before: []models.WordData{}, After: []models.WordData{word("today", 50, 20, 90, 30)}},
+		},
+		{
+			"delete",
+			DeleteWord(4),
+			page()[:4],
+			models.WordEdit{Operation: models.WordDelete, Index: 4, Before: page()[4:5], After: []models.WordData{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := page()
+			got, edit, err := test.operation(words)
+			if err != nil {
+				t.Fatalf("operation failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("words = %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(edit, test.edit) {
+				t.Errorf("edit = %+v, want %+v", edit, test.edit)
+			}
+			if !reflect.DeepEqual(words, page()) {
+				t.Errorf("operation changed its input")
+			}
+		})
+	}
+}
+
+func TestInvalidOperations(t *testing.T) {
+	empty := models.Rect{X0: 10, Y0: 0, X1: 10, Y1: 10}
+	tests := []struct {
+		name      string
+		operation Operation
+		want      error
+	}{
+		{"edit missing word", EditWord(5, "USD", nil), ErrWordNotFound},
+		{"edit to empty text", EditWord(0, " ", nil), ErrInvalidEdit},
+		{"edit to empty box", EditWord(0, "Total", &empty), ErrInvalidEdit},
+		{"merge one word", MergeWords([]int{1}, ""), ErrInvalidEdit},
+		{"merge a word twice", MergeWords([]int{1, 1}, ""), ErrInvalidEdit},
+		{"merge words apart", MergeWords([]int{1, 3}, ""), ErrInvalidEdit},
+		{"merge words apart with a neighbour", MergeWords([]int{0, 1, 3}, ""), ErrInvalidEdit},
+		{"merge missing words", MergeWords([]int{4, 5}, ""), ErrWordNotFound},
+		{"merge negative words", MergeWords([]int{-1, 0}, ""), ErrWordNotFound},
+		{"split missing word", SplitWord(-1, []string{"a", "b"}), ErrWordNotFound},
+		{"split in one part", SplitWord(0, []string{"Total"}), ErrInvalidEdit},
+		{"split with an empty part", SplitWord(0, []string{"Total", ""}), ErrInvalidEdit},
+		{"add empty text", AddWord(word("", 0, 40, 10, 50)), ErrInvalidEdit},
+		{"add empty box", AddWord(models.WordData{Rect: empty, Text: "net"}), ErrInvalidEdit},
+		{"delete missing word", DeleteWord(5), ErrWordNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := test.operation(page()); !errors.Is(err, test.want) {
+				t.Errorf("error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
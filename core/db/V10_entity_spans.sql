@@ -0,0 +1,32 @@
+-- Entity labels of a project, used to tag word ranges of the page text
+create table if not exists entity_labels
+(
+    id         integer primary key autoincrement,
+    project_id integer not null,
+    name       text    not null,
+    color      text    not null,
+    position   integer not null default 0,
+    unique (project_id, name),
+    foreign key (project_id) references projects (id) on delete cascade
+);
+
+insert into entity_labels (project_id, name, color, position)
+values (1, 'date', '#e6194b', 0),
+       (1, 'amount', '#3cb44b', 1),
+       (1, 'party', '#4363d8', 2),
+       (1, 'id', '#f58231', 3);
+
+-- A span tags the words start_word to end_word, both included, of the page word list with an entity label
+create table if not exists entity_spans
+(
+    id         integer primary key autoincrement,
+    page_id    integer  not null,
+    label      text     not null,
+    start_word integer  not null,
+    end_word   integer  not null,
+    author     text     not null,
+    created_at datetime not null,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+create index if not exists entity_spans_page on entity_spans (page_id, start_word);
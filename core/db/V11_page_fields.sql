@@ -0,0 +1,20 @@
+-- Key-value pairs of form pages. Boxes are json rects in pixels of the page image.
+-- Fields extracted by the pipeline have source 'model' and are replaced on every extraction, fields edited by an
+-- annotator become 'human' and are kept. Removed fields stay with removed = 1 so extraction does not bring them back.
+create table if not exists page_fields
+(
+    id         integer primary key autoincrement,
+    page_id    integer  not null,
+    position   integer  not null,
+    key        text     not null,
+    value      text     not null,
+    key_box    text     not null,
+    value_box  text     not null,
+    source     text     not null,
+    author     text     not null,
+    removed    boolean  not null default false,
+    updated_at datetime not null,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+create index if not exists page_fields_page on page_fields (page_id, position);
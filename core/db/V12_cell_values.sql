@@ -0,0 +1,2 @@
+-- Locale of numbers and dates in table cells of a project, empty when cell values are not typed
+alter table projects add column value_locale text not null default '';
@@ -0,0 +1,30 @@
+-- Endpoints notified of document and page events, events is a comma separated list of event names
+create table if not exists webhooks
+(
+    id         integer primary key autoincrement,
+    url        text     not null,
+    secret     text     not null,
+    events     text     not null,
+    created_by text     not null,
+    created_at datetime not null
+);
+
+-- Every event sent to a webhook, with the outcome of its last attempt. Pending deliveries are retried at next_attempt_at
+create table if not exists webhook_deliveries
+(
+    id              integer primary key autoincrement,
+    webhook_id      integer  not null,
+    event           text     not null,
+    payload         text     not null,
+    status          text     not null default 'pending',
+    attempts        integer  not null default 0,
+    response_status integer  not null default 0,
+    error           text     not null default '',
+    created_at      datetime not null,
+    next_attempt_at datetime not null,
+    delivered_at    datetime,
+    foreign key (webhook_id) references webhooks (id) on delete cascade
+);
+
+create index if not exists webhook_deliveries_due on webhook_deliveries (status, next_attempt_at);
+create index if not exists webhook_deliveries_webhook on webhook_deliveries (webhook_id, created_at);
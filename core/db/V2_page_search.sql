@@ -0,0 +1,16 @@
+create virtual table if not exists page_search using fts5
+(
+    content,
+    document_id unindexed,
+    page_num unindexed,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into page_search (rowid, document_id, page_num, content)
+select p.id,
+       p.document_id,
+       p.page_num,
+       coalesce(nullif((select group_concat(json_extract(w.value, '$.Text'), ' ')
+                        from json_each(case when p.ocr_text <> '' then p.ocr_text else p.pdf_text end) w), ''),
+                p.md, '')
+from pages p;
@@ -0,0 +1,20 @@
+create table if not exists annotations
+(
+    id          integer primary key,
+    page_id     integer  not null,
+    source      text     not null check (source in ('model', 'human')),
+    author      text     not null,
+    created_at  datetime not null default current_timestamp,
+    predictions text     not null,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+create index if not exists annotations_page_source on annotations (page_id, source, created_at);
+
+-- Earlier edits overwrote the detector output, so existing annotations can only be attributed by page status
+insert into annotations (page_id, source, author, predictions)
+select id,
+       case when status = 'PREDICTION' then 'model' else 'human' end,
+       case when status = 'PREDICTION' then 'detector' else 'unknown' end,
+       coalesce(predictions, '[]')
+from pages;
@@ -0,0 +1,9 @@
+alter table pages add column assignee text;
+
+create table if not exists page_leases
+(
+    page_id    integer primary key,
+    holder     text     not null,
+    expires_at datetime not null,
+    foreign key (page_id) references pages (id) on delete cascade
+);
@@ -0,0 +1,13 @@
+create table if not exists page_transitions
+(
+    id          integer primary key,
+    page_id     integer  not null,
+    from_status text     not null,
+    to_status   text     not null,
+    user        text     not null,
+    reason      text     not null default '',
+    created_at  datetime not null default current_timestamp,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+create index if not exists page_transitions_page on page_transitions (page_id, created_at);
@@ -0,0 +1,19 @@
+create table if not exists agreement_pages
+(
+    page_id             integer primary key,
+    required_annotators integer  not null default 2,
+    created_at          datetime not null default current_timestamp,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+-- One independent annotation of an agreement page per annotator, predictions stay empty until the first save
+create table if not exists annotation_sets
+(
+    page_id     integer  not null,
+    annotator   text     not null,
+    predictions text,
+    claimed_at  datetime not null default current_timestamp,
+    updated_at  datetime,
+    primary key (page_id, annotator),
+    foreign key (page_id) references pages (id) on delete cascade
+);
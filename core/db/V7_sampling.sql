@@ -0,0 +1,10 @@
+-- Outcome of the active learning policy for pages waiting in PREDICTION, reasons are empty when the page was not interesting
+create table if not exists page_sampling
+(
+    page_id      integer primary key,
+    reasons      text     not null default '',
+    confidence   real     not null default 1,
+    evaluated_at datetime not null,
+    selected_at  datetime,
+    foreign key (page_id) references pages (id) on delete cascade
+);
@@ -0,0 +1,32 @@
+-- Label schemas are defined per project, documents belong to the default project unless chosen on upload
+create table if not exists projects
+(
+    id   integer primary key autoincrement,
+    name text not null unique
+);
+
+insert into projects (id, name) values (1, 'default');
+
+alter table documents add column project_id integer not null default 1;
+
+-- parents is a json array of label names a box may be nested in, "page" for boxes placed directly on the page.
+-- renderer decides how the content of a box is rendered: paragraph, heading, table, image, cell or text.
+create table if not exists labels
+(
+    id         integer primary key autoincrement,
+    project_id integer not null,
+    name       text    not null,
+    color      text    not null,
+    parents    text    not null default '["page"]',
+    renderer   text    not null default 'text',
+    position   integer not null default 0,
+    unique (project_id, name),
+    foreign key (project_id) references projects (id) on delete cascade
+);
+
+insert into labels (project_id, name, color, parents, renderer, position)
+values (1, 'paragraph', '#0000ff', '["page"]', 'paragraph', 0),
+       (1, 'header', '#ff0000', '["page", "table"]', 'heading', 1),
+       (1, 'table', '#808080', '["page"]', 'table', 2),
+       (1, 'illustration', '#ffff00', '["page"]', 'image', 3),
+       (1, 'cell', '#0000ff', '["table"]', 'cell', 4);
@@ -0,0 +1,18 @@
+-- Words of a page as extracted, kept when the page text is corrected for the first time
+alter table pages add column original_words text;
+
+-- Audit log of word corrections, before and after are json arrays of the words replaced and inserted at word_index
+create table if not exists word_edits
+(
+    id         integer primary key autoincrement,
+    page_id    integer  not null,
+    operation  text     not null,
+    word_index integer  not null,
+    before     text     not null,
+    after      text     not null,
+    author     text     not null,
+    created_at datetime not null,
+    foreign key (page_id) references pages (id) on delete cascade
+);
+
+create index if not exists word_edits_page on word_edits (page_id, created_at);
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"time"
+)
+
+// SelectForAgreement marks a page to be annotated independently by the given number of annotators.
+func SelectForAgreement(docId int64, pageNum int, required int) error {
+	result, err := dbInstance.db.Exec(`
+		insert into agreement_pages (page_id, required_annotators)
+		select id, ? from pages where document_id = ? and page_num = ?
+		on conflict (page_id) do update set required_annotators = excluded.required_annotators
+	`, required, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// UnselectForAgreement drops a page from agreement together with its annotation sets.
+func UnselectForAgreement(docId int64, pageNum int) error {
+	_, err := dbInstance.db.Exec(`
+		delete from annotation_sets where page_id = (select id from pages where document_id = ? and page_num = ?)
+	`, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`
+		delete from agreement_pages where page_id = (select id from pages where document_id = ? and page_num = ?)
+	`, docId, pageNum)
+	return err
+}
+
+// RequiredAnnotators returns how many annotators should annotate a page, 0 for pages not selected for agreement.
+func RequiredAnnotators(docId int64, pageNum int) (int, error) {
+	var required int
+	err := dbInstance.db.QueryRow(`
+		select a.required_annotators
+		from agreement_pages a
+			join pages p on p.id = a.page_id
+		where p.document_id = ? and p.page_num = ?
+	`, docId, pageNum).Scan(&required)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return required, err
+}
+
+// ClaimAnnotationSet reserves a place for the annotator among the annotators of an agreement page.
+func ClaimAnnotationSet(pageId int64, annotator string) error {
+	_, err := dbInstance.db.Exec(`
+		insert into annotation_sets (page_id, annotator, claimed_at) values (?, ?, ?)
+		on conflict (page_id, annotator) do nothing
+	`, pageId, annotator, time.Now())
+	return err
+}
+
+func SaveAnnotationSet(docId int64, pageNum int, annotator string, predictions []models.Prediction) error {
+	serialisedPredictions, err := json.Marshal(predictions)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = dbInstance.db.Exec(`
+		insert into annotation_sets (page_id, annotator, predictions, claimed_at, updated_at)
+		select id, ?, ?, ?, ? from pages where document_id = ? and page_num = ?
+		on conflict (page_id, annotator) do update set predictions = excluded.predictions, updated_at = excluded.updated_at
+	`, annotator, string(serialisedPredictions), now, now, docId, pageNum)
+	return err
+}
+
+// GetAnnotationSet returns the saved annotation of a page by the annotator, sql.ErrNoRows when they have not saved any.
+func GetAnnotationSet(docId int64, pageNum int, annotator string) (models.AnnotationSet, error) {
+	row := dbInstance.db.QueryRow(`
+		select s.annotator, s.predictions, s.updated_at
+		from annotation_sets s
+			join pages p on p.id = s.page_id
+		where p.document_id = ? and p.page_num = ? and s.annotator = ? and s.predictions is not null
+	`, docId, pageNum, annotator)
+	return scanAnnotationSet(row)
+}
+
+// ListAnnotationSets returns the saved annotation sets of a page ordered by annotator.
+func ListAnnotationSets(docId int64, pageNum int) ([]models.AnnotationSet, error) {
+	rows, err := dbInstance.db.Query(`
+		select s.annotator, s.predictions, s.updated_at
+		from annotation_sets s
+			join pages p on p.id = s.page_id
+		where p.document_id = ? and p.page_num = ? and s.predictions is not null
+		order by s.annotator
+	`, docId, pageNum)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []models.AnnotationSet{}
+	for rows.Next() {
+		set, err := scanAnnotationSet(rows)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+// AgreementPages returns all pages selected for agreement with their saved annotation sets.
+func AgreementPages() ([]models.AgreementPage, error) {
+	rows, err := dbInstance.db.Query(`
+		select doc.id, doc.name, p.page_num, coalesce(p.status, ''), a.required_annotators
+		from agreement_pages a
+			join pages p on p.id = a.page_id
+			join documents doc on doc.id = p.document_id
+		order by a.created_at, p.id
+	`)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []models.AgreementPage{}
+	for rows.Next() {
+		var page models.AgreementPage
+		err := rows.Scan(&page.DocumentId, &page.DocumentName, &page.PageNum, &page.Status, &page.Required)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range pages {
+		pages[i].Sets, err = ListAnnotationSets(pages[i].DocumentId, pages[i].PageNum)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+func scanAnnotationSet(row scanner) (models.AnnotationSet, error) {
+	var set models.AnnotationSet
+	var serialisedPredictions string
+	err := row.Scan(&set.Annotator, &serialisedPredictions, &set.UpdatedAt)
+	if err != nil {
+		return set, err
+	}
+	err = json.Unmarshal([]byte(serialisedPredictions), &set.Predictions)
+	return set, err
+}
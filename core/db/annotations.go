@@ -0,0 +1,87 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"time"
+)
+
+func StoreAnnotation(docId int64, pageNum int, source string, author string, predictions []models.Prediction) error {
+	if predictions == nil {
+		predictions = []models.Prediction{}
+	}
+	serialisedPredictions, err := json.Marshal(predictions)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`
+		insert into annotations (page_id, source, author, created_at, predictions)
+		select id, ?, ?, ?, ? from pages where document_id = ? and page_num = ?
+	`, source, author, time.Now(), string(serialisedPredictions), docId, pageNum)
+	return err
+}
+
+// ListAnnotations returns annotations of a page, newest first. An empty source lists both model and human annotations.
+func ListAnnotations(docId int64, pageNum int, source string) ([]models.Annotation, error) {
+	rows, err := dbInstance.db.Query(`
+		select a.id, p.document_id, p.page_num, a.source, a.author, a.created_at, a.predictions
+		from annotations a
+			join pages p on p.id = a.page_id
+		where p.document_id = ? and p.page_num = ? and (? = '' or a.source = ?)
+		order by a.created_at desc, a.id desc
+	`, docId, pageNum, source, source)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	annotations := []models.Annotation{}
+	for rows.Next() {
+		annotation, err := scanAnnotation(rows)
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return nil, err
+	}
+	return annotations, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAnnotation(row scanner) (models.Annotation, error) {
+	var annotation models.Annotation
+	var serialisedPredictions string
+	err := row.Scan(
+		&annotation.Id,
+		&annotation.DocumentId,
+		&annotation.PageNum,
+		&annotation.Source,
+		&annotation.Author,
+		&annotation.CreatedAt,
+		&serialisedPredictions,
+	)
+	if err != nil {
+		return annotation, err
+	}
+	err = json.Unmarshal([]byte(serialisedPredictions), &annotation.Predictions)
+	return annotation, err
+}
+
+func GetAnnotation(docId int64, pageNum int, annotationId int64) (models.Annotation, error) {
+	row := dbInstance.db.QueryRow(`
+		select a.id, p.document_id, p.page_num, a.source, a.author, a.created_at, a.predictions
+		from annotations a
+			join pages p on p.id = a.page_id
+		where p.document_id = ? and p.page_num = ? and a.id = ?
+	`, docId, pageNum, annotationId)
+	return scanAnnotation(row)
+}
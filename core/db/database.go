@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -89,8 +90,10 @@ func (s *service) runMigrations() error {
 		return fmt.Errorf("failed to glob migration files: %v", err)
 	}
 
-	// Sort files to ensure correct order
-	sort.Strings(files)
+	// Sort files by version number, so V10 runs after V9
+	sort.Slice(files, func(i, j int) bool {
+		return migrationVersion(files[i]) < migrationVersion(files[j])
+	})
 
 	// Run pending migrations
 	for _, file := range files {
@@ -114,6 +117,10 @@ func (s *service) runMigrations() error {
 		// Execute migration
 		if _, err := tx.Exec(string(content)); err != nil {
 			tx.Rollback()
+			// Search is created with fts5, which the sqlite driver only compiles in with a build tag
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				return fmt.Errorf("failed to execute migration %s: %v, the binary has to be built with -tags sqlite_fts5", name, err)
+			}
 			return fmt.Errorf("failed to execute migration %s: %v", name, err)
 		}
 
@@ -134,6 +141,17 @@ func (s *service) runMigrations() error {
 	return nil
 }
 
+// migrationVersion reads the number of a migration file named V{number}_{description}.sql.
+func migrationVersion(file string) int {
+	name := strings.TrimPrefix(filepath.Base(file), "V")
+	number, _, _ := strings.Cut(name, "_")
+	version, err := strconv.Atoi(number)
+	if err != nil {
+		log.Fatalf("invalid migration file name %s", file)
+	}
+	return version
+}
+
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -3,6 +3,8 @@ package db
 import (
 	"log"
 	"smart-docs/core/models"
+	"strings"
+	"time"
 )
 
 func ListDocuments(limit int, offset int, search string) ([]models.Document, error) {
@@ -15,7 +17,7 @@ func ListDocuments(limit int, offset int, search string) ([]models.Document, err
 			d.mode,
 			count(p.id) as page_count,
 			COUNT(CASE WHEN p.status = 'VALIDATION' THEN 1 END) AS validated_count,
-			COUNT(CASE WHEN p.status = 'TRAINING' THEN 1 END) AS in_progress_count
+			COUNT(CASE WHEN p.status in ('TRAINING', 'REVIEW') THEN 1 END) AS in_progress_count
 		from documents d
 			left join pages p on d.id = p.document_id`
 
@@ -23,6 +25,10 @@ func ListDocuments(limit int, offset int, search string) ([]models.Document, err
 	if search != "" {
 		query += ` where d.name LIKE ?`
 		args = append(args, "%"+search+"%")
+		if contentSearch := ftsQuery(search); contentSearch != "" {
+			query += ` or d.id in (select document_id from page_search where page_search match ?)`
+			args = append(args, contentSearch)
+		}
 	}
 
 	query += `
@@ -64,29 +70,48 @@ func LoadDocument(docId int64) (models.Document, error) {
 			    d.status,
 			    d.mode,
 			    d.mistral_file_id,
+			    pr.name,
 			    count(p.id) as page_count,
 				COUNT(CASE WHEN p.status = 'VALIDATION' THEN 1 END) AS validated_count,
-				COUNT(CASE WHEN p.status = 'TRAINING' THEN 1 END) AS in_progress_count
+				COUNT(CASE WHEN p.status in ('TRAINING', 'REVIEW') THEN 1 END) AS in_progress_count
 			from documents d
+				join projects pr on pr.id = d.project_id
 				left join pages p on d.id = p.document_id
 			where d.id = ?
-			group by d.id, d.name, d.upload_date, d.status, d.mode, d.mistral_file_id`, docId).Scan(&doc.Id, &doc.Name, &doc.UploadDate, &doc.Status, &doc.Mode, &doc.MistralFileId, &doc.PageCount, &doc.Validated, &doc.InProgress)
+			group by d.id, d.name, d.upload_date, d.status, d.mode, d.mistral_file_id, pr.name`, docId).Scan(&doc.Id, &doc.Name, &doc.UploadDate, &doc.Status, &doc.Mode, &doc.MistralFileId, &doc.Project, &doc.PageCount, &doc.Validated, &doc.InProgress)
 	if err != nil {
 		return doc, err
 	}
 	return doc, nil
 }
 
+// FindDocumentByName returns the most recently uploaded document with the given file name.
+func FindDocumentByName(name string) (int64, error) {
+	var docId int64
+	err := dbInstance.db.QueryRow(`
+		select id from documents where name = ? order by upload_date desc limit 1
+	`, name).Scan(&docId)
+	if err != nil {
+		return -1, err
+	}
+	return docId, nil
+}
+
+// StoreDocument adds the document to its project, the default project when none is set.
 func StoreDocument(doc *models.Document) (int64, error) {
+	if doc.Project == "" {
+		doc.Project = DefaultProject
+	}
 	res, err := dbInstance.db.Exec(`
 		INSERT INTO documents (
 			name,
 			status,
 			upload_date,
 			ocr_required,
-			mode
-		) VALUES (?, ?, ?, ?, ?)
-	`, doc.Name, doc.Status, doc.UploadDate, doc.OcrRequired, doc.Mode)
+			mode,
+			project_id
+		) VALUES (?, ?, ?, ?, ?, (select id from projects where name = ?))
+	`, doc.Name, doc.Status, doc.UploadDate, doc.OcrRequired, doc.Mode, doc.Project)
 	if err != nil {
 		return -1, err
 	}
@@ -113,3 +138,69 @@ func UpdateMistralFileId(docId int64, fileId string) error {
 	}
 	return nil
 }
+
+// FilterDocuments lists documents matching the filter, the most recently uploaded first.
+func FilterDocuments(filter models.DocumentFilter) ([]models.Document, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, `d.status = ?`)
+		args = append(args, filter.Status)
+	}
+	if filter.Mode != "" {
+		conditions = append(conditions, `d.mode = ?`)
+		args = append(args, filter.Mode)
+	}
+	if filter.Project != "" {
+		conditions = append(conditions, `pr.name = ?`)
+		args = append(args, filter.Project)
+	}
+	// Upload dates are stored in local time
+	if !filter.From.IsZero() {
+		conditions = append(conditions, `d.upload_date >= ?`)
+		args = append(args, filter.From.In(time.Local))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, `d.upload_date < ?`)
+		args = append(args, filter.To.In(time.Local))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = `where ` + strings.Join(conditions, ` and `)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := dbInstance.db.Query(`
+		select
+			d.id,
+			d.name,
+			d.upload_date,
+			d.status,
+			d.mode,
+			pr.name,
+			count(p.id) as page_count,
+			COUNT(CASE WHEN p.status = 'VALIDATION' THEN 1 END) AS validated_count,
+			COUNT(CASE WHEN p.status in ('TRAINING', 'REVIEW') THEN 1 END) AS in_progress_count
+		from documents d
+			join projects pr on pr.id = d.project_id
+			left join pages p on d.id = p.document_id
+		`+where+`
+		group by d.id, d.name, d.upload_date, d.status, d.mode, pr.name
+		order by d.upload_date desc, d.id desc
+		limit ? offset ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []models.Document{}
+	for rows.Next() {
+		var doc models.Document
+		err := rows.Scan(&doc.Id, &doc.Name, &doc.UploadDate, &doc.Status, &doc.Mode, &doc.Project, &doc.PageCount, &doc.Validated, &doc.InProgress)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+	return documents, rows.Err()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"time"
+)
+
+// ListEntitySpans returns the entity spans of a page in text order, without their words.
+func ListEntitySpans(docId int64, pageNum int) ([]models.EntitySpan, error) {
+	return queryEntitySpans(`p.document_id = ? and p.page_num = ?`, docId, pageNum)
+}
+
+// ProjectEntitySpans returns the entity spans of every page of a project, ordered by document, page and position.
+func ProjectEntitySpans(project string) ([]models.EntitySpan, error) {
+	return queryEntitySpans(`p.document_id in (
+			select d.id from documents d join projects pr on pr.id = d.project_id where pr.name = ?
+		)`, project)
+}
+
+func queryEntitySpans(condition string, args ...any) ([]models.EntitySpan, error) {
+	rows, err := dbInstance.db.Query(`
+		select s.id, p.document_id, p.page_num, s.label, s.start_word, s.end_word, s.author, s.created_at
+		from entity_spans s
+			join pages p on p.id = s.page_id
+		where `+condition+`
+		order by p.document_id, p.page_num, s.start_word
+	`, args...)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	spans := []models.EntitySpan{}
+	for rows.Next() {
+		var span models.EntitySpan
+		err := rows.Scan(&span.Id, &span.DocumentId, &span.PageNum, &span.Label, &span.Start, &span.End, &span.Author, &span.CreatedAt)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		spans = append(spans, span)
+	}
+	return spans, rows.Err()
+}
+
+func StoreEntitySpan(span *models.EntitySpan) error {
+	span.CreatedAt = time.Now()
+	return dbInstance.db.QueryRow(`
+		insert into entity_spans (page_id, label, start_word, end_word, author, created_at)
+		select id, ?, ?, ?, ?, ? from pages where document_id = ? and page_num = ?
+		returning id
+	`, span.Label, span.Start, span.End, span.Author, span.CreatedAt, span.DocumentId, span.PageNum).Scan(&span.Id)
+}
+
+// DeleteEntitySpan removes a span of a page, sql.ErrNoRows when the page has no such span.
+func DeleteEntitySpan(docId int64, pageNum int, spanId int64) error {
+	res, err := dbInstance.db.Exec(`
+		delete from entity_spans where id = ? and page_id = (select id from pages where document_id = ? and page_num = ?)
+	`, spanId, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// shiftEntitySpans follows a word correction replacing count words at index by inserted words.
+func shiftEntitySpans(tx *sql.Tx, pageId int64, index int, count int, inserted int) error {
+	rows, err := tx.Query(`select id, start_word, end_word from entity_spans where page_id = ?`, pageId)
+	if err != nil {
+		return err
+	}
+	var spans []models.EntitySpan
+	for rows.Next() {
+		var span models.EntitySpan
+		err = rows.Scan(&span.Id, &span.Start, &span.End)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		spans = append(spans, span)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, span := range spans {
+		start, end, ok := shiftSpan(span.Start, span.End, index, count, inserted)
+		if !ok {
+			_, err = tx.Exec(`delete from entity_spans where id = ?`, span.Id)
+		} else if start != span.Start || end != span.End {
+			_, err = tx.Exec(`update entity_spans set start_word = ?, end_word = ? where id = ?`, start, end, span.Id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shiftSpan moves the words start to end of a span along with a correction replacing count words at index by inserted
+// words. Spans after the replaced words move, spans reaching into them are cut to the inserted words and ok is false for
+// spans left without words.
+func shiftSpan(start int, end int, index int, count int, inserted int) (int, int, bool) {
+	move := func(word int, inside int) int {
+		switch {
+		case word < index:
+			return word
+		case word >= index+count:
+			return word + inserted - count
+		default:
+			return inside
+		}
+	}
+	start, end = move(start, index), move(end, index+inserted-1)
+	return start, end, start <= end
+}
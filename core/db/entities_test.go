@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestShiftSpan(t *testing.T) {
+	tests := []struct {
+		name                   string
+		start, end             int
+		index, count, inserted int
+		wantStart, wantEnd     int
+		wantOk                 bool
+	}{
+		{"before the edit", 0, 1, 3, 1, 1, 0, 1, true},
+		{"after an edited word", 4, 5, 3, 1, 1, 4, 5, true},
+		{"after a split", 4, 5, 3, 1, 2, 5, 6, true},
+		{"after a merge", 4, 5, 1, 3, 1, 2, 3, true},
+		{"after an added word", 3, 4, 3, 0, 1, 4, 5, true},
+		{"after a deleted word", 4, 5, 3, 1, 0, 3, 4, true},
+		{"around a merge", 0, 5, 1, 3, 1, 0, 3, true},
+		{"ending in a merge", 0, 2, 1, 3, 1, 0, 1, true},
+		{"starting in a merge", 2, 5, 1, 3, 1, 1, 3, true},
+		{"on a split word", 3, 3, 3, 1, 2, 3, 4, true},
+		{"ending on a deleted word", 1, 3, 3, 1, 0, 1, 2, true},
+		{"starting on a deleted word", 3, 5, 3, 1, 0, 3, 4, true},
+		{"on a deleted word", 3, 3, 3, 1, 0, 0, 0, false},
+		{"on deleted words", 3, 4, 3, 2, 0, 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, ok := shiftSpan(test.start, test.end, test.index, test.count, test.inserted)
+			if ok != test.wantOk || (ok && (start != test.wantStart || end != test.wantEnd)) {
+				t.Errorf("shiftSpan() = %d, %d, %v, want %d, %d, %v", start, end, ok, test.wantStart, test.wantEnd, test.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"strings"
+	"time"
+)
+
+// ListFields returns the key-value pairs of a page in reading order.
+func ListFields(docId int64, pageNum int) ([]models.Field, error) {
+	return queryFields(`p.document_id = ? and p.page_num = ?`, docId, pageNum)
+}
+
+// ListDocumentFields returns the key-value pairs of every page of a document, ordered by page.
+func ListDocumentFields(docId int64) ([]models.Field, error) {
+	return queryFields(`p.document_id = ?`, docId)
+}
+
+func queryFields(condition string, args ...any) ([]models.Field, error) {
+	rows, err := dbInstance.db.Query(`
+		select f.id, p.document_id, p.page_num, f.key, f.value, f.key_box, f.value_box, f.source, f.author, f.updated_at
+		from page_fields f
+			join pages p on p.id = f.page_id
+		where not f.removed and `+condition+`
+		order by p.document_id, p.page_num, f.position, f.id
+	`, args...)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []models.Field{}
+	for rows.Next() {
+		var field models.Field
+		var keyBox, valueBox string
+		err := rows.Scan(&field.Id, &field.DocumentId, &field.PageNum, &field.Key, &field.Value, &keyBox, &valueBox, &field.Source, &field.Author, &field.UpdatedAt)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(keyBox), &field.KeyBox); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(valueBox), &field.ValueBox); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
+
+// ReplaceExtractedFields stores the fields extracted from a page in place of the ones extracted before.
+// Extracted fields with the key of a field edited or removed by an annotator are dropped.
+func ReplaceExtractedFields(docId int64, pageNum int, fields []models.Field) error {
+	tx, err := dbInstance.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pageId int64
+	err = tx.QueryRow(`select id from pages where document_id = ? and page_num = ?`, docId, pageNum).Scan(&pageId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`delete from page_fields where page_id = ? and source = ?`, pageId, models.SourceModel)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`select key from page_fields where page_id = ?`, pageId)
+	if err != nil {
+		return err
+	}
+	edited := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+		edited[strings.ToLower(key)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		if edited[strings.ToLower(field.Key)] {
+			continue
+		}
+		err = insertField(tx, pageId, i, field)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// StoreField adds a field to the end of a page, sql.ErrNoRows when the page does not exist.
+func StoreField(field *models.Field) error {
+	tx, err := dbInstance.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pageId int64
+	var position int
+	err = tx.QueryRow(`
+		select p.id, coalesce((select max(position) + 1 from page_fields where page_id = p.id), 0)
+		from pages p where p.document_id = ? and p.page_num = ?
+	`, field.DocumentId, field.PageNum).Scan(&pageId, &position)
+	if err != nil {
+		return err
+	}
+	field.UpdatedAt = time.Now()
+	err = insertField(tx, pageId, position, *field)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(`select last_insert_rowid()`).Scan(&field.Id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertField(tx *sql.Tx, pageId int64, position int, field models.Field) error {
+	keyBox, err := json.Marshal(field.KeyBox)
+	if err != nil {
+		return err
+	}
+	valueBox, err := json.Marshal(field.ValueBox)
+	if err != nil {
+		return err
+	}
+	updatedAt := field.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+	_, err = tx.Exec(`
+		insert into page_fields (page_id, position, key, value, key_box, value_box, source, author, updated_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, pageId, position, field.Key, field.Value, string(keyBox), string(valueBox), field.Source, field.Author, updatedAt)
+	return err
+}
+
+// UpdateField changes the key, value and boxes of a field, sql.ErrNoRows when the page has no such field.
+func UpdateField(field *models.Field) error {
+	keyBox, err := json.Marshal(field.KeyBox)
+	if err != nil {
+		return err
+	}
+	valueBox, err := json.Marshal(field.ValueBox)
+	if err != nil {
+		return err
+	}
+	field.UpdatedAt = time.Now()
+	res, err := dbInstance.db.Exec(`
+		update page_fields
+		set key = ?, value = ?, key_box = ?, value_box = ?, source = ?, author = ?, updated_at = ?
+		where id = ? and not removed and page_id = (select id from pages where document_id = ? and page_num = ?)
+	`, field.Key, field.Value, string(keyBox), string(valueBox), field.Source, field.Author, field.UpdatedAt,
+		field.Id, field.DocumentId, field.PageNum)
+	if err != nil {
+		return err
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveField hides a field of a page, sql.ErrNoRows when the page has no such field. The field is kept as removed by
+// the author, so it is not extracted again.
+func RemoveField(docId int64, pageNum int, fieldId int64, author string) error {
+	res, err := dbInstance.db.Exec(`
+		update page_fields
+		set removed = true, source = ?, author = ?, updated_at = ?
+		where id = ? and not removed and page_id = (select id from pages where document_id = ? and page_num = ?)
+	`, models.SourceHuman, author, time.Now(), fieldId, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	if removed, _ := res.RowsAffected(); removed == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+)
+
+// DefaultProject holds documents uploaded without choosing a project.
+const DefaultProject = "default"
+
+func ListProjects() ([]string, error) {
+	rows, err := dbInstance.db.Query(`select name from projects order by id`)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		projects = append(projects, name)
+	}
+	return projects, rows.Err()
+}
+
+// LoadLabelSchema returns the labels of a project, sql.ErrNoRows when the project does not exist.
+func LoadLabelSchema(project string) (models.LabelSchema, error) {
+	var projectId int64
+	err := dbInstance.db.QueryRow(`select id from projects where name = ?`, project).Scan(&projectId)
+	if err != nil {
+		return models.LabelSchema{}, err
+	}
+	return loadLabels(project, projectId)
+}
+
+// DocumentLabelSchema returns the labels of the project the document belongs to.
+func DocumentLabelSchema(docId int64) (models.LabelSchema, error) {
+	var project string
+	var projectId int64
+	err := dbInstance.db.QueryRow(`
+		select p.id, p.name from documents d join projects p on p.id = d.project_id where d.id = ?
+	`, docId).Scan(&projectId, &project)
+	if err != nil {
+		return models.LabelSchema{}, err
+	}
+	return loadLabels(project, projectId)
+}
+
+func loadLabels(project string, projectId int64) (models.LabelSchema, error) {
+	rows, err := dbInstance.db.Query(`
+		select name, color, parents, renderer from labels where project_id = ? order by position, id
+	`, projectId)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return models.LabelSchema{}, err
+	}
+	defer rows.Close()
+
+	schema := models.LabelSchema{Project: project, Labels: []models.Label{}}
+	err = dbInstance.db.QueryRow(`select value_locale from projects where id = ?`, projectId).Scan(&schema.Locale)
+	if err != nil {
+		return models.LabelSchema{}, err
+	}
+	for rows.Next() {
+		var label models.Label
+		var serialisedParents string
+		err := rows.Scan(&label.Name, &label.Color, &serialisedParents, &label.Renderer)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return models.LabelSchema{}, err
+		}
+		err = json.Unmarshal([]byte(serialisedParents), &label.Parents)
+		if err != nil {
+			return models.LabelSchema{}, err
+		}
+		schema.Labels = append(schema.Labels, label)
+	}
+	if err := rows.Err(); err != nil {
+		return models.LabelSchema{}, err
+	}
+
+	entityRows, err := dbInstance.db.Query(`
+		select name, color from entity_labels where project_id = ? order by position, id
+	`, projectId)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return models.LabelSchema{}, err
+	}
+	defer entityRows.Close()
+
+	schema.Entities = []models.EntityLabel{}
+	for entityRows.Next() {
+		var entity models.EntityLabel
+		if err := entityRows.Scan(&entity.Name, &entity.Color); err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return models.LabelSchema{}, err
+		}
+		schema.Entities = append(schema.Entities, entity)
+	}
+	return schema, entityRows.Err()
+}
+
+// SaveLabelSchema replaces the labels and entity labels of a project, creating the project when it does not exist yet.
+func SaveLabelSchema(schema models.LabelSchema) error {
+	tx, err := dbInstance.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var projectId int64
+	err = tx.QueryRow(`select id from projects where name = ?`, schema.Project).Scan(&projectId)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(`insert into projects (name) values (?) returning id`, schema.Project).Scan(&projectId)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`update projects set value_locale = ? where id = ?`, schema.Locale, projectId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`delete from labels where project_id = ?`, projectId)
+	if err != nil {
+		return err
+	}
+	for position, label := range schema.Labels {
+		parents, err := json.Marshal(label.Parents)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			insert into labels (project_id, name, color, parents, renderer, position) values (?, ?, ?, ?, ?, ?)
+		`, projectId, label.Name, label.Color, string(parents), label.Renderer, position)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`delete from entity_labels where project_id = ?`, projectId)
+	if err != nil {
+		return err
+	}
+	for position, entity := range schema.Entities {
+		_, err = tx.Exec(`
+			insert into entity_labels (project_id, name, color, position) values (?, ?, ?, ?)
+		`, projectId, entity.Name, entity.Color, position)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -53,49 +54,43 @@ func StorePages(pages *[]models.Page) error {
 		if err != nil {
 			return err
 		}
+		err = reindexPage(page.DocumentId, page.PageNum)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func DeleteDocument(docId int64) error {
-	_, err := dbInstance.db.Exec(`delete from pages where document_id = ?`, docId)
+	_, err := dbInstance.db.Exec(`delete from page_search where document_id = ?`, docId)
 	if err != nil {
 		return err
 	}
-	_, err = dbInstance.db.Exec(`delete from documents where id = ?`, docId)
+	for _, table := range []string{"annotation_sets", "agreement_pages", "page_sampling", "word_edits", "entity_spans", "page_fields"} {
+		_, err = dbInstance.db.Exec(`delete from `+table+` where page_id in (select id from pages where document_id = ?)`, docId)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dbInstance.db.Exec(`delete from page_transitions where page_id in (select id from pages where document_id = ?)`, docId)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func NextPageToAnnotate(page *models.PageView) error {
-	err := dbInstance.db.QueryRow(`
-		select 
-		    p.id, 
-		    doc.name,
-		    doc.id,
-		    p.status,
-		    p.page_num,
-		    p.page_num - 1,
-		    p.page_num + 1,
-		    coalesce(p.page_num > 0, false),
-		    coalesce(doc.page_count - 1 > p.page_num, false)
-		from documents doc
-			left join pages p on doc.id = p.document_id
-		where p.status = 'TRAINING'
-	`).Scan(
-		&page.Id,
-		&page.DocumentName,
-		&page.DocumentId,
-		&page.Status,
-		&page.PageNum,
-		&page.PreviousPage,
-		&page.NextPage,
-		&page.HasPreviousPage,
-		&page.HasNextPage,
-	)
+	_, err = dbInstance.db.Exec(`delete from page_leases where page_id in (select id from pages where document_id = ?)`, docId)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`delete from annotations where page_id in (select id from pages where document_id = ?)`, docId)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`delete from pages where document_id = ?`, docId)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`delete from documents where id = ?`, docId)
 	if err != nil {
 		return err
 	}
@@ -144,6 +139,100 @@ func LoadPage(docId int64, pageNum int, page *models.PageView) error {
 	return nil
 }
 
+func LoadPages(docId int64) ([]models.Page, error) {
+	return queryPages(`p.document_id = ?`, docId)
+}
+
+// LoadPagesByStatus returns pages of all documents with the given status, ordered by document and page.
+func LoadPagesByStatus(status string) ([]models.Page, error) {
+	return queryPages(`p.status = ?`, status)
+}
+
+// LoadProjectPagesByStatus returns pages with the given status of documents in a project, ordered by document and page.
+func LoadProjectPagesByStatus(project string, status string) ([]models.Page, error) {
+	return queryPages(`p.status = ? and p.document_id in (
+			select d.id from documents d join projects pr on pr.id = d.project_id where pr.name = ?
+		)`, status, project)
+}
+
+// LoadPageContent returns a page with its words, annotations and rendered content, sql.ErrNoRows when it does not exist.
+func LoadPageContent(docId int64, pageNum int) (models.Page, error) {
+	pages, err := queryPages(`p.document_id = ? and p.page_num = ?`, docId, pageNum)
+	if err != nil {
+		return models.Page{}, err
+	}
+	if len(pages) == 0 {
+		return models.Page{}, sql.ErrNoRows
+	}
+	return pages[0], nil
+}
+
+func queryPages(condition string, args ...interface{}) ([]models.Page, error) {
+	rows, err := dbInstance.db.Query(`
+		select
+			p.id,
+			p.document_id,
+			p.page_num,
+			coalesce(p.pdf_text, ''),
+			coalesce(p.ocr_text, ''),
+			p.status,
+			coalesce(p.predictions, ''),
+			coalesce(p.html, ''),
+			coalesce(p.md, ''),
+			p.width,
+			p.height
+		from pages p
+		where `+condition+`
+		order by p.document_id, p.page_num
+	`, args...)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []models.Page
+	for rows.Next() {
+		var page models.Page
+		var serialisedWords string
+		var serialisedPredictions string
+		err := rows.Scan(
+			&page.Id,
+			&page.DocumentId,
+			&page.PageNum,
+			&serialisedWords,
+			&page.OcrText,
+			&page.Status,
+			&serialisedPredictions,
+			&page.Html,
+			&page.Md,
+			&page.Width,
+			&page.Height,
+		)
+		if err != nil {
+			log.Println(fmt.Sprintf("scan failed: %v", err))
+			return nil, err
+		}
+		if serialisedWords != "" {
+			if err := json.Unmarshal([]byte(serialisedWords), &page.PdfText); err != nil {
+				return nil, err
+			}
+		}
+		if serialisedPredictions != "" {
+			if err := json.Unmarshal([]byte(serialisedPredictions), &page.Predictions); err != nil {
+				return nil, err
+			}
+		}
+		pages = append(pages, page)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return nil, err
+	}
+	return pages, nil
+}
+
 func GetPredictions(docId int64, pageNum int) (string, error) {
 	var serialisedPredictions string
 	err := dbInstance.db.QueryRow(`
@@ -161,6 +250,15 @@ func GetPredictions(docId int64, pageNum int) (string, error) {
 	return serialisedPredictions, nil
 }
 
+// GetPageSize returns the size of the page image, sql.ErrNoRows when the page does not exist.
+func GetPageSize(docId int64, pageNum int) (int, int, error) {
+	var width, height int
+	err := dbInstance.db.QueryRow(`
+		select width, height from pages where document_id = ? and page_num = ?
+	`, docId, pageNum).Scan(&width, &height)
+	return width, height, err
+}
+
 func GetPdfPageText(docId int64, pageNum int) ([]models.WordData, error) {
 	var serialisedText string
 	err := dbInstance.db.QueryRow(`
@@ -191,7 +289,7 @@ func GetPdfPageText(docId int64, pageNum int) ([]models.WordData, error) {
 func GetNonValidatedPages(docId int64) ([]int, error) {
 	var pages []int
 	rows, err := dbInstance.db.Query(`
-		select page_num from pages where status != 'VALIDATION' and document_id = ?;
+		select page_num from pages where status not in ('VALIDATION', 'REVIEW') and document_id = ?;
 	`, docId)
 	if err != nil {
 		log.Println(fmt.Sprintf("query failed: %v", err))
@@ -254,28 +352,49 @@ func GetPdfDocText(docId int64) (string, error) {
 	return b.String(), nil
 }
 
-func UpdateTrainingStatus(docId int64, pageNum int, status string) error {
-	_, err := dbInstance.db.Exec(`
-		update pages set status = ? where document_id=? and page_num=?
-	`, status, docId, pageNum)
+func GetDocMarkdown(docId int64) (string, error) {
+	rows, err := dbInstance.db.Query(`
+		select p.page_num, coalesce(p.md, '')
+		from pages p
+		where p.document_id = ?
+		order by p.page_num
+	`, docId)
 	if err != nil {
-		return err
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return "", err
 	}
-	return nil
+	defer rows.Close()
+
+	var b strings.Builder
+	for rows.Next() {
+		var pageNum int
+		var md string
+		if err := rows.Scan(&pageNum, &md); err != nil {
+			log.Println(fmt.Sprintf("scan failed: %v", err))
+			return "", err
+		}
+		b.WriteString(fmt.Sprintf("<!-- page %d -->\n\n%s\n\n", pageNum, md))
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return "", err
+	}
+	return b.String(), nil
 }
 
-func UpdatePredictionsAndText(docId int64, pageNum int, predictions *[]models.Prediction, html *string) error {
+func UpdatePredictionsAndText(docId int64, pageNum int, predictions *[]models.Prediction, html *string, md *string) error {
 	serialisedPredictions, err := json.Marshal(*predictions)
 	if err != nil {
 		return err
 	}
 	_, err = dbInstance.db.Exec(`
 		update pages 
-		set predictions = ?, html = ?
+		set predictions = ?, html = ?, md = ?
 		where document_id=? and page_num=?
-	`, string(serialisedPredictions), html, docId, pageNum)
+	`, string(serialisedPredictions), html, md, docId, pageNum)
 	if err != nil {
 		return err
 	}
-	return nil
+	return reindexPage(docId, pageNum)
 }
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"strings"
+	"time"
+)
+
+// QueueCandidates returns pages waiting for annotation with their assignment and active lease.
+func QueueCandidates() ([]models.QueueItem, error) {
+	now := time.Now().UTC()
+	rows, err := dbInstance.db.Query(`
+		select p.id, doc.id, doc.name, p.page_num, doc.upload_date, coalesce(p.assignee, ''),
+		       coalesce(l.holder, ''), l.expires_at, coalesce(p.predictions, '[]'), coalesce(a.required_annotators, 0),
+		       coalesce((select group_concat(annotator, char(31)) from annotation_sets where page_id = p.id), ''),
+		       coalesce((select group_concat(annotator, char(31)) from annotation_sets where page_id = p.id and predictions is not null), ''),
+		       coalesce(s.reasons, '')
+		from pages p
+			join documents doc on doc.id = p.document_id
+			left join page_leases l on l.page_id = p.id and l.expires_at > ?
+			left join agreement_pages a on a.page_id = p.id
+			left join page_sampling s on s.page_id = p.id and s.selected_at is not null
+		where p.status = 'TRAINING'
+		order by p.id
+	`, now)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.QueueItem{}
+	for rows.Next() {
+		var item models.QueueItem
+		var uploadDate, expiresAt sql.NullTime
+		var serialisedPredictions, annotators, finished string
+		err := rows.Scan(
+			&item.PageId,
+			&item.DocumentId,
+			&item.DocumentName,
+			&item.PageNum,
+			&uploadDate,
+			&item.Assignee,
+			&item.LeasedBy,
+			&expiresAt,
+			&serialisedPredictions,
+			&item.RequiredAnnotators,
+			&annotators,
+			&finished,
+			&item.Reason,
+		)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		item.UploadDate = uploadDate.Time
+		item.Annotators = splitNames(annotators)
+		item.Finished = splitNames(finished)
+		if expiresAt.Valid {
+			item.LeaseExpiresAt = &expiresAt.Time
+		}
+		err = json.Unmarshal([]byte(serialisedPredictions), &item.Predictions)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return nil, err
+	}
+	return items, nil
+}
+
+func splitNames(names string) []string {
+	if names == "" {
+		return []string{}
+	}
+	return strings.Split(names, "\x1f")
+}
+
+// LabelCounts counts boxes per label over pages being annotated or already validated.
+func LabelCounts() (map[string]int, error) {
+	rows, err := dbInstance.db.Query(`
+		select json_extract(j.value, '$.label'), count(*)
+		from pages p, json_each(coalesce(p.predictions, '[]')) j
+		where p.status in ('TRAINING', 'VALIDATION')
+		group by 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var label sql.NullString
+		var count int
+		if err := rows.Scan(&label, &count); err != nil {
+			return nil, err
+		}
+		counts[label.String] += count
+	}
+	return counts, rows.Err()
+}
+
+// ClaimPage leases a page to the holder until the given time. It fails with false when another annotator holds an active lease.
+func ClaimPage(pageId int64, holder string, until time.Time) (bool, error) {
+	result, err := dbInstance.db.Exec(`
+		insert into page_leases (page_id, holder, expires_at) values (?, ?, ?)
+		on conflict (page_id) do update set holder = excluded.holder, expires_at = excluded.expires_at
+		where page_leases.holder = excluded.holder or page_leases.expires_at <= ?
+	`, pageId, holder, until.UTC(), time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// GetLease returns the active lease of a page, sql.ErrNoRows when nobody is annotating it.
+func GetLease(docId int64, pageNum int) (models.Lease, error) {
+	var lease models.Lease
+	err := dbInstance.db.QueryRow(`
+		select l.holder, l.expires_at
+		from page_leases l
+			join pages p on p.id = l.page_id
+		where p.document_id = ? and p.page_num = ? and l.expires_at > ?
+	`, docId, pageNum, time.Now().UTC()).Scan(&lease.Holder, &lease.ExpiresAt)
+	return lease, err
+}
+
+// ReleasePage drops the lease of a page. With an empty holder the lease is dropped whoever holds it.
+func ReleasePage(docId int64, pageNum int, holder string) error {
+	_, err := dbInstance.db.Exec(`
+		delete from page_leases
+		where page_id = (select id from pages where document_id = ? and page_num = ?) and (? = '' or holder = ?)
+	`, docId, pageNum, holder, holder)
+	return err
+}
+
+// AssignPage reserves a page in the queue for an annotator, an empty assignee puts it back to the shared queue.
+func AssignPage(docId int64, pageNum int, assignee string) error {
+	result, err := dbInstance.db.Exec(`
+		update pages set assignee = nullif(?, '') where document_id = ? and page_num = ?
+	`, assignee, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"time"
+)
+
+// UnsampledPages returns pages in PREDICTION the active learning policy has not evaluated yet.
+func UnsampledPages() ([]models.SamplingCandidate, error) {
+	rows, err := dbInstance.db.Query(`
+		select p.id, p.document_id, p.page_num, coalesce(p.predictions, '[]')
+		from pages p
+			join documents doc on doc.id = p.document_id
+			left join page_sampling s on s.page_id = p.id
+		where p.status = 'PREDICTION' and doc.mode = 'manual' and s.page_id is null
+		order by p.id
+	`)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []models.SamplingCandidate{}
+	for rows.Next() {
+		var candidate models.SamplingCandidate
+		var serialisedPredictions string
+		err := rows.Scan(&candidate.PageId, &candidate.DocumentId, &candidate.PageNum, &serialisedPredictions)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(serialisedPredictions), &candidate.Predictions)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+func StoreSamplingResult(pageId int64, reasons string, confidence float32) error {
+	_, err := dbInstance.db.Exec(`
+		insert into page_sampling (page_id, reasons, confidence, evaluated_at) values (?, ?, ?, ?)
+		on conflict (page_id) do update set reasons = excluded.reasons, confidence = excluded.confidence, evaluated_at = excluded.evaluated_at
+	`, pageId, reasons, confidence, time.Now())
+	return err
+}
+
+// SampledPages returns evaluated pages still in PREDICTION with a reason to be annotated, the least confident first.
+func SampledPages() ([]models.SampledPage, error) {
+	rows, err := dbInstance.db.Query(`
+		select p.id, p.document_id, p.page_num, s.reasons, s.confidence
+		from page_sampling s
+			join pages p on p.id = s.page_id
+		where p.status = 'PREDICTION' and s.reasons != '' and s.selected_at is null
+		order by s.confidence, p.id
+	`)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []models.SampledPage{}
+	for rows.Next() {
+		var page models.SampledPage
+		err := rows.Scan(&page.PageId, &page.DocumentId, &page.PageNum, &page.Reasons, &page.Confidence)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, rows.Err()
+}
+
+// CountSelectedSince counts pages moved to the training queue by the active learning policy since the given time.
+func CountSelectedSince(since time.Time) (int, error) {
+	var count int
+	err := dbInstance.db.QueryRow(`select count(*) from page_sampling where selected_at >= ?`, since).Scan(&count)
+	return count, err
+}
+
+func MarkSampled(pageId int64) error {
+	_, err := dbInstance.db.Exec(`update page_sampling set selected_at = ? where page_id = ?`, time.Now(), pageId)
+	return err
+}
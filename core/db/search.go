@@ -0,0 +1,172 @@
+package db
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"smart-docs/core/models"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Snippet boundaries are control characters, so the snippet can be html escaped before they are turned into tags.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// Page text is taken from the words used for parsing (ocr when available) and falls back to markdown for mistral pages.
+const pageSearchContent = `
+	coalesce(nullif((select group_concat(json_extract(w.value, '$.Text'), ' ')
+					 from json_each(case when p.ocr_text <> '' then p.ocr_text else p.pdf_text end) w), ''),
+			 p.md, '')`
+
+func reindexPage(docId int64, pageNum int) error {
+	_, err := dbInstance.db.Exec(`
+		delete from page_search where rowid in (select id from pages where document_id = ? and page_num = ?)
+	`, docId, pageNum)
+	if err != nil {
+		return err
+	}
+	_, err = dbInstance.db.Exec(`
+		insert into page_search (rowid, document_id, page_num, content)
+		select p.id, p.document_id, p.page_num, `+pageSearchContent+`
+		from pages p
+		where p.document_id = ? and p.page_num = ?
+	`, docId, pageNum)
+	return err
+}
+
+// ftsQuery turns free text into an fts5 query matching all terms, the last one as a prefix.
+// Every term is quoted, so user input can never be interpreted as fts5 syntax.
+func ftsQuery(search string) string {
+	terms := strings.Fields(search)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	if len(terms) > 0 {
+		terms[len(terms)-1] += "*"
+	}
+	return strings.Join(terms, " ")
+}
+
+func SearchPages(search string, limit int, offset int) ([]models.SearchHit, error) {
+	query := ftsQuery(search)
+	if query == "" {
+		return []models.SearchHit{}, nil
+	}
+	rows, err := dbInstance.db.Query(`
+		select
+			d.id,
+			d.name,
+			s.page_num,
+			p.width,
+			p.height,
+			snippet(page_search, 0, ?, ?, '…', 16)
+		from page_search s
+			join documents d on d.id = s.document_id
+			join pages p on p.id = s.rowid
+		where page_search match ?
+		order by rank
+		limit ? offset ?
+	`, snippetStart, snippetEnd, query, limit, offset)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []models.SearchHit{}
+	for rows.Next() {
+		var hit models.SearchHit
+		var snippet string
+		err := rows.Scan(&hit.DocumentId, &hit.DocumentName, &hit.PageNum, &hit.Width, &hit.Height, &snippet)
+		if err != nil {
+			return nil, err
+		}
+		hit.Snippet = highlightSnippet(snippet)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return nil, err
+	}
+
+	for i := range hits {
+		hits[i].Rects, err = PageHighlights(hits[i].DocumentId, hits[i].PageNum, search)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hits, nil
+}
+
+// FirstMatchingPage returns the first page of a document whose content matches the search.
+func FirstMatchingPage(docId int64, search string) (int, error) {
+	var pageNum int
+	err := dbInstance.db.QueryRow(`
+		select page_num from page_search
+		where page_search match ? and document_id = ?
+		order by page_num
+		limit 1
+	`, ftsQuery(search), docId).Scan(&pageNum)
+	if err != nil {
+		return 0, err
+	}
+	return pageNum, nil
+}
+
+// PageHighlights returns bounding boxes of the page words matching the search terms.
+// Words are compared the same way the fts5 tokenizer does: case and diacritics insensitive, last term as a prefix.
+func PageHighlights(docId int64, pageNum int, search string) ([]models.Rect, error) {
+	words, err := GetPdfPageText(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTokens(search)
+	rects := []models.Rect{}
+	if len(terms) == 0 {
+		return rects, nil
+	}
+	for _, word := range words {
+		if matchesAnyTerm(searchTokens(word.Text), terms) {
+			rects = append(rects, word.Rect)
+		}
+	}
+	return rects, nil
+}
+
+func matchesAnyTerm(tokens []string, terms []string) bool {
+	for _, token := range tokens {
+		for i, term := range terms {
+			if token == term || (i == len(terms)-1 && strings.HasPrefix(token, term)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func searchTokens(text string) []string {
+	// Transformers keep state, so a new chain is needed for every call.
+	foldDiacritics := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(foldDiacritics, strings.ToLower(text))
+	if err != nil {
+		folded = strings.ToLower(text)
+	}
+	return strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func highlightSnippet(snippet string) template.HTML {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, snippetEnd, "</mark>")
+	return template.HTML(escaped)
+}
@@ -0,0 +1,23 @@
+package db
+
+import "testing"
+
+func TestFtsQuery(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"invoice", `"invoice"*`},
+		{"total amount", `"total" "amount"*`},
+		{`say "hi"`, `"say" """hi"""*`},
+		{"a OR b", `"a" "OR" "b"*`},
+		{"col:value NEAR(x)", `"col:value" "NEAR(x)"*`},
+	}
+	for _, test := range tests {
+		if got := ftsQuery(test.search); got != test.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", test.search, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"time"
+)
+
+// ChangeStatus moves a page from one status to another and records who did it and why.
+// It fails with sql.ErrNoRows when the page is no longer in the expected status.
+func ChangeStatus(docId int64, pageNum int, from string, to string, user string, reason string) error {
+	tx, err := dbInstance.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pageId int64
+	err = tx.QueryRow(`
+		update pages set status = ? where document_id = ? and page_num = ? and coalesce(status, '') = ? returning id
+	`, to, docId, pageNum, from).Scan(&pageId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		insert into page_transitions (page_id, from_status, to_status, user, reason, created_at) values (?, ?, ?, ?, ?, ?)
+	`, pageId, from, to, user, reason, time.Now())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListTransitions returns the status changes of a page, oldest first.
+func ListTransitions(docId int64, pageNum int) ([]models.StatusChange, error) {
+	rows, err := dbInstance.db.Query(`
+		select t.id, p.document_id, p.page_num, t.from_status, t.to_status, t.user, t.reason, t.created_at
+		from page_transitions t
+			join pages p on p.id = t.page_id
+		where p.document_id = ? and p.page_num = ?
+		order by t.created_at, t.id
+	`, docId, pageNum)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.StatusChange{}
+	for rows.Next() {
+		var change models.StatusChange
+		err := rows.Scan(
+			&change.Id,
+			&change.DocumentId,
+			&change.PageNum,
+			&change.From,
+			&change.To,
+			&change.User,
+			&change.Reason,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(fmt.Sprintf("row iteration failed: %v", err))
+		return nil, err
+	}
+	return changes, nil
+}
+
+// LastTransition returns the most recent change of a page to the given status, sql.ErrNoRows when there is none.
+func LastTransition(docId int64, pageNum int, to string) (models.StatusChange, error) {
+	var change models.StatusChange
+	err := dbInstance.db.QueryRow(`
+		select t.id, p.document_id, p.page_num, t.from_status, t.to_status, t.user, t.reason, t.created_at
+		from page_transitions t
+			join pages p on p.id = t.page_id
+		where p.document_id = ? and p.page_num = ? and t.to_status = ?
+		order by t.created_at desc, t.id desc
+		limit 1
+	`, docId, pageNum, to).Scan(
+		&change.Id,
+		&change.DocumentId,
+		&change.PageNum,
+		&change.From,
+		&change.To,
+		&change.User,
+		&change.Reason,
+		&change.CreatedAt,
+	)
+	return change, err
+}
+
+// ReviewQueue lists pages waiting for review, the longest waiting first.
+func ReviewQueue() ([]models.ReviewItem, error) {
+	rows, err := dbInstance.db.Query(`
+		select doc.id, doc.name, p.page_num, t.user, t.created_at
+		from pages p
+			join documents doc on doc.id = p.document_id
+			join page_transitions t on t.id = (
+				select max(id) from page_transitions where page_id = p.id and to_status = 'REVIEW'
+			)
+		where p.status = 'REVIEW'
+		order by t.created_at
+	`)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ReviewItem{}
+	for rows.Next() {
+		var item models.ReviewItem
+		var submittedAt sql.NullTime
+		err := rows.Scan(&item.DocumentId, &item.DocumentName, &item.PageNum, &item.SubmittedBy, &submittedAt)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		item.SubmittedAt = submittedAt.Time
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func GetPageStatus(docId int64, pageNum int) (string, error) {
+	var status sql.NullString
+	err := dbInstance.db.QueryRow(`
+		select status from pages where document_id = ? and page_num = ?
+	`, docId, pageNum).Scan(&status)
+	return status.String, err
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"smart-docs/core/models"
+	"strings"
+	"time"
+)
+
+func StoreWebhook(webhook *models.Webhook) (int64, error) {
+	res, err := dbInstance.db.Exec(`
+		insert into webhooks (url, secret, events, created_by, created_at) values (?, ?, ?, ?, ?)
+	`, webhook.Url, webhook.Secret, strings.Join(webhook.Events, ","), webhook.CreatedBy, webhook.CreatedAt)
+	if err != nil {
+		return -1, err
+	}
+	webhook.Id, _ = res.LastInsertId()
+	return webhook.Id, nil
+}
+
+// ListWebhooks returns the webhooks with their secrets, oldest first.
+func ListWebhooks() ([]models.Webhook, error) {
+	return queryWebhooks(`1 = 1`)
+}
+
+// LoadWebhook returns a webhook with its secret, sql.ErrNoRows when it does not exist.
+func LoadWebhook(webhookId int64) (models.Webhook, error) {
+	webhooks, err := queryWebhooks(`id = ?`, webhookId)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if len(webhooks) == 0 {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	return webhooks[0], nil
+}
+
+func queryWebhooks(condition string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := dbInstance.db.Query(`
+		select id, url, secret, events, created_by, created_at from webhooks where `+condition+` order by id
+	`, args...)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		var events string
+		err := rows.Scan(&webhook.Id, &webhook.Url, &webhook.Secret, &events, &webhook.CreatedBy, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes a webhook with its deliveries, sql.ErrNoRows when it does not exist.
+func DeleteWebhook(webhookId int64) error {
+	_, err := dbInstance.db.Exec(`delete from webhook_deliveries where webhook_id = ?`, webhookId)
+	if err != nil {
+		return err
+	}
+	res, err := dbInstance.db.Exec(`delete from webhooks where id = ?`, webhookId)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// QueueDeliveries adds a pending delivery of the payload for every webhook subscribed to the event.
+func QueueDeliveries(event string, payload []byte) (int64, error) {
+	now := time.Now()
+	res, err := dbInstance.db.Exec(`
+		insert into webhook_deliveries (webhook_id, event, payload, created_at, next_attempt_at)
+		select id, ?, ?, ?, ? from webhooks where ',' || events || ',' like '%,' || ? || ',%'
+	`, event, string(payload), now, now, event)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RedeliverDelivery queues the payload of a delivery again as a new delivery, sql.ErrNoRows when it does not exist.
+func RedeliverDelivery(webhookId int64, deliveryId int64) (int64, error) {
+	now := time.Now()
+	var id int64
+	err := dbInstance.db.QueryRow(`
+		insert into webhook_deliveries (webhook_id, event, payload, created_at, next_attempt_at)
+		select webhook_id, event, payload, ?, ? from webhook_deliveries where webhook_id = ? and id = ?
+		returning id
+	`, now, now, webhookId, deliveryId).Scan(&id)
+	return id, err
+}
+
+// DueDeliveries returns pending deliveries whose next attempt is due, the oldest first.
+func DueDeliveries(limit int) ([]models.WebhookDelivery, error) {
+	return queryDeliveries(`d.status = ? and d.next_attempt_at <= ? order by d.next_attempt_at, d.id limit ?`,
+		models.DeliveryPending, time.Now(), limit)
+}
+
+// ListDeliveries returns the latest deliveries of a webhook, the newest first.
+func ListDeliveries(webhookId int64, limit int) ([]models.WebhookDelivery, error) {
+	return queryDeliveries(`d.webhook_id = ? order by d.id desc limit ?`, webhookId, limit)
+}
+
+// LoadDelivery returns a delivery of a webhook, sql.ErrNoRows when it does not exist.
+func LoadDelivery(webhookId int64, deliveryId int64) (models.WebhookDelivery, error) {
+	deliveries, err := queryDeliveries(`d.webhook_id = ? and d.id = ?`, webhookId, deliveryId)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return models.WebhookDelivery{}, sql.ErrNoRows
+	}
+	return deliveries[0], nil
+}
+
+func queryDeliveries(condition string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := dbInstance.db.Query(`
+		select d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts, d.response_status, d.error,
+			d.created_at, d.next_attempt_at, d.delivered_at
+		from webhook_deliveries d
+			join webhooks w on w.id = d.webhook_id
+		where `+condition, args...)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload string
+		var nextAttemptAt time.Time
+		err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Url, &delivery.Secret, &delivery.Event, &payload,
+			&delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.Error,
+			&delivery.CreatedAt, &nextAttemptAt, &delivery.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		if delivery.Status == models.DeliveryPending {
+			delivery.NextAttemptAt = &nextAttemptAt
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt stores the outcome of an attempt to send a delivery, next is when a pending delivery is tried again.
+func RecordAttempt(deliveryId int64, status string, responseStatus int, message string, next time.Time) error {
+	var deliveredAt *time.Time
+	if status == models.DeliveryDelivered {
+		now := time.Now()
+		deliveredAt = &now
+	}
+	_, err := dbInstance.db.Exec(`
+		update webhook_deliveries
+		set status = ?, attempts = attempts + 1, response_status = ?, error = ?, next_attempt_at = ?, delivered_at = ?
+		where id = ?
+	`, status, responseStatus, message, next, deliveredAt, deliveryId)
+	return err
+}
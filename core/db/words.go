@@ -0,0 +1,154 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"smart-docs/core/models"
+	"time"
+)
+
+// PageText is the word list of a page, taken from the OCR when the page was OCRed and from the pdf otherwise.
+type PageText struct {
+	PageId int64
+	Words  []models.WordData
+	// serialised is the stored form of the words, used to detect corrections made in the meantime.
+	// pdf_text is written as a blob by StorePages, it is compared as text.
+	serialised string
+}
+
+func LoadPageText(docId int64, pageNum int) (PageText, error) {
+	text := PageText{}
+	err := dbInstance.db.QueryRow(`
+		select id, case when ocr_text <> '' then ocr_text else coalesce(cast(pdf_text as text), '[]') end
+		from pages
+		where document_id = ? and page_num = ?
+	`, docId, pageNum).Scan(&text.PageId, &text.serialised)
+	if err != nil {
+		return text, err
+	}
+	err = json.Unmarshal([]byte(text.serialised), &text.Words)
+	return text, err
+}
+
+// SaveWordEdit replaces the words of a page and records the edit. The words as extracted are kept on the first correction
+// and entity spans are moved along with the words they tag, so the edit has to replace neighbouring words.
+// It fails with sql.ErrNoRows when the words were changed since they were loaded.
+func SaveWordEdit(text PageText, words []models.WordData, edit models.WordEdit) error {
+	if !splices(text.Words, words, edit) {
+		return fmt.Errorf("word edit at %d does not replace neighbouring words", edit.Index)
+	}
+	serialisedWords, err := json.Marshal(words)
+	if err != nil {
+		return err
+	}
+	before, err := json.Marshal(edit.Before)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(edit.After)
+	if err != nil {
+		return err
+	}
+
+	tx, err := dbInstance.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pageId int64
+	err = tx.QueryRow(`
+		update pages
+		set original_words = coalesce(original_words, ?),
+		    ocr_text = case when ocr_text <> '' then ? else ocr_text end,
+		    pdf_text = case when ocr_text <> '' then pdf_text else ? end
+		where id = ? and case when ocr_text <> '' then ocr_text else coalesce(cast(pdf_text as text), '[]') end = ?
+		returning id
+	`, text.serialised, string(serialisedWords), string(serialisedWords), text.PageId, text.serialised).Scan(&pageId)
+	if err != nil {
+		return err
+	}
+	err = shiftEntitySpans(tx, pageId, edit.Index, len(edit.Before), len(edit.After))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		insert into word_edits (page_id, operation, word_index, before, after, author, created_at) values (?, ?, ?, ?, ?, ?, ?)
+	`, pageId, edit.Operation, edit.Index, string(before), string(after), edit.Author, time.Now())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// splices tells whether the edit turns the words before into the words after by replacing the words at its index.
+func splices(before []models.WordData, after []models.WordData, edit models.WordEdit) bool {
+	end := edit.Index + len(edit.Before)
+	if edit.Index < 0 || end > len(before) {
+		return false
+	}
+	return slices.Equal(before[edit.Index:end], edit.Before) &&
+		slices.Equal(after, slices.Concat(before[:edit.Index], edit.After, before[end:]))
+}
+
+// ListWordEdits returns the word corrections of a page, oldest first.
+func ListWordEdits(docId int64, pageNum int) ([]models.WordEdit, error) {
+	rows, err := dbInstance.db.Query(`
+		select e.id, p.document_id, p.page_num, e.operation, e.word_index, e.before, e.after, e.author, e.created_at
+		from word_edits e
+			join pages p on p.id = e.page_id
+		where p.document_id = ? and p.page_num = ?
+		order by e.created_at, e.id
+	`, docId, pageNum)
+	if err != nil {
+		log.Println(fmt.Sprintf("query failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []models.WordEdit{}
+	for rows.Next() {
+		var edit models.WordEdit
+		var before, after string
+		err := rows.Scan(&edit.Id, &edit.DocumentId, &edit.PageNum, &edit.Operation, &edit.Index, &before, &after, &edit.Author, &edit.CreatedAt)
+		if err != nil {
+			log.Println(fmt.Sprintf("row scan failed: %v", err))
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(before), &edit.Before); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(after), &edit.After); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// LoadCorrectedPages returns pages of a project with corrected words, ordered by document and page.
+func LoadCorrectedPages(project string) ([]models.CorrectedPage, error) {
+	pages, err := queryPages(`p.original_words is not null and p.document_id in (
+			select d.id from documents d join projects pr on pr.id = d.project_id where pr.name = ?
+		)`, project)
+	if err != nil {
+		return nil, err
+	}
+	corrected := make([]models.CorrectedPage, 0, len(pages))
+	for _, page := range pages {
+		var serialised string
+		err := dbInstance.db.QueryRow(`select original_words from pages where id = ?`, page.Id).Scan(&serialised)
+		if err != nil {
+			return nil, err
+		}
+		correctedPage := models.CorrectedPage{Page: page}
+		err = json.Unmarshal([]byte(serialised), &correctedPage.OriginalWords)
+		if err != nil {
+			return nil, err
+		}
+		corrected = append(corrected, correctedPage)
+	}
+	return corrected, nil
+}
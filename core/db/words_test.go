@@ -0,0 +1,34 @@
+package db
+
+import (
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestSplices(t *testing.T) {
+	a, b, c, d := models.WordData{Text: "a"}, models.WordData{Text: "b"}, models.WordData{Text: "c"}, models.WordData{Text: "d"}
+	bc := models.WordData{Text: "bc"}
+	words := []models.WordData{a, b, c, d}
+	tests := []struct {
+		name  string
+		after []models.WordData
+		edit  models.WordEdit
+		want  bool
+	}{
+		{"merge of neighbours", []models.WordData{a, bc, d}, models.WordEdit{Index: 1, Before: []models.WordData{b, c}, After: []models.WordData{bc}}, true},
+		{"added word", []models.WordData{a, b, bc, c, d}, models.WordEdit{Index: 2, After: []models.WordData{bc}}, true},
+		{"added last word", []models.WordData{a, b, c, d, bc}, models.WordEdit{Index: 4, After: []models.WordData{bc}}, true},
+		{"deleted word", []models.WordData{a, c, d}, models.WordEdit{Index: 1, Before: []models.WordData{b}}, true},
+		{"merge of words apart", []models.WordData{a, bc, c}, models.WordEdit{Index: 1, Before: []models.WordData{b, d}, After: []models.WordData{bc}}, false},
+		{"words other than the edited ones", []models.WordData{a, bc}, models.WordEdit{Index: 1, Before: []models.WordData{b, c}, After: []models.WordData{bc}}, false},
+		{"index outside of the words", []models.WordData{a, b, c, d}, models.WordEdit{Index: 4, Before: []models.WordData{d}, After: []models.WordData{d}}, false},
+		{"negative index", []models.WordData{a, b, c, d}, models.WordEdit{Index: -1}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := splices(words, test.after, test.edit); got != test.want {
+				t.Errorf("splices() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
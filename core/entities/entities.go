@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"strings"
+)
+
+var ErrInvalidSpan = errors.New("invalid span")
+
+// Outside is the BIO tag of words outside of any span.
+const Outside = "O"
+
+// List returns the spans of a page with their words and text.
+func List(docId int64, pageNum int) ([]models.EntitySpan, error) {
+	text, err := db.LoadPageText(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	spans, err := db.ListEntitySpans(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	for i := range spans {
+		fill(&spans[i], text.Words)
+	}
+	return spans, nil
+}
+
+// Create tags words of a page with an entity label of the project. Spans cannot overlap, as BIO tags give each word
+// a single entity.
+func Create(docId int64, pageNum int, label string, start int, end int, author string) (models.EntitySpan, error) {
+	schema, err := db.DocumentLabelSchema(docId)
+	if err != nil {
+		return models.EntitySpan{}, err
+	}
+	if _, ok := schema.Entity(label); !ok {
+		return models.EntitySpan{}, fmt.Errorf("%w: unknown entity %q", ErrInvalidSpan, label)
+	}
+	text, err := db.LoadPageText(docId, pageNum)
+	if err != nil {
+		return models.EntitySpan{}, err
+	}
+	if start < 0 || end < start || end >= len(text.Words) {
+		return models.EntitySpan{}, fmt.Errorf("%w: words %d to %d are not on the page", ErrInvalidSpan, start, end)
+	}
+	spans, err := db.ListEntitySpans(docId, pageNum)
+	if err != nil {
+		return models.EntitySpan{}, err
+	}
+	for _, other := range spans {
+		if start <= other.End && other.Start <= end {
+			return models.EntitySpan{}, fmt.Errorf("%w: overlaps %s span %d", ErrInvalidSpan, other.Label, other.Id)
+		}
+	}
+
+	span := models.EntitySpan{
+		DocumentId: docId,
+		PageNum:    pageNum,
+		Label:      label,
+		Start:      start,
+		End:        end,
+		Author:     author,
+	}
+	err = db.StoreEntitySpan(&span)
+	if err != nil {
+		return models.EntitySpan{}, err
+	}
+	fill(&span, text.Words)
+	return span, nil
+}
+
+// Tags returns the BIO tag of each of count words: "B-label" for the first word of a span, "I-label" for the rest.
+func Tags(count int, spans []models.EntitySpan) []string {
+	tags := make([]string, count)
+	for i := range tags {
+		tags[i] = Outside
+	}
+	for _, span := range spans {
+		for i := span.Start; i <= span.End && i < count; i++ {
+			prefix := "I-"
+			if i == span.Start {
+				prefix = "B-"
+			}
+			tags[i] = prefix + span.Label
+		}
+	}
+	return tags
+}
+
+func fill(span *models.EntitySpan, words []models.WordData) {
+	span.Words = []models.PageWord{}
+	var texts []string
+	for i := span.Start; i <= span.End && i < len(words); i++ {
+		span.Words = append(span.Words, models.PageWord{Rect: words[i].Rect, Index: i, Text: words[i].Text})
+		texts = append(texts, words[i].Text)
+	}
+	span.Text = strings.Join(texts, " ")
+}
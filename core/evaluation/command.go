@@ -0,0 +1,56 @@
+package evaluation
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"smart-docs/core/pipeline"
+)
+
+// RunCommand evaluates a detector against validated pages from the command line:
+//
+//	smart-docs evaluate -source detector -doc-url http://localhost:10003 -format html -out report.html
+func RunCommand(args []string) error {
+	docUrl, tableUrl := pipeline.DetectorUrls()
+	flags := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	source := flags.String("source", SourceStored, "predictions to evaluate: stored detector output or a detector run")
+	flags.StringVar(&docUrl, "doc-url", docUrl, "layout detector to run with -source detector")
+	flags.StringVar(&tableUrl, "table-url", tableUrl, "table detector to run with -source detector")
+	threshold := flags.Float64("iou", 0.5, "minimum IoU of a prediction matching an annotation")
+	worst := flags.Int("worst", 20, "number of worst pages to report")
+	format := flags.String("format", "json", "report format: json or html")
+	out := flags.String("out", "", "report file, standard output when empty")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "json" && *format != "html" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	report, err := Evaluate(Options{
+		Source:       *source,
+		DocUrl:       docUrl,
+		TableUrl:     tableUrl,
+		IoUThreshold: float32(*threshold),
+		WorstPages:   *worst,
+	})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if *format == "html" {
+		return WriteHTML(w, report)
+	}
+	return WriteJSON(w, report)
+}
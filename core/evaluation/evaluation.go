@@ -0,0 +1,192 @@
+package evaluation
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"time"
+)
+
+const (
+	// SourceStored compares validated pages with the detector output stored when they were processed.
+	SourceStored = "stored"
+	// SourceDetector runs the configured detectors over validated pages again.
+	SourceDetector = "detector"
+)
+
+type Options struct {
+	Source       string
+	DocUrl       string
+	TableUrl     string
+	IoUThreshold float32
+	WorstPages   int
+}
+
+type Report struct {
+	CreatedAt    time.Time    `json:"createdAt"`
+	Source       string       `json:"source"`
+	DocUrl       string       `json:"docUrl,omitempty"`
+	TableUrl     string       `json:"tableUrl,omitempty"`
+	IoUThreshold float32      `json:"iouThreshold"`
+	Pages        int          `json:"pages"`
+	Skipped      []string     `json:"skipped"`
+	Layout       Metrics      `json:"layout"`
+	Cells        Metrics      `json:"cells"`
+	WorstPages   []PageResult `json:"worstPages"`
+}
+
+// Metrics of one detection task. MAP is measured at the report IoU threshold, MAPCoco averages IoU thresholds 0.5 to 0.95.
+type Metrics struct {
+	MAP     float64        `json:"mAP"`
+	MAPCoco float64        `json:"mAPCoco"`
+	Labels  []LabelMetrics `json:"labels"`
+}
+
+// LabelMetrics counts all predictions of a label regardless of their score.
+type LabelMetrics struct {
+	Label         string  `json:"label"`
+	GroundTruth   int     `json:"groundTruth"`
+	Predicted     int     `json:"predicted"`
+	TruePositives int     `json:"truePositives"`
+	Precision     float64 `json:"precision"`
+	Recall        float64 `json:"recall"`
+	AP            float64 `json:"ap"`
+}
+
+type PageResult struct {
+	DocumentId   int64   `json:"documentId"`
+	DocumentName string  `json:"documentName"`
+	PageNum      int     `json:"pageNum"`
+	GroundTruth  int     `json:"groundTruth"`
+	Predicted    int     `json:"predicted"`
+	Precision    float64 `json:"precision"`
+	Recall       float64 `json:"recall"`
+	F1           float64 `json:"f1"`
+	CellF1       float64 `json:"cellF1"`
+}
+
+// Evaluate compares detector output with the annotations of validated pages.
+func Evaluate(options Options) (Report, error) {
+	report := Report{
+		CreatedAt:    time.Now(),
+		Source:       options.Source,
+		IoUThreshold: options.IoUThreshold,
+		Skipped:      []string{},
+		WorstPages:   []PageResult{},
+	}
+	if options.Source == SourceDetector {
+		report.DocUrl = options.DocUrl
+		report.TableUrl = options.TableUrl
+	} else if options.Source != SourceStored {
+		return report, fmt.Errorf("unknown source %q", options.Source)
+	}
+	if options.IoUThreshold <= 0 || options.IoUThreshold > 1 {
+		return report, fmt.Errorf("IoU threshold must be between 0 and 1")
+	}
+
+	pages, err := db.LoadPagesByStatus("VALIDATION")
+	if err != nil {
+		return report, err
+	}
+
+	documentNames := map[int64]string{}
+	var layout, cells []boxes
+	var results []PageResult
+	for _, page := range pages {
+		key := fmt.Sprintf("%d_%d", page.DocumentId, page.PageNum)
+		predicted, err := detectorOutput(page, options)
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if _, ok := documentNames[page.DocumentId]; !ok {
+			doc, err := db.LoadDocument(page.DocumentId)
+			if err != nil {
+				log.Println(fmt.Sprintf("Could not load document %d: %v", page.DocumentId, err))
+			}
+			documentNames[page.DocumentId] = doc.Name
+		}
+
+		pageLayout := boxes{Truth: layoutBoxes(page.Predictions), Predicted: layoutBoxes(predicted)}
+		pageCells := boxes{Truth: tableCells(page.Predictions), Predicted: tableCells(predicted)}
+		layout = append(layout, pageLayout)
+		cells = append(cells, pageCells)
+		results = append(results, pageResult(page, documentNames[page.DocumentId], pageLayout, pageCells, options.IoUThreshold))
+	}
+
+	report.Pages = len(layout)
+	report.Layout = computeMetrics(layout, options.IoUThreshold)
+	report.Cells = computeMetrics(cells, options.IoUThreshold)
+
+	slices.SortStableFunc(results, func(a, b PageResult) int {
+		return cmp.Or(cmp.Compare(a.F1, b.F1), cmp.Compare(a.CellF1, b.CellF1))
+	})
+	report.WorstPages = append(report.WorstPages, results[:min(len(results), max(options.WorstPages, 0))]...)
+	return report, nil
+}
+
+// detectorOutput returns the boxes detected on a page, shrunk to their words like the validated boxes they are
+// compared with.
+func detectorOutput(page models.Page, options Options) ([]models.Prediction, error) {
+	var predicted []models.Prediction
+	if options.Source == SourceDetector {
+		var err error
+		predicted, err = pipeline.RunDetectionWith(page.DocumentId, page.PageNum, options.DocUrl, options.TableUrl)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		annotations, err := db.ListAnnotations(page.DocumentId, page.PageNum, models.SourceModel)
+		if err != nil {
+			return nil, err
+		}
+		if len(annotations) == 0 {
+			return nil, fmt.Errorf("no stored detector output")
+		}
+		predicted = annotations[0].Predictions
+	}
+	words, err := db.GetPdfPageText(page.DocumentId, page.PageNum)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.AlignToWords(page.DocumentId, words, predicted), nil
+}
+
+func pageResult(page models.Page, documentName string, layout boxes, cells boxes, threshold float32) PageResult {
+	tp := countMatched(match(layout, threshold))
+	precision := ratio(tp, len(layout.Predicted))
+	recall := ratio(tp, len(layout.Truth))
+	result := PageResult{
+		DocumentId:   page.DocumentId,
+		DocumentName: documentName,
+		PageNum:      page.PageNum,
+		GroundTruth:  len(layout.Truth),
+		Predicted:    len(layout.Predicted),
+		Precision:    precision,
+		Recall:       recall,
+		F1:           f1(precision, recall),
+		CellF1:       1,
+	}
+	if len(layout.Truth) == 0 && len(layout.Predicted) == 0 {
+		result.F1 = 1
+	}
+	if len(cells.Truth) > 0 || len(cells.Predicted) > 0 {
+		cellTp := countMatched(match(cells, threshold))
+		result.CellF1 = f1(ratio(cellTp, len(cells.Predicted)), ratio(cellTp, len(cells.Truth)))
+	}
+	return result
+}
+
+func countMatched(matched []bool) int {
+	count := 0
+	for _, m := range matched {
+		if m {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,227 @@
+package evaluation
+
+import (
+	"cmp"
+	"slices"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+// COCO style mAP averages over these IoU thresholds.
+var cocoThresholds = []float32{0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+// boxes holds the ground truth and the detector output of a page, or of the table cells of a page.
+type boxes struct {
+	Truth     []models.Prediction
+	Predicted []models.Prediction
+}
+
+type detection struct {
+	Score   float32
+	Matched bool
+}
+
+type labelStats struct {
+	Truth      int
+	Detections []detection
+}
+
+// match pairs predictions with ground truth boxes of the same label, the most confident prediction first,
+// and reports which predictions found a box with IoU of at least the threshold.
+func match(b boxes, threshold float32) []bool {
+	order := make([]int, len(b.Predicted))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return cmp.Compare(b.Predicted[j].Score, b.Predicted[i].Score)
+	})
+
+	matched := make([]bool, len(b.Predicted))
+	used := make([]bool, len(b.Truth))
+	for _, i := range order {
+		prediction := b.Predicted[i]
+		best := -1
+		bestIoU := threshold
+		for t, truth := range b.Truth {
+			if used[t] || truth.Label != prediction.Label {
+				continue
+			}
+			iou := pipeline.IoU(prediction.Rect, truth.Rect)
+			if iou >= bestIoU {
+				best = t
+				bestIoU = iou
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			matched[i] = true
+		}
+	}
+	return matched
+}
+
+func collectStats(pages []boxes, threshold float32) map[string]*labelStats {
+	stats := map[string]*labelStats{}
+	statsOf := func(label string) *labelStats {
+		if _, ok := stats[label]; !ok {
+			stats[label] = &labelStats{}
+		}
+		return stats[label]
+	}
+	for _, page := range pages {
+		for _, truth := range page.Truth {
+			statsOf(truth.Label).Truth++
+		}
+		matched := match(page, threshold)
+		for i, prediction := range page.Predicted {
+			s := statsOf(prediction.Label)
+			s.Detections = append(s.Detections, detection{Score: prediction.Score, Matched: matched[i]})
+		}
+	}
+	return stats
+}
+
+// averagePrecision is the area under the interpolated precision-recall curve of the detections of a label.
+func averagePrecision(s labelStats) float64 {
+	if s.Truth == 0 {
+		return 0
+	}
+	detections := slices.Clone(s.Detections)
+	slices.SortStableFunc(detections, func(a, b detection) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	precisions := make([]float64, len(detections))
+	recalls := make([]float64, len(detections))
+	tp := 0
+	for i, d := range detections {
+		if d.Matched {
+			tp++
+		}
+		precisions[i] = float64(tp) / float64(i+1)
+		recalls[i] = float64(tp) / float64(s.Truth)
+	}
+	for i := len(precisions) - 2; i >= 0; i-- {
+		precisions[i] = max(precisions[i], precisions[i+1])
+	}
+
+	ap := 0.0
+	previousRecall := 0.0
+	for i := range detections {
+		ap += (recalls[i] - previousRecall) * precisions[i]
+		previousRecall = recalls[i]
+	}
+	return ap
+}
+
+func (s labelStats) matched() int {
+	count := 0
+	for _, d := range s.Detections {
+		if d.Matched {
+			count++
+		}
+	}
+	return count
+}
+
+func ratio(a int, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func f1(precision float64, recall float64) float64 {
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// computeMetrics reports precision, recall and AP per label at the threshold, with mAP over labels present in the ground truth.
+func computeMetrics(pages []boxes, threshold float32) Metrics {
+	stats := collectStats(pages, threshold)
+	metrics := Metrics{Labels: []LabelMetrics{}}
+	var labels []string
+	for label := range stats {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+
+	evaluated := 0
+	for _, label := range labels {
+		s := stats[label]
+		tp := s.matched()
+		ap := averagePrecision(*s)
+		metrics.Labels = append(metrics.Labels, LabelMetrics{
+			Label:         label,
+			GroundTruth:   s.Truth,
+			Predicted:     len(s.Detections),
+			TruePositives: tp,
+			Precision:     ratio(tp, len(s.Detections)),
+			Recall:        ratio(tp, s.Truth),
+			AP:            ap,
+		})
+		if s.Truth > 0 {
+			metrics.MAP += ap
+			evaluated++
+		}
+	}
+	if evaluated > 0 {
+		metrics.MAP /= float64(evaluated)
+	}
+
+	for _, t := range cocoThresholds {
+		metrics.MAPCoco += meanAP(collectStats(pages, t))
+	}
+	metrics.MAPCoco /= float64(len(cocoThresholds))
+	return metrics
+}
+
+func meanAP(stats map[string]*labelStats) float64 {
+	total := 0.0
+	evaluated := 0
+	for _, s := range stats {
+		if s.Truth > 0 {
+			total += averagePrecision(*s)
+			evaluated++
+		}
+	}
+	if evaluated == 0 {
+		return 0
+	}
+	return total / float64(evaluated)
+}
+
+// tableCells flattens the cells of all tables on a page, moving them to page coordinates.
+func tableCells(predictions []models.Prediction) []models.Prediction {
+	var cells []models.Prediction
+	for _, prediction := range predictions {
+		if prediction.Label != "table" {
+			continue
+		}
+		for _, cell := range prediction.Table {
+			cells = append(cells, models.Prediction{
+				Score: cell.Score,
+				Label: cell.Label,
+				Rect: models.Rect{
+					X0: cell.X0 + prediction.X0,
+					Y0: cell.Y0 + prediction.Y0,
+					X1: cell.X1 + prediction.X0,
+					Y1: cell.Y1 + prediction.Y0,
+				},
+			})
+		}
+	}
+	return cells
+}
+
+// layoutBoxes leaves out nested table cells, they are evaluated separately.
+func layoutBoxes(predictions []models.Prediction) []models.Prediction {
+	result := make([]models.Prediction, 0, len(predictions))
+	for _, prediction := range predictions {
+		result = append(result, models.Prediction{Rect: prediction.Rect, Label: prediction.Label, Score: prediction.Score})
+	}
+	return result
+}
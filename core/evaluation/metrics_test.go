@@ -0,0 +1,140 @@
+package evaluation
+
+import (
+	"math"
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func box(label string, score float32, x0, y0, x1, y1 float32) models.Prediction {
+	return models.Prediction{Label: label, Score: score, Rect: models.Rect{X0: x0, Y0: y0, X1: x1, Y1: y1}}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		boxes     boxes
+		threshold float32
+		want      []bool
+	}{
+		{
+			name:      "exact box",
+			boxes:     boxes{Truth: []models.Prediction{box("text", 1, 0, 0, 10, 10)}, Predicted: []models.Prediction{box("text", 0.9, 0, 0, 10, 10)}},
+			threshold: 0.5,
+			want:      []bool{true},
+		},
+		{
+			name:      "other label",
+			boxes:     boxes{Truth: []models.Prediction{box("text", 1, 0, 0, 10, 10)}, Predicted: []models.Prediction{box("table", 0.9, 0, 0, 10, 10)}},
+			threshold: 0.5,
+			want:      []bool{false},
+		},
+		{
+			name: "overlap below threshold",
+			// IoU is 50 / 150
+			boxes:     boxes{Truth: []models.Prediction{box("text", 1, 0, 0, 10, 10)}, Predicted: []models.Prediction{box("text", 0.9, 5, 0, 15, 10)}},
+			threshold: 0.5,
+			want:      []bool{false},
+		},
+		{
+			name:      "overlap at threshold",
+			boxes:     boxes{Truth: []models.Prediction{box("text", 1, 0, 0, 10, 10)}, Predicted: []models.Prediction{box("text", 0.9, 5, 0, 15, 10)}},
+			threshold: 1.0 / 3,
+			want:      []bool{true},
+		},
+		{
+			name: "most confident prediction takes the box",
+			boxes: boxes{
+				Truth:     []models.Prediction{box("text", 1, 0, 0, 10, 10)},
+				Predicted: []models.Prediction{box("text", 0.4, 0, 0, 10, 10), box("text", 0.8, 1, 1, 10, 10)},
+			},
+			threshold: 0.5,
+			want:      []bool{false, true},
+		},
+		{
+			name: "best overlap is picked",
+			boxes: boxes{
+				Truth:     []models.Prediction{box("text", 1, 0, 0, 10, 10), box("text", 1, 20, 0, 30, 10)},
+				Predicted: []models.Prediction{box("text", 0.9, 19, 0, 30, 10), box("text", 0.5, 0, 0, 10, 11)},
+			},
+			threshold: 0.5,
+			want:      []bool{true, true},
+		},
+		{
+			name:      "nothing predicted",
+			boxes:     boxes{Truth: []models.Prediction{box("text", 1, 0, 0, 10, 10)}},
+			threshold: 0.5,
+			want:      []bool{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := match(test.boxes, test.threshold); !slices.Equal(got, test.want) {
+				t.Errorf("match() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAveragePrecision(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats labelStats
+		want  float64
+	}{
+		{"no ground truth", labelStats{Detections: []detection{{Score: 0.9}}}, 0},
+		{"nothing detected", labelStats{Truth: 2}, 0},
+		{"all found", labelStats{Truth: 2, Detections: []detection{{0.9, true}, {0.8, true}}}, 1},
+		{"half found", labelStats{Truth: 2, Detections: []detection{{0.9, true}}}, 0.5},
+		{"false positive ranked last", labelStats{Truth: 1, Detections: []detection{{0.2, false}, {0.9, true}}}, 1},
+		{"false positive ranked first", labelStats{Truth: 1, Detections: []detection{{0.9, false}, {0.2, true}}}, 0.5},
+		// Precision is interpolated: 1 at recall 1/2, then 2/3 at recall 1
+		{"interpolated", labelStats{Truth: 2, Detections: []detection{{0.9, true}, {0.8, false}, {0.7, true}}}, 0.5 + 0.5*2.0/3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := averagePrecision(test.stats); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("averagePrecision() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestComputeMetrics(t *testing.T) {
+	pages := []boxes{
+		{
+			Truth:     []models.Prediction{box("text", 1, 0, 0, 10, 10), box("table", 1, 0, 20, 10, 30)},
+			Predicted: []models.Prediction{box("text", 0.9, 0, 0, 10, 10), box("figure", 0.6, 0, 20, 10, 30)},
+		},
+	}
+	metrics := computeMetrics(pages, 0.5)
+	if metrics.MAP != 0.5 {
+		t.Errorf("MAP = %v, want 0.5 as only text of text and table is found", metrics.MAP)
+	}
+	labels := map[string]LabelMetrics{}
+	for _, label := range metrics.Labels {
+		labels[label.Label] = label
+	}
+	if labels["text"].Precision != 1 || labels["text"].Recall != 1 {
+		t.Errorf("text metrics = %+v", labels["text"])
+	}
+	if labels["figure"].Predicted != 1 || labels["figure"].TruePositives != 0 {
+		t.Errorf("figure metrics = %+v", labels["figure"])
+	}
+	if labels["table"].GroundTruth != 1 || labels["table"].Recall != 0 {
+		t.Errorf("table metrics = %+v", labels["table"])
+	}
+}
+
+func TestTableCells(t *testing.T) {
+	table := box("table", 1, 100, 200, 300, 400)
+	table.Table = []models.Prediction{box("cell", 0.7, 0, 0, 50, 20)}
+	cells := tableCells([]models.Prediction{table, box("text", 1, 0, 0, 10, 10)})
+	want := []models.Prediction{box("cell", 0.7, 100, 200, 150, 220)}
+	if !slices.EqualFunc(cells, want, func(a, b models.Prediction) bool {
+		return a.Label == b.Label && a.Score == b.Score && a.Rect == b.Rect
+	}) {
+		t.Errorf("tableCells() = %+v, want %+v", cells, want)
+	}
+}
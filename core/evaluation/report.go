@@ -0,0 +1,81 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.1f%%", v*100)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>Detector evaluation</title>
+    <style>
+        body { font-family: sans-serif; margin: 2rem; }
+        table { border-collapse: collapse; margin-bottom: 2rem; }
+        th, td { border: 1px solid #ccc; padding: 0.3rem 0.6rem; text-align: right; }
+        th:first-child, td:first-child { text-align: left; }
+    </style>
+</head>
+<body>
+<h1>Detector evaluation</h1>
+<p>
+    {{.CreatedAt.Format "2006-01-02 15:04"}},
+    {{if eq .Source "detector"}}detectors {{.DocUrl}} and {{.TableUrl}}{{else}}stored detector output{{end}},
+    {{.Pages}} validated pages, IoU threshold {{.IoUThreshold}}
+</p>
+{{range .Sections}}{{template "metrics" .}}{{end}}
+<h2>Worst pages</h2>
+<table>
+    <tr><th>Document</th><th>Page</th><th>Ground truth</th><th>Predicted</th><th>Precision</th><th>Recall</th><th>F1</th><th>Cell F1</th></tr>
+    {{range .WorstPages}}
+    <tr>
+        <td>{{.DocumentName}} ({{.DocumentId}})</td><td>{{.PageNum}}</td><td>{{.GroundTruth}}</td><td>{{.Predicted}}</td>
+        <td>{{percent .Precision}}</td><td>{{percent .Recall}}</td><td>{{percent .F1}}</td><td>{{percent .CellF1}}</td>
+    </tr>
+    {{end}}
+</table>
+{{if .Skipped}}
+<h2>Skipped pages</h2>
+<ul>{{range .Skipped}}<li>{{.}}</li>{{end}}</ul>
+{{end}}
+</body>
+</html>
+{{define "metrics"}}
+<h2>{{.Title}}</h2>
+<p>mAP {{percent .Metrics.MAP}}, mAP@[.5:.95] {{percent .Metrics.MAPCoco}}</p>
+<table>
+    <tr><th>Label</th><th>Ground truth</th><th>Predicted</th><th>Matched</th><th>Precision</th><th>Recall</th><th>AP</th></tr>
+    {{range .Metrics.Labels}}
+    <tr>
+        <td>{{.Label}}</td><td>{{.GroundTruth}}</td><td>{{.Predicted}}</td><td>{{.TruePositives}}</td>
+        <td>{{percent .Precision}}</td><td>{{percent .Recall}}</td><td>{{percent .AP}}</td>
+    </tr>
+    {{end}}
+</table>
+{{end}}`))
+
+type section struct {
+	Title   string
+	Metrics Metrics
+}
+
+func (r Report) Sections() []section {
+	return []section{{Title: "Layout", Metrics: r.Layout}, {Title: "Table cells", Metrics: r.Cells}}
+}
+
+func WriteJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func WriteHTML(w io.Writer, report Report) error {
+	return reportTemplate.Execute(w, report)
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"smart-docs/core/db"
+	"smart-docs/core/pipeline"
+	"strings"
+)
+
+const bundleAssetDir = "assets/"
+
+// WriteContentBundle writes a zip archive with the document html, markdown, json and every image they reference.
+// Image URLs are rewritten to paths relative to the archive root, so the export can be opened offline.
+func WriteContentBundle(w io.Writer, docId int64) error {
+	content, err := db.GetPdfDocText(docId)
+	if err != nil {
+		return err
+	}
+	md, err := db.GetDocMarkdown(docId)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	err = writeZipEntry(archive, "content.html", []byte(relativeAssetUrls(content, docId)))
+	if err != nil {
+		return err
+	}
+	err = writeZipEntry(archive, "content.md", []byte(relativeAssetUrls(md, docId)))
+	if err != nil {
+		return err
+	}
+	var doc bytes.Buffer
+	err = WriteDocumentJson(&doc, docId)
+	if err != nil {
+		return err
+	}
+	err = writeZipEntry(archive, "content.json", []byte(relativeAssetUrls(doc.String(), docId)))
+	if err != nil {
+		return err
+	}
+	err = writeAssets(archive, docId)
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func relativeAssetUrls(content string, docId int64) string {
+	return strings.ReplaceAll(content, pipeline.AssetUrl(docId, ""), bundleAssetDir)
+}
+
+func writeAssets(archive *zip.Writer, docId int64) error {
+	files, err := filepath.Glob(filepath.Join(pipeline.AssetDir(docId), "*"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, bundleAssetDir+filepath.Base(file), data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZipEntry(archive *zip.Writer, name string, data []byte) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+type cocoDataset struct {
+	Info        cocoInfo         `json:"info"`
+	Images      []cocoImage      `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []cocoCategory   `json:"categories"`
+}
+
+type cocoInfo struct {
+	Description string `json:"description"`
+	DateCreated string `json:"date_created"`
+}
+
+type cocoImage struct {
+	Id       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type cocoAnnotation struct {
+	Id         int        `json:"id"`
+	ImageId    int        `json:"image_id"`
+	CategoryId int        `json:"category_id"`
+	BBox       [4]float32 `json:"bbox"`
+	Area       float32    `json:"area"`
+	IsCrowd    int        `json:"iscrowd"`
+}
+
+type cocoCategory struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	Supercategory string `json:"supercategory"`
+}
+
+// WriteCocoDataset writes a zip archive with two COCO datasets built from validated pages of a project:
+// "layout" with whole pages for the document detector and "tables" with table crops for the table detector.
+// Categories follow the label schema of the project.
+func WriteCocoDataset(w io.Writer, project string) error {
+	schema, pages, err := loadDataset(project)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	layout := layoutCategories(schema)
+	err = writeCocoSamples(archive, "layout/", "Document layout", layout, layoutSamples(pages, layout))
+	if err != nil {
+		return err
+	}
+	cells := cellCategories(schema)
+	err = writeCocoSamples(archive, "tables/", "Table cells", cells, tableSamples(pages, schema, cells))
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeCocoSamples(archive *zip.Writer, dir string, description string, categories []string, samples []sample) error {
+	dataset := cocoDataset{
+		Info: cocoInfo{
+			Description: description,
+			DateCreated: time.Now().Format(time.RFC3339),
+		},
+		Images:      []cocoImage{},
+		Annotations: []cocoAnnotation{},
+		Categories:  []cocoCategory{},
+	}
+
+	// COCO reserves category 0 for the background
+	categoryIds := map[string]int{}
+	for i, label := range categories {
+		categoryIds[label] = i + 1
+		dataset.Categories = append(dataset.Categories, cocoCategory{Id: i + 1, Name: label, Supercategory: strings.TrimSuffix(dir, "/")})
+	}
+
+	for i, s := range samples {
+		data, err := s.image()
+		if err != nil {
+			return err
+		}
+		fileName := s.Name + ".jpg"
+		err = writeZipEntry(archive, dir+"images/"+fileName, data)
+		if err != nil {
+			return err
+		}
+
+		imageId := i + 1
+		dataset.Images = append(dataset.Images, cocoImage{
+			Id:       imageId,
+			FileName: fileName,
+			Width:    s.Width,
+			Height:   s.Height,
+		})
+		for _, box := range s.Boxes {
+			dataset.Annotations = append(dataset.Annotations, cocoAnnotation{
+				Id:         len(dataset.Annotations) + 1,
+				ImageId:    imageId,
+				CategoryId: categoryIds[box.Label],
+				BBox:       [4]float32{box.X0, box.Y0, box.Width(), box.Height()},
+				Area:       box.Width() * box.Height(),
+			})
+		}
+	}
+
+	content, err := json.MarshalIndent(dataset, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeZipEntry(archive, dir+"annotations.json", content)
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+// sample is a single training image of a detector dataset together with its ground truth boxes.
+// Table samples are crops of the page, their boxes are relative to the crop.
+type sample struct {
+	Name       string
+	DocumentId int64
+	PageNum    int
+	Width      int
+	Height     int
+	Crop       *models.Rect
+	Boxes      []models.Prediction
+}
+
+func (s sample) image() ([]byte, error) {
+	if s.Crop == nil {
+		return os.ReadFile(pipeline.PageImagePath(s.DocumentId, s.PageNum))
+	}
+	return pipeline.CropPage(s.DocumentId, s.PageNum, *s.Crop)
+}
+
+// loadDataset returns the label schema of a project together with its validated pages.
+func loadDataset(project string) (models.LabelSchema, []models.Page, error) {
+	schema, err := db.LoadLabelSchema(project)
+	if err != nil {
+		return schema, nil, err
+	}
+	pages, err := db.LoadProjectPagesByStatus(project, "VALIDATION")
+	return schema, pages, err
+}
+
+// layoutSamples turns pages into samples for the layout detector, nested table cells and boxes with labels
+// outside of the categories are left out.
+func layoutSamples(pages []models.Page, categories []string) []sample {
+	samples := make([]sample, 0, len(pages))
+	for _, page := range pages {
+		boxes := make([]models.Prediction, 0, len(page.Predictions))
+		for _, prediction := range page.Predictions {
+			if isValidBox(prediction.Rect) && slices.Contains(categories, prediction.Label) {
+				boxes = append(boxes, models.Prediction{Rect: prediction.Rect, Label: prediction.Label, Score: prediction.Score})
+			}
+		}
+		samples = append(samples, sample{
+			Name:       fmt.Sprintf("%d_%d", page.DocumentId, page.PageNum),
+			DocumentId: page.DocumentId,
+			PageNum:    page.PageNum,
+			Width:      page.Width,
+			Height:     page.Height,
+			Boxes:      boxes,
+		})
+	}
+	return samples
+}
+
+// TableCrop is a table of a page together with the number of its crop in table datasets.
+type TableCrop struct {
+	Number int
+	Index  int
+}
+
+// TableCrops lists the tables of a page in the order of their boxes. Crops are numbered by the position of the table
+// among the boxes of the layout dataset, valid boxes with a label allowed on the page, so that the tables are found
+// again when a table dataset is imported along with its layout dataset.
+func TableCrops(predictions []models.Prediction, schema models.LabelSchema) []TableCrop {
+	categories := layoutCategories(schema)
+	var crops []TableCrop
+	number := -1
+	for i, prediction := range predictions {
+		if !isValidBox(prediction.Rect) || !slices.Contains(categories, prediction.Label) {
+			continue
+		}
+		number++
+		if schema.Renderer(prediction.Label) == models.RenderTable {
+			crops = append(crops, TableCrop{Number: number, Index: i})
+		}
+	}
+	return crops
+}
+
+// tableSamples turns every annotated table into a sample for the table detector, named after the page and the number
+// of its crop.
+func tableSamples(pages []models.Page, schema models.LabelSchema, categories []string) []sample {
+	var samples []sample
+	for _, page := range pages {
+		for _, table := range TableCrops(page.Predictions, schema) {
+			prediction := page.Predictions[table.Index]
+			if len(prediction.Table) == 0 {
+				continue
+			}
+			crop := prediction.Rect
+			boxes := make([]models.Prediction, 0, len(prediction.Table))
+			for _, cell := range prediction.Table {
+				if isValidBox(cell.Rect) && slices.Contains(categories, cell.Label) {
+					boxes = append(boxes, models.Prediction{Rect: cell.Rect, Label: cell.Label, Score: cell.Score})
+				}
+			}
+			samples = append(samples, sample{
+				Name:       fmt.Sprintf("%d_%d_%d", page.DocumentId, page.PageNum, table.Number),
+				DocumentId: page.DocumentId,
+				PageNum:    page.PageNum,
+				Width:      int(crop.X1) - int(crop.X0),
+				Height:     int(crop.Y1) - int(crop.Y0),
+				Crop:       &crop,
+				Boxes:      boxes,
+			})
+		}
+	}
+	return samples
+}
+
+// layoutCategories lists labels allowed on the page, in schema order so the category ids are stable between exports.
+func layoutCategories(schema models.LabelSchema) []string {
+	var categories []string
+	for _, label := range schema.Children(models.PageParent) {
+		categories = append(categories, label.Name)
+	}
+	return categories
+}
+
+// cellCategories lists labels allowed inside tables, in schema order.
+func cellCategories(schema models.LabelSchema) []string {
+	var categories []string
+	for _, label := range schema.Labels {
+		if label.Renderer != models.RenderTable {
+			continue
+		}
+		for _, child := range schema.Children(label.Name) {
+			if !slices.Contains(categories, child.Name) {
+				categories = append(categories, child.Name)
+			}
+		}
+	}
+	return categories
+}
+
+func isValidBox(r models.Rect) bool {
+	return r.X1 > r.X0 && r.Y1 > r.Y0
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+var schema = models.LabelSchema{
+	Labels: []models.Label{
+		{Name: "text", Parents: []string{models.PageParent}, Renderer: models.RenderParagraph},
+		{Name: "table", Parents: []string{models.PageParent}, Renderer: models.RenderTable},
+		{Name: "cell", Parents: []string{"table"}, Renderer: models.RenderCell},
+	},
+}
+
+func TestTableCrops(t *testing.T) {
+	box := func(label string, x1 float32) models.Prediction {
+		return models.Prediction{Rect: models.Rect{X1: x1, Y1: 10}, Label: label}
+	}
+	tests := []struct {
+		name        string
+		predictions []models.Prediction
+		want        []TableCrop
+	}{
+		{"no tables", []models.Prediction{box("text", 10)}, nil},
+		{"tables among other boxes", []models.Prediction{box("text", 10), box("table", 10), box("table", 10)}, []TableCrop{{1, 1}, {2, 2}}},
+		{"invalid boxes are not counted", []models.Prediction{box("text", 0), box("table", 10)}, []TableCrop{{0, 1}}},
+		{"labels off the layout dataset are not counted", []models.Prediction{box("figure", 10), box("cell", 10), box("table", 10)}, []TableCrop{{0, 2}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TableCrops(test.predictions, schema); !slices.Equal(got, test.want) {
+				t.Errorf("TableCrops() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTableSamplesMatchLayoutSamples(t *testing.T) {
+	table := models.Prediction{Rect: models.Rect{X0: 10, Y0: 10, X1: 60, Y1: 40}, Label: "table", Table: []models.Prediction{
+		{Rect: models.Rect{X1: 20, Y1: 10}, Label: "cell"},
+	}}
+	page := models.Page{DocumentId: 3, PageNum: 1, Width: 100, Height: 100, Predictions: []models.Prediction{
+		{Rect: models.Rect{X0: 5, Y0: 5, X1: 5, Y1: 8}, Label: "text"},
+		{Rect: models.Rect{X1: 10, Y1: 5}, Label: "figure"},
+		{Rect: models.Rect{X1: 50, Y1: 5}, Label: "text"},
+		table,
+	}}
+	samples := tableSamples([]models.Page{page}, schema, cellCategories(schema))
+	if len(samples) != 1 || samples[0].Name != "3_1_1" {
+		t.Fatalf("tableSamples() = %+v, want one crop named 3_1_1", samples)
+	}
+	layout := layoutSamples([]models.Page{page}, layoutCategories(schema))[0].Boxes
+	if layout[1].Rect != table.Rect {
+		t.Errorf("layout box 1 = %+v, want the table", layout[1])
+	}
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"time"
+)
+
+// SchemaVersion of the json export. Bump it whenever a field is removed or changes meaning.
+const SchemaVersion = 1
+
+// Document is the json export of a document. All coordinates are in pixels of the page image.
+type Document struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Id            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Mode          string    `json:"mode"`
+	UploadDate    time.Time `json:"uploadDate"`
+	// Project and Labels describe the label schema the blocks were annotated with
+	Project string         `json:"project"`
+	Labels  []models.Label `json:"labels"`
+	Pages   []Page         `json:"pages"`
+}
+
+type Page struct {
+	PageNum  int     `json:"pageNum"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Status   string  `json:"status"`
+	Markdown string  `json:"markdown"`
+	Blocks   []Block `json:"blocks"`
+	Fields   []Field `json:"fields"`
+}
+
+// Block is a layout segment. Blocks are listed in reading order, which is also stored in Order.
+type Block struct {
+	Order int         `json:"order"`
+	Label string      `json:"label"`
+	Score float32     `json:"score"`
+	BBox  models.Rect `json:"bbox"`
+	Text  string      `json:"text"`
+	Words []Word      `json:"words"`
+	Cells []Cell      `json:"cells,omitempty"`
+	Image string      `json:"image,omitempty"`
+}
+
+type Word struct {
+	Text string      `json:"text"`
+	BBox models.Rect `json:"bbox"`
+}
+
+// Field is a key-value pair of a form page. Source is "model" for extracted pairs and "human" for edited ones.
+type Field struct {
+	Key      string      `json:"key"`
+	Value    string      `json:"value"`
+	KeyBox   models.Rect `json:"keyBox"`
+	ValueBox models.Rect `json:"valueBox"`
+	Source   string      `json:"source"`
+}
+
+type Cell struct {
+	Row     int         `json:"row"`
+	Col     int         `json:"col"`
+	Rowspan int         `json:"rowspan"`
+	Colspan int         `json:"colspan"`
+	Header  bool        `json:"header"`
+	Label   string      `json:"label"`
+	BBox    models.Rect `json:"bbox"`
+	Text    string      `json:"text"`
+	// Value is the typed value of the text, when the project has a locale and the text is not plain text
+	Value *models.CellValue `json:"value,omitempty"`
+	Words []Word            `json:"words"`
+}
+
+// BuildDocument assembles the json export from the stored predictions and words of every page.
+func BuildDocument(docId int64) (Document, error) {
+	doc, err := db.LoadDocument(docId)
+	if err != nil {
+		return Document{}, err
+	}
+	pages, err := db.LoadPages(docId)
+	if err != nil {
+		return Document{}, err
+	}
+	schema, err := db.DocumentLabelSchema(docId)
+	if err != nil {
+		return Document{}, err
+	}
+	fields, err := db.ListDocumentFields(docId)
+	if err != nil {
+		return Document{}, err
+	}
+	pageFields := map[int][]Field{}
+	for _, field := range fields {
+		pageFields[field.PageNum] = append(pageFields[field.PageNum], Field{
+			Key:      field.Key,
+			Value:    field.Value,
+			KeyBox:   field.KeyBox,
+			ValueBox: field.ValueBox,
+			Source:   field.Source,
+		})
+	}
+
+	export := Document{
+		SchemaVersion: SchemaVersion,
+		Id:            doc.Id,
+		Name:          doc.Name,
+		Mode:          doc.Mode,
+		UploadDate:    doc.UploadDate,
+		Project:       doc.Project,
+		Labels:        schema.Labels,
+		Pages:         make([]Page, 0, len(pages)),
+	}
+	for _, page := range pages {
+		words, err := page.Words()
+		if err != nil {
+			return Document{}, err
+		}
+		blocks := pipeline.AnalyzeLayout(docId, page.PageNum, words, page.Predictions)
+		exportPage := Page{
+			PageNum:  page.PageNum,
+			Width:    page.Width,
+			Height:   page.Height,
+			Status:   page.Status,
+			Markdown: page.Md,
+			Blocks:   make([]Block, len(blocks)),
+			Fields:   pageFields[page.PageNum],
+		}
+		if exportPage.Fields == nil {
+			exportPage.Fields = []Field{}
+		}
+		for i, block := range blocks {
+			exportPage.Blocks[i] = exportBlock(block)
+		}
+		export.Pages = append(export.Pages, exportPage)
+	}
+	return export, nil
+}
+
+func WriteDocumentJson(w io.Writer, docId int64) error {
+	doc, err := BuildDocument(docId)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+func exportBlock(block pipeline.Block) Block {
+	exported := Block{
+		Order: block.Order,
+		Label: block.Label,
+		Score: block.Score,
+		BBox:  block.Rect,
+		Text:  block.Text,
+		Words: exportWords(block.Words),
+		Image: block.Image,
+	}
+	for _, cell := range block.Cells {
+		exported.Cells = append(exported.Cells, Cell{
+			Row:     cell.Row,
+			Col:     cell.Col,
+			Rowspan: cell.Rowspan,
+			Colspan: cell.Colspan,
+			Header:  cell.Header,
+			Label:   cell.Label,
+			BBox:    cell.Rect,
+			Text:    cell.Text,
+			Value:   cell.Value,
+			Words:   exportWords(cell.Words),
+		})
+	}
+	return exported
+}
+
+func exportWords(words []models.WordData) []Word {
+	exported := make([]Word, len(words))
+	for i, word := range words {
+		exported[i] = Word{Text: word.Text, BBox: word.Rect}
+	}
+	return exported
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"smart-docs/core/db"
+	"smart-docs/core/entities"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"strings"
+)
+
+// entityExample is a page of the token classification dataset in the layout LayoutLM is trained on:
+// words with their boxes normalised to 0-1000 and a BIO tag per word.
+type entityExample struct {
+	Id      string   `json:"id"`
+	Image   string   `json:"image"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Tokens  []string `json:"tokens"`
+	BBoxes  [][4]int `json:"bboxes"`
+	NerTags []string `json:"ner_tags"`
+}
+
+// WriteEntityDataset writes a zip archive with pages of a project tagged with entity spans: data.jsonl with one page
+// per line, the page images and labels.txt listing every BIO tag of the project entities.
+func WriteEntityDataset(w io.Writer, project string) error {
+	schema, err := db.LoadLabelSchema(project)
+	if err != nil {
+		return err
+	}
+	spans, err := db.ProjectEntitySpans(project)
+	if err != nil {
+		return err
+	}
+	byPage := map[[2]int64][]models.EntitySpan{}
+	var pageKeys [][2]int64
+	for _, span := range spans {
+		key := [2]int64{span.DocumentId, int64(span.PageNum)}
+		if _, ok := byPage[key]; !ok {
+			pageKeys = append(pageKeys, key)
+		}
+		byPage[key] = append(byPage[key], span)
+	}
+
+	archive := zip.NewWriter(w)
+	var data bytes.Buffer
+	for _, key := range pageKeys {
+		docId, pageNum := key[0], int(key[1])
+		width, height, err := db.GetPageSize(docId, pageNum)
+		if err != nil {
+			return err
+		}
+		text, err := db.LoadPageText(docId, pageNum)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%d_%d", docId, pageNum)
+		image, err := os.ReadFile(pipeline.PageImagePath(docId, pageNum))
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, "images/"+name+".jpg", image)
+		if err != nil {
+			return err
+		}
+
+		example := entityExample{
+			Id:      name,
+			Image:   "images/" + name + ".jpg",
+			Width:   width,
+			Height:  height,
+			Tokens:  make([]string, len(text.Words)),
+			BBoxes:  make([][4]int, len(text.Words)),
+			NerTags: entities.Tags(len(text.Words), byPage[key]),
+		}
+		for i, word := range text.Words {
+			example.Tokens[i] = word.Text
+			example.BBoxes[i] = normaliseBox(word.Rect, width, height)
+		}
+		line, err := json.Marshal(example)
+		if err != nil {
+			return err
+		}
+		data.Write(line)
+		data.WriteByte('\n')
+	}
+	err = writeZipEntry(archive, "data.jsonl", data.Bytes())
+	if err != nil {
+		return err
+	}
+
+	tags := []string{entities.Outside}
+	for _, entity := range schema.Entities {
+		tags = append(tags, "B-"+entity.Name, "I-"+entity.Name)
+	}
+	err = writeZipEntry(archive, "labels.txt", []byte(strings.Join(tags, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// normaliseBox scales a box to the 0-1000 grid LayoutLM expects.
+func normaliseBox(r models.Rect, width int, height int) [4]int {
+	if width <= 0 || height <= 0 {
+		return [4]int{}
+	}
+	scale := func(v float32, size int) int {
+		return int(clamp01(v/float32(size)) * 1000)
+	}
+	return [4]int{scale(r.X0, width), scale(r.Y0, height), scale(r.X1, width), scale(r.Y1, height)}
+}
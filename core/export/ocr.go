@@ -0,0 +1,63 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"smart-docs/core/db"
+	"smart-docs/core/pipeline"
+)
+
+// ocrGroundTruth is the corrected text of a page image. Original lists the words as extracted, before any correction.
+type ocrGroundTruth struct {
+	Image    string `json:"image"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Words    []Word `json:"words"`
+	Original []Word `json:"original"`
+}
+
+// WriteOcrGroundTruth writes a zip archive with the images of pages of a project whose words were corrected,
+// each with a json file listing the corrected words and their boxes in pixels of the image.
+func WriteOcrGroundTruth(w io.Writer, project string) error {
+	pages, err := db.LoadCorrectedPages(project)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, page := range pages {
+		name := fmt.Sprintf("%d_%d", page.DocumentId, page.PageNum)
+		data, err := os.ReadFile(pipeline.PageImagePath(page.DocumentId, page.PageNum))
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, "images/"+name+".jpg", data)
+		if err != nil {
+			return err
+		}
+
+		words, err := page.Words()
+		if err != nil {
+			return err
+		}
+		truth := ocrGroundTruth{
+			Image:    "images/" + name + ".jpg",
+			Width:    page.Width,
+			Height:   page.Height,
+			Words:    exportWords(words),
+			Original: exportWords(page.OriginalWords),
+		}
+		content, err := json.MarshalIndent(truth, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, "ground_truth/"+name+".json", content)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"smart-docs/core/models"
+	"strconv"
+)
+
+// Table is a table of a document. Order is its position among the blocks of the page.
+type Table struct {
+	PageNum int         `json:"pageNum"`
+	Order   int         `json:"order"`
+	Label   string      `json:"label"`
+	BBox    models.Rect `json:"bbox"`
+	Cells   []Cell      `json:"cells"`
+}
+
+var tableCsvHeader = []string{"page", "table", "row", "col", "rowspan", "colspan", "header", "label", "text", "type", "number", "currency", "date"}
+
+// DocumentTables lists the tables of every page of a document with their cells.
+func DocumentTables(docId int64) ([]Table, error) {
+	doc, err := BuildDocument(docId)
+	if err != nil {
+		return nil, err
+	}
+	tables := []Table{}
+	for _, page := range doc.Pages {
+		for _, block := range page.Blocks {
+			if len(block.Cells) == 0 {
+				continue
+			}
+			tables = append(tables, Table{
+				PageNum: page.PageNum,
+				Order:   block.Order,
+				Label:   block.Label,
+				BBox:    block.BBox,
+				Cells:   block.Cells,
+			})
+		}
+	}
+	return tables, nil
+}
+
+func WriteTablesJson(w io.Writer, docId int64) error {
+	tables, err := DocumentTables(docId)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tables)
+}
+
+// WriteTablesCsv writes the cells of all tables of a document, one per line, with their raw text and typed value.
+func WriteTablesCsv(w io.Writer, docId int64) error {
+	tables, err := DocumentTables(docId)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	err = writer.Write(tableCsvHeader)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for _, cell := range table.Cells {
+			valueType, number, currency, date := "text", "", "", ""
+			if cell.Value != nil {
+				valueType, currency, date = cell.Value.Type, cell.Value.Currency, cell.Value.Date
+				if cell.Value.Number != nil {
+					number = strconv.FormatFloat(*cell.Value.Number, 'f', -1, 64)
+				}
+			}
+			err = writer.Write([]string{
+				strconv.Itoa(table.PageNum),
+				strconv.Itoa(table.Order),
+				strconv.Itoa(cell.Row),
+				strconv.Itoa(cell.Col),
+				strconv.Itoa(cell.Rowspan),
+				strconv.Itoa(cell.Colspan),
+				strconv.FormatBool(cell.Header),
+				cell.Label,
+				cell.Text,
+				valueType,
+				number,
+				currency,
+				date,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Splits holds the share of documents, in percent, going to the train and validation sets. The rest is used for testing.
+type Splits struct {
+	Train int
+	Val   int
+}
+
+var DefaultSplits = Splits{Train: 80, Val: 10}
+
+func (s Splits) Valid() bool {
+	return s.Train >= 0 && s.Val >= 0 && s.Train+s.Val <= 100
+}
+
+// splitNames lists the splits in the order of their share of buckets.
+var splitNames = []string{"train", "val", "test"}
+
+// bucketRange returns the buckets [from, to) of a split, it has no share of the documents when they are equal.
+func (s Splits) bucketRange(split string) (int, int) {
+	switch split {
+	case "train":
+		return 0, s.Train
+	case "val":
+		return s.Train, s.Train + s.Val
+	default:
+		return s.Train + s.Val, 100
+	}
+}
+
+// bucketOf hashes a document id, so documents keep their bucket when the dataset is rebuilt with more documents.
+func bucketOf(docId int64) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strconv.FormatInt(docId, 10)))
+	return int(hash.Sum32() % 100)
+}
+
+// assign maps documents to splits by hashing their id, so all pages of a document end up in the same split and keep it
+// when documents are added. With few documents a split may be left empty, which is logged rather than fixed by moving
+// documents between splits.
+func (s Splits) assign(docIds []int64) map[int64]string {
+	ids := slices.Clone(docIds)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	assigned := map[int64]string{}
+	counts := map[string]int{}
+	for _, docId := range ids {
+		bucket := bucketOf(docId)
+		for _, split := range splitNames {
+			from, to := s.bucketRange(split)
+			if bucket >= from && bucket < to {
+				assigned[docId] = split
+				counts[split]++
+				break
+			}
+		}
+	}
+
+	for _, split := range splitNames {
+		from, to := s.bucketRange(split)
+		if from < to && counts[split] == 0 && len(ids) > 0 {
+			log.Println(fmt.Sprintf("No documents in the %s split of %d documents", split, len(ids)))
+		}
+	}
+	return assigned
+}
+
+// WriteYoloDataset writes a zip archive with validated pages of a project in the YOLO layout: images and label files per split and data.yaml.
+// With tables set, the dataset holds table crops labelled with their cells instead of whole pages.
+// Class ids follow the label schema of the project.
+func WriteYoloDataset(w io.Writer, project string, splits Splits, tables bool) error {
+	schema, pages, err := loadDataset(project)
+	if err != nil {
+		return err
+	}
+	labels := layoutCategories(schema)
+	samples := layoutSamples(pages, labels)
+	if tables {
+		labels = cellCategories(schema)
+		samples = tableSamples(pages, schema, labels)
+	}
+
+	classIds := map[string]int{}
+	for i, label := range labels {
+		classIds[label] = i
+	}
+
+	docIds := make([]int64, 0, len(samples))
+	for _, s := range samples {
+		docIds = append(docIds, s.DocumentId)
+	}
+	assigned := splits.assign(docIds)
+
+	archive := zip.NewWriter(w)
+	for _, s := range samples {
+		split := assigned[s.DocumentId]
+		data, err := s.image()
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, fmt.Sprintf("images/%s/%s.jpg", split, s.Name), data)
+		if err != nil {
+			return err
+		}
+		err = writeZipEntry(archive, fmt.Sprintf("labels/%s/%s.txt", split, s.Name), []byte(yoloLabels(s, classIds)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeZipEntry(archive, "data.yaml", []byte(yoloDataYaml(labels)))
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// yoloLabels writes a line "class cx cy w h" per box within the image, with coordinates normalised to the image size.
+func yoloLabels(s sample, classIds map[string]int) string {
+	if s.Width <= 0 || s.Height <= 0 {
+		return ""
+	}
+	width := float32(s.Width)
+	height := float32(s.Height)
+	var b strings.Builder
+	for _, box := range s.Boxes {
+		// Corners are clamped before the centre and size are taken from them, so the box stays within the image
+		x0, x1 := clamp01(box.X0/width), clamp01(box.X1/width)
+		y0, y1 := clamp01(box.Y0/height), clamp01(box.Y1/height)
+		if x1 <= x0 || y1 <= y0 {
+			// Boxes outside of the image have nothing left to label
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%d %.6f %.6f %.6f %.6f\n",
+			classIds[box.Label],
+			(x0+x1)/2,
+			(y0+y1)/2,
+			x1-x0,
+			y1-y0,
+		))
+	}
+	return b.String()
+}
+
+func yoloDataYaml(labels []string) string {
+	var b strings.Builder
+	b.WriteString("path: .\n")
+	b.WriteString("train: images/train\n")
+	b.WriteString("val: images/val\n")
+	b.WriteString("test: images/test\n")
+	b.WriteString(fmt.Sprintf("nc: %d\n", len(labels)))
+	b.WriteString("names:\n")
+	for i, label := range labels {
+		b.WriteString(fmt.Sprintf("  %d: %s\n", i, strconv.Quote(label)))
+	}
+	return b.String()
+}
+
+func clamp01(v float32) float32 {
+	return min(max(v, 0), 1)
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"maps"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestSplitsAssign(t *testing.T) {
+	tests := []struct {
+		name   string
+		splits Splits
+		docIds []int64
+		want   map[string]int
+	}{
+		{"no documents", DefaultSplits, nil, map[string]int{}},
+		{"single document", DefaultSplits, []int64{1}, map[string]int{"train": 1}},
+		{"few documents leave a split empty", DefaultSplits, []int64{1, 2, 3}, map[string]int{"train": 2, "val": 1}},
+		{"pages of a document count once", DefaultSplits, []int64{1, 1, 2, 2, 3}, map[string]int{"train": 2, "val": 1}},
+		{"train only", Splits{Train: 100}, []int64{1, 2, 3}, map[string]int{"train": 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counts := map[string]int{}
+			for _, split := range test.splits.assign(test.docIds) {
+				counts[split]++
+			}
+			if !maps.Equal(counts, test.want) {
+				t.Errorf("assign() counts = %v, want %v", counts, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitsAssignIsStable(t *testing.T) {
+	docIds := make([]int64, 200)
+	for i := range docIds {
+		docIds[i] = int64(i + 1)
+	}
+	assigned := DefaultSplits.assign(docIds)
+	for _, docId := range docIds {
+		from, to := DefaultSplits.bucketRange(assigned[docId])
+		if bucket := bucketOf(docId); bucket < from || bucket >= to {
+			t.Errorf("document %d with bucket %d moved to %s", docId, bucket, assigned[docId])
+		}
+	}
+	for docId, split := range DefaultSplits.assign(docIds[:3]) {
+		if assigned[docId] != split {
+			t.Errorf("document %d is in %s with few documents and in %s with more", docId, split, assigned[docId])
+		}
+	}
+}
+
+func TestYoloLabels(t *testing.T) {
+	classIds := map[string]int{"paragraph": 0, "table": 1}
+	tests := []struct {
+		name string
+		box  models.Rect
+		want string
+	}{
+		{"inside", models.Rect{X0: 10, Y0: 20, X1: 30, Y1: 60}, "0 0.200000 0.400000 0.200000 0.400000\n"},
+		{"overflowing right and bottom", models.Rect{X0: 80, Y0: 50, X1: 120, Y1: 150}, "0 0.900000 0.750000 0.200000 0.500000\n"},
+		{"overflowing left and top", models.Rect{X0: -20, Y0: -10, X1: 20, Y1: 10}, "0 0.100000 0.050000 0.200000 0.100000\n"},
+		{"outside", models.Rect{X0: 110, Y0: 0, X1: 130, Y1: 10}, ""},
+		{"outside below", models.Rect{X0: 10, Y0: 100, X1: 30, Y1: 120}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := sample{Width: 100, Height: 100, Boxes: []models.Prediction{{Rect: test.box, Label: "paragraph"}}}
+			if got := yoloLabels(s, classIds); got != test.want {
+				t.Errorf("yoloLabels() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestYoloLabelsWithoutSize(t *testing.T) {
+	s := sample{Boxes: []models.Prediction{{Rect: models.Rect{X1: 1, Y1: 1}, Label: "table"}}}
+	if got := yoloLabels(s, map[string]int{"table": 1}); got != "" {
+		t.Errorf("yoloLabels() = %q, want no labels", got)
+	}
+}
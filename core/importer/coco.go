@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"smart-docs/core/models"
+	"strings"
+)
+
+type cocoDataset struct {
+	Images []struct {
+		Id       int     `json:"id"`
+		FileName string  `json:"file_name"`
+		Width    float32 `json:"width"`
+		Height   float32 `json:"height"`
+	} `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"categories"`
+}
+
+type cocoAnnotation struct {
+	Id         int        `json:"id"`
+	ImageId    int        `json:"image_id"`
+	CategoryId int        `json:"category_id"`
+	BBox       [4]float32 `json:"bbox"`
+	Score      *float32   `json:"score"`
+}
+
+// parseCoco reads every json file of the upload as a COCO dataset. Boxes keep the order of their annotation ids.
+func parseCoco(files map[string][]byte) ([]imageAnnotations, error) {
+	var images []imageAnnotations
+	for name, content := range files {
+		if !strings.EqualFold(path.Ext(name), ".json") {
+			continue
+		}
+		var dataset cocoDataset
+		err := json.Unmarshal(content, &dataset)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a COCO dataset: %w", name, err)
+		}
+
+		categories := map[int]string{}
+		for _, category := range dataset.Categories {
+			categories[category.Id] = category.Name
+		}
+		annotations := dataset.Annotations
+		slices.SortStableFunc(annotations, func(a, b cocoAnnotation) int {
+			return cmp.Compare(a.Id, b.Id)
+		})
+
+		for _, image := range dataset.Images {
+			imported := imageAnnotations{
+				Key:    image.FileName,
+				Width:  image.Width,
+				Height: image.Height,
+				Boxes:  []models.Prediction{},
+			}
+			for _, annotation := range annotations {
+				if annotation.ImageId != image.Id {
+					continue
+				}
+				label, ok := categories[annotation.CategoryId]
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown category %d", name, annotation.CategoryId)
+				}
+				var score float32 = 1
+				if annotation.Score != nil {
+					score = *annotation.Score
+				}
+				imported.Boxes = append(imported.Boxes, models.Prediction{
+					Label: label,
+					Score: score,
+					Rect: models.Rect{
+						X0: annotation.BBox[0],
+						Y0: annotation.BBox[1],
+						X1: annotation.BBox[0] + annotation.BBox[2],
+						Y1: annotation.BBox[1] + annotation.BBox[3],
+					},
+				})
+			}
+			images = append(images, imported)
+		}
+	}
+	return images, nil
+}
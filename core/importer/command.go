@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RunCommand imports annotation files from the command line:
+//
+//	smart-docs import -format coco -status VALIDATION annotations.json
+func RunCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", FormatCoco, "annotation format: coco, yolo or labelstudio")
+	status := flags.String("status", "TRAINING", "status of imported pages: PREDICTION, TRAINING or VALIDATION")
+	author := flags.String("author", "import", "author recorded for the imported annotations")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(Statuses, *status) {
+		return fmt.Errorf("unknown status %q, expected one of %s", *status, strings.Join(Statuses, ", "))
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("missing annotation files")
+	}
+
+	files := map[string][]byte{}
+	for _, name := range flags.Args() {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		read, err := ReadFiles(filepath.Base(name), content)
+		if err != nil {
+			return err
+		}
+		for file, data := range read {
+			files[file] = data
+		}
+	}
+
+	result, err := Import(files, *format, *status, *author)
+	if err != nil {
+		return err
+	}
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
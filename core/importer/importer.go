@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/export"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/validation"
+	"smart-docs/core/workflow"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCoco        = "coco"
+	FormatYolo        = "yolo"
+	FormatLabelStudio = "labelstudio"
+)
+
+// Boxes mostly inside a table are treated as its cells when importing flat datasets.
+const minCellOverlap = 0.8
+
+var cellLabels = []string{"cell", "header"}
+
+// Statuses lists the workflow statuses imported pages can be moved to.
+var Statuses = []string{"PREDICTION", "TRAINING", "VALIDATION"}
+
+// imageAnnotations are the boxes of a single image of an imported dataset.
+// Coordinates are in pixels of an image of Width x Height, or fractions of the image size when Normalised.
+type imageAnnotations struct {
+	Key        string
+	Width      float32
+	Height     float32
+	Normalised bool
+	Boxes      []models.Prediction
+	// Skipped describes annotations of the image which could not be imported
+	Skipped []string
+}
+
+type Result struct {
+	Imported  []string `json:"imported"`
+	Unmatched []string `json:"unmatched"`
+	Skipped   []string `json:"skipped"`
+	Errors    []string `json:"errors"`
+}
+
+// pageImport collects everything imported for one page: its boxes and cells of table crops keyed by box index.
+type pageImport struct {
+	key        string
+	docId      int64
+	pageNum    int
+	page       *imageAnnotations
+	tableCells map[int]*imageAnnotations
+}
+
+// ReadFiles returns the files of an upload, unpacking zip archives.
+func ReadFiles(name string, content []byte) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if !strings.EqualFold(path.Ext(name), ".zip") {
+		files[name] = content
+		return files, nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open archive: %w", err)
+	}
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+	return files, nil
+}
+
+// Import converts annotation files of the given format to predictions, matches them to pages by image name,
+// re-renders the matched pages and moves them to the given status. Imported annotations are stored as made by the author.
+func Import(files map[string][]byte, format string, status string, author string) (Result, error) {
+	if !slices.Contains(Statuses, status) {
+		return Result{}, fmt.Errorf("unknown status %q, expected one of %s", status, strings.Join(Statuses, ", "))
+	}
+	var images []imageAnnotations
+	var err error
+	switch format {
+	case FormatCoco:
+		images, err = parseCoco(files)
+	case FormatYolo:
+		images, err = parseYolo(files)
+	case FormatLabelStudio:
+		images, err = parseLabelStudio(files)
+	default:
+		return Result{}, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Imported: []string{}, Unmatched: []string{}, Skipped: []string{}, Errors: []string{}}
+	var imports []*pageImport
+	byPage := map[string]*pageImport{}
+	for i := range images {
+		image := &images[i]
+		result.Skipped = append(result.Skipped, image.Skipped...)
+		docId, pageNum, tableIndex, ok := matchImage(image.Key)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, image.Key)
+			continue
+		}
+		pageKey := fmt.Sprintf("%d_%d", docId, pageNum)
+		imported, exists := byPage[pageKey]
+		if !exists {
+			imported = &pageImport{key: pageKey, docId: docId, pageNum: pageNum, tableCells: map[int]*imageAnnotations{}}
+			byPage[pageKey] = imported
+			imports = append(imports, imported)
+		}
+		if tableIndex < 0 {
+			imported.page = image
+		} else {
+			imported.tableCells[tableIndex] = image
+		}
+	}
+
+	for _, imported := range imports {
+		err := importPage(imported, status, author)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", imported.key, err))
+			continue
+		}
+		result.Imported = append(result.Imported, imported.key)
+	}
+	return result, nil
+}
+
+func importPage(imported *pageImport, status string, author string) error {
+	if imported.page == nil {
+		return fmt.Errorf("table cells found without the page annotations")
+	}
+	var page models.PageView
+	err := db.LoadPage(imported.docId, imported.pageNum, &page)
+	if err != nil {
+		return fmt.Errorf("page not found: %w", err)
+	}
+
+	schema, err := db.DocumentLabelSchema(imported.docId)
+	if err != nil {
+		return err
+	}
+	predictions := toPagePixels(*imported.page, float32(page.Width), float32(page.Height))
+	if len(imported.tableCells) == 0 {
+		predictions = nestTableCells(predictions)
+	}
+	crops := export.TableCrops(predictions, schema)
+	for number, cells := range imported.tableCells {
+		i := slices.IndexFunc(crops, func(crop export.TableCrop) bool { return crop.Number == number })
+		if i < 0 {
+			return fmt.Errorf("table %d does not exist on the page", number)
+		}
+		table := &predictions[crops[i].Index]
+		table.Table = toPagePixels(*cells, float32(int(table.X1)-int(table.X0)), float32(int(table.Y1)-int(table.Y0)))
+	}
+
+	// Rounding while scaling can push boxes slightly off the page, those are clamped rather than rejected
+	predictions = validation.Fix(predictions, float32(page.Width), float32(page.Height))
+	if problems := validation.Validate(predictions, schema, float32(page.Width), float32(page.Height)); len(problems) > 0 {
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.String()
+		}
+		return fmt.Errorf("invalid annotations: %s", strings.Join(messages, "; "))
+	}
+
+	err = pipeline.ApplyPredictions(imported.docId, imported.pageNum, &predictions, author)
+	if err != nil {
+		return err
+	}
+	return workflow.Set(imported.docId, imported.pageNum, status, author, "imported")
+}
+
+// toPagePixels scales imported boxes to an image of the given size.
+func toPagePixels(image imageAnnotations, width float32, height float32) []models.Prediction {
+	scaleX, scaleY := float32(1), float32(1)
+	if image.Normalised {
+		scaleX, scaleY = width, height
+	} else if image.Width > 0 && image.Height > 0 {
+		scaleX, scaleY = width/image.Width, height/image.Height
+	}
+	predictions := make([]models.Prediction, 0, len(image.Boxes))
+	for _, box := range image.Boxes {
+		predictions = append(predictions, models.Prediction{
+			Score: box.Score,
+			Label: box.Label,
+			Table: []models.Prediction{},
+			Rect: models.Rect{
+				X0: box.X0 * scaleX,
+				Y0: box.Y0 * scaleY,
+				X1: box.X1 * scaleX,
+				Y1: box.Y1 * scaleY,
+			},
+		})
+	}
+	return predictions
+}
+
+// nestTableCells moves cell boxes lying inside a table into the table, with coordinates relative to the table.
+func nestTableCells(predictions []models.Prediction) []models.Prediction {
+	var tables []int
+	for i, prediction := range predictions {
+		if prediction.Label == "table" {
+			tables = append(tables, i)
+		}
+	}
+	if len(tables) == 0 {
+		return predictions
+	}
+
+	nested := make([]bool, len(predictions))
+	for i, prediction := range predictions {
+		if !slices.Contains(cellLabels, prediction.Label) {
+			continue
+		}
+		best := -1
+		var bestOverlap float32 = minCellOverlap
+		for _, t := range tables {
+			overlap := pipeline.Intersection(prediction.Rect, predictions[t].Rect)
+			if overlap > bestOverlap {
+				best = t
+				bestOverlap = overlap
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		table := &predictions[best]
+		table.Table = append(table.Table, models.Prediction{
+			Score: prediction.Score,
+			Label: prediction.Label,
+			Table: []models.Prediction{},
+			Rect: models.Rect{
+				X0: prediction.X0 - table.X0,
+				Y0: prediction.Y0 - table.Y0,
+				X1: prediction.X1 - table.X0,
+				Y1: prediction.Y1 - table.Y0,
+			},
+		})
+		nested[i] = true
+	}
+
+	result := make([]models.Prediction, 0, len(predictions))
+	for i, prediction := range predictions {
+		if !nested[i] {
+			result = append(result, prediction)
+		}
+	}
+	return result
+}
+
+var (
+	exportedKeyRe = regexp.MustCompile(`^(\d+)_(\d+)(?:_(\d+))?$`)
+	namedKeyRe    = regexp.MustCompile(`^(.+)[_\-/](\d+)$`)
+	// Label Studio prefixes uploaded files with a random hash
+	labelStudioPrefixRe = regexp.MustCompile(`^[0-9a-f]{8}-`)
+)
+
+// matchImage finds the page an image belongs to. Images are matched either by the "{documentId}_{pageNum}" names
+// used by our exports (with a "_{tableIndex}" suffix for table crops), or by "{documentName}_{pageNum}".
+func matchImage(key string) (int64, int, int, bool) {
+	name := strings.TrimSuffix(path.Base(key), path.Ext(key))
+	name = labelStudioPrefixRe.ReplaceAllString(name, "")
+
+	if match := exportedKeyRe.FindStringSubmatch(name); match != nil {
+		docId, _ := strconv.ParseInt(match[1], 10, 64)
+		pageNum, _ := strconv.Atoi(match[2])
+		tableIndex := -1
+		if match[3] != "" {
+			tableIndex, _ = strconv.Atoi(match[3])
+		}
+		if _, err := db.LoadDocument(docId); err == nil {
+			return docId, pageNum, tableIndex, true
+		}
+	}
+
+	if match := namedKeyRe.FindStringSubmatch(name); match != nil {
+		pageNum, _ := strconv.Atoi(match[2])
+		docId, err := db.FindDocumentByName(match[1])
+		if err != nil {
+			docId, err = db.FindDocumentByName(match[1] + ".pdf")
+		}
+		if err == nil {
+			return docId, pageNum, -1, true
+		}
+	}
+	return 0, 0, -1, false
+}
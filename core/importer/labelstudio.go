@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"smart-docs/core/models"
+	"strings"
+)
+
+type labelStudioTask struct {
+	Data        map[string]interface{} `json:"data"`
+	Annotations []struct {
+		WasCancelled bool                `json:"was_cancelled"`
+		Result       []labelStudioResult `json:"result"`
+	} `json:"annotations"`
+}
+
+type labelStudioResult struct {
+	Type  string `json:"type"`
+	Value struct {
+		X               float32  `json:"x"`
+		Y               float32  `json:"y"`
+		Width           float32  `json:"width"`
+		Height          float32  `json:"height"`
+		RectangleLabels []string `json:"rectanglelabels"`
+		Labels          []string `json:"labels"`
+	} `json:"value"`
+}
+
+// parseLabelStudio reads Label Studio json exports. The last submitted annotation of each task is used,
+// rectangles are given in percent of the image size.
+func parseLabelStudio(files map[string][]byte) ([]imageAnnotations, error) {
+	var images []imageAnnotations
+	for name, content := range files {
+		if !strings.EqualFold(path.Ext(name), ".json") {
+			continue
+		}
+		var tasks []labelStudioTask
+		err := json.Unmarshal(content, &tasks)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a Label Studio export: %w", name, err)
+		}
+		for t, task := range tasks {
+			image, ok := task.Data["image"].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: task %d has no image", name, t)
+			}
+			imported := imageAnnotations{Key: image, Normalised: true, Boxes: []models.Prediction{}}
+			for a := len(task.Annotations) - 1; a >= 0; a-- {
+				annotation := task.Annotations[a]
+				if annotation.WasCancelled {
+					continue
+				}
+				for _, result := range annotation.Result {
+					labels := result.Value.RectangleLabels
+					if len(labels) == 0 {
+						labels = result.Value.Labels
+					}
+					if (result.Type != "rectanglelabels" && result.Type != "labels") || len(labels) == 0 {
+						continue
+					}
+					imported.Boxes = append(imported.Boxes, models.Prediction{
+						Label: labels[0],
+						Score: 1,
+						Rect: models.Rect{
+							X0: result.Value.X / 100,
+							Y0: result.Value.Y / 100,
+							X1: (result.Value.X + result.Value.Width) / 100,
+							Y1: (result.Value.Y + result.Value.Height) / 100,
+						},
+					})
+				}
+				break
+			}
+			images = append(images, imported)
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"smart-docs/core/models"
+	"strconv"
+	"strings"
+)
+
+// parseYolo reads label files "class cx cy w h", optionally followed by a score, with class names taken from data.yaml
+// or classes.txt. Polygons of segmentation datasets, "class x1 y1 x2 y2 ...", are skipped.
+func parseYolo(files map[string][]byte) ([]imageAnnotations, error) {
+	var names map[int]string
+	for name, content := range files {
+		switch path.Base(name) {
+		case "data.yaml", "data.yml":
+			names = parseYoloYamlNames(string(content))
+		case "classes.txt":
+			names = map[int]string{}
+			for i, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+				names[i] = strings.TrimSpace(line)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("class names not found, expected data.yaml or classes.txt")
+	}
+
+	var images []imageAnnotations
+	for name, content := range files {
+		if path.Ext(name) != ".txt" || path.Base(name) == "classes.txt" {
+			continue
+		}
+		imported := imageAnnotations{Key: name, Normalised: true, Boxes: []models.Prediction{}}
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for line := 1; scanner.Scan(); line++ {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) < 5 {
+				return nil, fmt.Errorf("%s:%d: expected class cx cy w h", name, line)
+			}
+			if len(fields) > 6 {
+				imported.Skipped = append(imported.Skipped, fmt.Sprintf("%s:%d: polygons are not supported", name, line))
+				continue
+			}
+			class, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid class %q", name, line, fields[0])
+			}
+			label, ok := names[class]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown class %d", name, line, class)
+			}
+			values := make([]float32, 5)
+			values[4] = 1
+			for i := 1; i < len(fields); i++ {
+				value, err := strconv.ParseFloat(fields[i], 32)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: invalid number %q", name, line, fields[i])
+				}
+				values[i-1] = float32(value)
+			}
+			cx, cy, w, h := values[0], values[1], values[2], values[3]
+			imported.Boxes = append(imported.Boxes, models.Prediction{
+				Label: label,
+				Score: values[4],
+				Rect: models.Rect{
+					X0: cx - w/2,
+					Y0: cy - h/2,
+					X1: cx + w/2,
+					Y1: cy + h/2,
+				},
+			})
+		}
+		images = append(images, imported)
+	}
+	return images, nil
+}
+
+// parseYoloYamlNames understands the "names" forms written by common YOLO tooling:
+// an inline list, a block list or a map of class ids to names.
+func parseYoloYamlNames(content string) map[int]string {
+	names := map[int]string{}
+	inNames := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "names:") {
+			inline := strings.TrimSpace(strings.TrimPrefix(line, "names:"))
+			if strings.HasPrefix(inline, "[") {
+				for i, item := range strings.Split(strings.Trim(inline, "[]"), ",") {
+					names[i] = unquoteYaml(item)
+				}
+				return names
+			}
+			inNames = true
+			continue
+		}
+		if !inNames || trimmed == "" {
+			continue
+		}
+		if line == trimmed {
+			// back at top level
+			break
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			names[len(names)] = unquoteYaml(strings.TrimPrefix(trimmed, "- "))
+			continue
+		}
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(key))
+		if err == nil {
+			names[id] = unquoteYaml(value)
+		}
+	}
+	return names
+}
+
+func unquoteYaml(value string) string {
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return strings.Trim(value, `'`)
+}
@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestParseYolo(t *testing.T) {
+	files := map[string][]byte{
+		"data.yaml": []byte("names:\n  0: text\n  1: table\n"),
+		"labels/train/3_1.txt": []byte(
+			"0 0.5 0.5 0.2 0.4\n" +
+				"1 0.5 0.5 1 1 0.75\n" +
+				"\n" +
+				"0 0.1 0.1 0.3 0.1 0.3 0.3 0.1 0.3\n",
+		),
+	}
+	images, err := parseYolo(files)
+	if err != nil {
+		t.Fatalf("parseYolo() error = %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("parseYolo() = %d images, want 1", len(images))
+	}
+	want := []models.Prediction{
+		{Rect: models.Rect{X0: 0.4, Y0: 0.3, X1: 0.6, Y1: 0.7}, Score: 1, Label: "text"},
+		{Rect: models.Rect{X0: 0, Y0: 0, X1: 1, Y1: 1}, Score: 0.75, Label: "table"},
+	}
+	if !slices.EqualFunc(images[0].Boxes, want, func(a, b models.Prediction) bool {
+		return a.Label == b.Label && a.Score == b.Score && a.Rect == b.Rect
+	}) {
+		t.Errorf("parseYolo() boxes = %+v, want %+v", images[0].Boxes, want)
+	}
+	if skipped := []string{"labels/train/3_1.txt:4: polygons are not supported"}; !slices.Equal(images[0].Skipped, skipped) {
+		t.Errorf("parseYolo() skipped = %q, want %q", images[0].Skipped, skipped)
+	}
+}
+
+func TestParseYoloErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string][]byte
+	}{
+		{"no class names", map[string][]byte{"a.txt": []byte("0 0.5 0.5 0.2 0.2\n")}},
+		{"short line", map[string][]byte{"classes.txt": []byte("text\n"), "a.txt": []byte("0 0.5 0.5 0.2\n")}},
+		{"unknown class", map[string][]byte{"classes.txt": []byte("text\n"), "a.txt": []byte("1 0.5 0.5 0.2 0.2\n")}},
+		{"invalid number", map[string][]byte{"classes.txt": []byte("text\n"), "a.txt": []byte("0 0.5 half 0.2 0.2\n")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseYolo(test.files); err == nil {
+				t.Errorf("parseYolo() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 )
 
 type Client struct {
@@ -98,7 +96,8 @@ func (c *Client) UploadFile(filePath string) (string, error) {
 	return result.ID, nil
 }
 
-func (c *Client) ParseFile(fileId string) ([]string, error) {
+// ParseFile runs OCR on an uploaded file and returns the markdown of every page together with its images.
+func (c *Client) ParseFile(fileId string) ([]Page, error) {
 	client := &http.Client{}
 
 	// Get file URL
@@ -175,26 +174,5 @@ func (c *Client) ParseFile(fileId string) ([]string, error) {
 		return nil, fmt.Errorf("error decoding OCR response: %w", err)
 	}
 
-	// Process each page and replace image references
-	markdownPages := make([]string, len(ocrResponse.Pages))
-	for i, page := range ocrResponse.Pages {
-		markdown := page.Markdown
-		re := regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
-		matches := re.FindAllStringSubmatch(markdown, -1)
-
-		for _, match := range matches {
-			if match[1] == match[2] {
-				for _, img := range page.Images {
-					if strings.Contains(img.ID, match[1]) {
-						imgRef := fmt.Sprintf(`<img src="%s"/>`, img.ImageBase64)
-						mdRef := fmt.Sprintf("![%s](%s)", match[1], match[2])
-						markdown = strings.Replace(markdown, mdRef, imgRef, 1)
-					}
-				}
-			}
-		}
-		markdownPages[i] = markdown
-	}
-
-	return markdownPages, nil
+	return ocrResponse.Pages, nil
 }
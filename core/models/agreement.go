@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// AnnotationSet is the independent annotation of a page by one annotator.
+type AnnotationSet struct {
+	Annotator   string       `json:"annotator"`
+	Predictions []Prediction `json:"predictions"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// AgreementPage is a page selected to be annotated independently by several annotators.
+type AgreementPage struct {
+	DocumentId   int64           `json:"documentId"`
+	DocumentName string          `json:"documentName"`
+	PageNum      int             `json:"pageNum"`
+	Status       string          `json:"status"`
+	Required     int             `json:"required"`
+	Sets         []AnnotationSet `json:"sets"`
+}
+
+// Agreement between annotations. Boxes is the share of boxes matched by overlap, IoU the mean overlap of matched boxes
+// and Labels the share of matched boxes given the same label.
+type Agreement struct {
+	Boxes  float64 `json:"boxes"`
+	IoU    float64 `json:"iou"`
+	Labels float64 `json:"labels"`
+}
+
+type PageAgreement struct {
+	AgreementPage
+	Agreement
+	Pairs int `json:"pairs"`
+}
+
+type AnnotatorAgreement struct {
+	Annotator string `json:"annotator"`
+	Agreement
+	Pages int `json:"pages"`
+}
+
+type AgreementReport struct {
+	Overall    Agreement            `json:"overall"`
+	Pages      []PageAgreement      `json:"pages"`
+	Annotators []AnnotatorAgreement `json:"annotators"`
+}
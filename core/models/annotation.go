@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+const (
+	SourceModel = "model"
+	SourceHuman = "human"
+)
+
+// Annotation is a set of predictions for a page, either produced by the detector or saved by an annotator.
+type Annotation struct {
+	Id          int64        `json:"id"`
+	DocumentId  int64        `json:"documentId"`
+	PageNum     int          `json:"pageNum"`
+	Source      string       `json:"source"`
+	Author      string       `json:"author"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Predictions []Prediction `json:"predictions"`
+}
+
+// AnnotationDiff lists what changed between two prediction sets. Indexes point into the older and the newer set.
+type AnnotationDiff struct {
+	Added      []IndexedPrediction `json:"added"`
+	Removed    []IndexedPrediction `json:"removed"`
+	Moved      []BoxChange         `json:"moved"`
+	Relabelled []BoxChange         `json:"relabelled"`
+	Tables     []TableChange       `json:"tables"`
+}
+
+type IndexedPrediction struct {
+	Index      int        `json:"index"`
+	Prediction Prediction `json:"prediction"`
+}
+
+type BoxChange struct {
+	FromIndex int        `json:"fromIndex"`
+	ToIndex   int        `json:"toIndex"`
+	Before    Prediction `json:"before"`
+	After     Prediction `json:"after"`
+}
+
+// TableChange holds changes of the cells of a table present in both sets.
+type TableChange struct {
+	FromIndex int            `json:"fromIndex"`
+	ToIndex   int            `json:"toIndex"`
+	Cells     AnnotationDiff `json:"cells"`
+}
+
+func (d AnnotationDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Relabelled) == 0 && len(d.Tables) == 0
+}
+
+// AnnotationVersion is an annotation together with its changes against the previous version of the page.
+type AnnotationVersion struct {
+	Annotation
+	Changes AnnotationDiff
+	First   bool
+}
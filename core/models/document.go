@@ -15,5 +15,18 @@ type Document struct {
 	IsLast        bool `json:"-"`
 	Offset        int  `json:"-"`
 	Mode          string
+	Project       string
 	MistralFileId *string
 }
+
+// DocumentFilter narrows a document listing, empty fields and zero dates match every document.
+type DocumentFilter struct {
+	Status  string
+	Mode    string
+	Project string
+	// Documents uploaded from this time on and before To
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EntitySpan tags the words Start to End, both included, of the page word list with an entity label.
+// Text and Words are filled from the current words of the page when spans are listed.
+type EntitySpan struct {
+	Id         int64      `json:"id"`
+	DocumentId int64      `json:"documentId"`
+	PageNum    int        `json:"pageNum"`
+	Label      string     `json:"label"`
+	Start      int        `json:"start"`
+	End        int        `json:"end"`
+	Text       string     `json:"text"`
+	Words      []PageWord `json:"words"`
+	Author     string     `json:"author"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Field is a key-value pair of a form page, such as "Invoice number: 12345". Source tells whether it was extracted
+// by the pipeline or entered by an annotator.
+type Field struct {
+	Id         int64     `json:"id"`
+	DocumentId int64     `json:"documentId"`
+	PageNum    int       `json:"pageNum"`
+	Key        string    `json:"key"`
+	Value      string    `json:"value"`
+	KeyBox     Rect      `json:"keyBox"`
+	ValueBox   Rect      `json:"valueBox"`
+	Source     string    `json:"source"`
+	Author     string    `json:"author"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
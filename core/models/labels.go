@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PageParent is the parent of boxes placed directly on the page rather than nested in another box.
+const PageParent = "page"
+
+// Renderers decide how the words of a box end up in the html and markdown of a page.
+const (
+	RenderParagraph = "paragraph"
+	RenderHeading   = "heading"
+	RenderTable     = "table"
+	RenderImage     = "image"
+	RenderCell      = "cell"
+	RenderText      = "text"
+)
+
+var Renderers = []string{RenderParagraph, RenderHeading, RenderTable, RenderImage, RenderCell, RenderText}
+
+// fallbackColor is used to draw boxes with labels missing from the schema.
+const fallbackColor = "#008000"
+
+type Label struct {
+	Name     string   `json:"name"`
+	Color    string   `json:"color"`
+	Parents  []string `json:"parents"`
+	Renderer string   `json:"renderer"`
+}
+
+// EntityLabel tags ranges of words of the page text, such as dates or amounts.
+type EntityLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// LabelSchema lists the labels annotators may use in a project, in the order they are offered in the annotation tool.
+// Labels are drawn as boxes on the page, Entities tag words of the page text. Table cells are typed as numbers,
+// amounts, percentages and dates written as in Locale, when it is set.
+type LabelSchema struct {
+	Project  string        `json:"project"`
+	Labels   []Label       `json:"labels"`
+	Entities []EntityLabel `json:"entities"`
+	Locale   string        `json:"locale"`
+}
+
+func (s LabelSchema) Entity(name string) (EntityLabel, bool) {
+	for _, entity := range s.Entities {
+		if entity.Name == name {
+			return entity, true
+		}
+	}
+	return EntityLabel{}, false
+}
+
+func (s LabelSchema) Label(name string) (Label, bool) {
+	for _, label := range s.Labels {
+		if label.Name == name {
+			return label, true
+		}
+	}
+	return Label{}, false
+}
+
+// Renderer returns how boxes with the label are rendered, unknown labels are rendered as plain text.
+func (s LabelSchema) Renderer(name string) string {
+	if label, ok := s.Label(name); ok {
+		return label.Renderer
+	}
+	return RenderText
+}
+
+func (s LabelSchema) Color(name string) string {
+	if label, ok := s.Label(name); ok {
+		return label.Color
+	}
+	return fallbackColor
+}
+
+// Children returns labels allowed inside boxes with the given label, PageParent for labels allowed on the page.
+func (s LabelSchema) Children(parent string) []Label {
+	var children []Label
+	for _, label := range s.Labels {
+		if slices.Contains(label.Parents, parent) {
+			children = append(children, label)
+		}
+	}
+	return children
+}
+
+// Check verifies the schema itself: unique names, known renderers, colours and parents.
+// Entity names end up in BIO tags like "B-date", so they cannot contain dashes or spaces.
+func (s LabelSchema) Check() error {
+	names := map[string]bool{}
+	for _, label := range s.Labels {
+		if label.Name == "" || label.Name == PageParent {
+			return fmt.Errorf("invalid label name %q", label.Name)
+		}
+		if names[label.Name] {
+			return fmt.Errorf("label %q is defined twice", label.Name)
+		}
+		names[label.Name] = true
+		if !slices.Contains(Renderers, label.Renderer) {
+			return fmt.Errorf("label %q: unknown renderer %q", label.Name, label.Renderer)
+		}
+		if !isHexColor(label.Color) {
+			return fmt.Errorf("label %q: colour must be written as #rrggbb", label.Name)
+		}
+		if len(label.Parents) == 0 {
+			return fmt.Errorf("label %q: at least one parent is required", label.Name)
+		}
+	}
+	for _, label := range s.Labels {
+		for _, parent := range label.Parents {
+			if parent != PageParent && !names[parent] {
+				return fmt.Errorf("label %q: unknown parent %q", label.Name, parent)
+			}
+		}
+	}
+	entities := map[string]bool{}
+	for _, entity := range s.Entities {
+		if entity.Name == "" || strings.ContainsAny(entity.Name, " -") {
+			return fmt.Errorf("invalid entity name %q, spaces and dashes are not allowed", entity.Name)
+		}
+		if entities[entity.Name] {
+			return fmt.Errorf("entity %q is defined twice", entity.Name)
+		}
+		entities[entity.Name] = true
+		if !isHexColor(entity.Color) {
+			return fmt.Errorf("entity %q: colour must be written as #rrggbb", entity.Name)
+		}
+	}
+	return nil
+}
+
+func isHexColor(value string) bool {
+	if len(value) != 7 || value[0] != '#' {
+		return false
+	}
+	for _, c := range value[1:] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
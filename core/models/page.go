@@ -1,6 +1,9 @@
 package models
 
-import "html/template"
+import (
+	"encoding/json"
+	"html/template"
+)
 
 type Rect struct {
 	X0 float32 `json:"x0"`
@@ -35,6 +38,8 @@ type Prediction struct {
 	Score float32      `json:"score"`
 	Label string       `json:"label"`
 	Table []Prediction `json:"table"`
+	// Grid is the logical structure of a table, kept once the table was rendered or edited
+	Grid *TableGrid `json:"grid,omitempty"`
 }
 
 type Page struct {
@@ -52,6 +57,16 @@ type Page struct {
 	Height      int
 }
 
+// Words returns the words used for parsing: ocr results when the page was ocr-ed, embedded pdf text otherwise.
+func (p Page) Words() ([]WordData, error) {
+	if p.OcrText == "" {
+		return p.PdfText, nil
+	}
+	var words []WordData
+	err := json.Unmarshal([]byte(p.OcrText), &words)
+	return words, err
+}
+
 type PageView struct {
 	Id              int64
 	DocumentName    string
@@ -66,6 +81,12 @@ type PageView struct {
 	Html            template.HTML
 	Width           int
 	Height          int
+	Search          string
+	Highlights      []Rect
+	Lock            *Lease
+	Transitions     []Transition
+	Rejection       *StatusChange
+	CanReview       bool
+	// Pages selected for agreement are annotated independently by this many annotators
+	RequiredAnnotators int
 }
-
-// TODO: Only allow 2 states from doc view and 3 states from training view
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// AnonymousUser makes requests without a name. Everyone without one shares it, so it cannot hold pages.
+const AnonymousUser = "anonymous"
+
+// QueueItem is a page waiting for annotation, with who it is assigned to and who is currently annotating it.
+type QueueItem struct {
+	PageId         int64      `json:"pageId"`
+	DocumentId     int64      `json:"documentId"`
+	DocumentName   string     `json:"documentName"`
+	PageNum        int        `json:"pageNum"`
+	UploadDate     time.Time  `json:"uploadDate"`
+	Assignee       string     `json:"assignee,omitempty"`
+	LeasedBy       string     `json:"leasedBy,omitempty"`
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	Confidence     float32    `json:"confidence"`
+	Rarity         int        `json:"rarity"`
+	// Why the active learning policy sent the page to annotation, empty for pages queued by hand
+	Reason string `json:"reason,omitempty"`
+	// Pages selected for agreement are handed to several annotators, Annotators lists who took the page
+	// and Finished who of them saved their annotation.
+	RequiredAnnotators int      `json:"requiredAnnotators,omitempty"`
+	Annotators         []string `json:"annotators,omitempty"`
+	Finished           []string `json:"finished,omitempty"`
+
+	Predictions []Prediction `json:"-"`
+}
+
+// Lease is a claim of a page by an annotator, other annotators cannot save the page until it expires.
+type Lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
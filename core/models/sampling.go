@@ -0,0 +1,18 @@
+package models
+
+// SamplingCandidate is a page waiting in PREDICTION that the active learning policy has not looked at yet.
+type SamplingCandidate struct {
+	PageId      int64
+	DocumentId  int64
+	PageNum     int
+	Predictions []Prediction
+}
+
+// SampledPage is a page the active learning policy found worth annotating.
+type SampledPage struct {
+	PageId     int64
+	DocumentId int64
+	PageNum    int
+	Reasons    string
+	Confidence float32
+}
@@ -0,0 +1,13 @@
+package models
+
+import "html/template"
+
+type SearchHit struct {
+	DocumentId   int64         `json:"documentId"`
+	DocumentName string        `json:"documentName"`
+	PageNum      int           `json:"pageNum"`
+	Snippet      template.HTML `json:"snippet"`
+	Width        int           `json:"width"`
+	Height       int           `json:"height"`
+	Rects        []Rect        `json:"rects"`
+}
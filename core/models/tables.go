@@ -0,0 +1,82 @@
+package models
+
+// TableGrid is the logical structure of a table: its rows and columns, the cells placed on them and the rows forming
+// the header. Rendering uses it as long as the cell boxes of the table are the ones it produced.
+type TableGrid struct {
+	// Bands of the rows and columns, relative to the table like its cells
+	Rows       []Band     `json:"rows"`
+	Columns    []Band     `json:"columns"`
+	HeaderRows int        `json:"headerRows"`
+	Cells      []GridCell `json:"cells"`
+}
+
+// Band is the extent of a row or a column.
+type Band struct {
+	Start float32 `json:"start"`
+	End   float32 `json:"end"`
+}
+
+// GridCell covers Rowspan rows and Colspan columns from Row and Col. Text replaces the words found in the cell when set.
+// Value is the typed value of the cell text found when the page was last rendered, when the project has a locale.
+type GridCell struct {
+	Row     int        `json:"row"`
+	Col     int        `json:"col"`
+	Rowspan int        `json:"rowspan"`
+	Colspan int        `json:"colspan"`
+	Label   string     `json:"label"`
+	Text    *string    `json:"text,omitempty"`
+	Value   *CellValue `json:"value,omitempty"`
+}
+
+// Rect returns the box of a cell relative to the table.
+func (g TableGrid) Rect(cell GridCell) Rect {
+	return Rect{
+		X0: g.Columns[cell.Col].Start,
+		Y0: g.Rows[cell.Row].Start,
+		X1: g.Columns[cell.Col+cell.Colspan-1].End,
+		Y1: g.Rows[cell.Row+cell.Rowspan-1].End,
+	}
+}
+
+// Boxes returns the cell boxes of the grid as stored in Prediction.Table.
+func (g TableGrid) Boxes() []Prediction {
+	boxes := make([]Prediction, len(g.Cells))
+	for i, cell := range g.Cells {
+		boxes[i] = Prediction{Rect: g.Rect(cell), Score: 1, Label: cell.Label, Table: []Prediction{}}
+	}
+	return boxes
+}
+
+// Matches tells whether the cell boxes of a table are still the ones of the grid, so it was not redrawn since.
+func (g TableGrid) Matches(cells []Prediction) bool {
+	if len(cells) != len(g.Cells) {
+		return false
+	}
+	for i, cell := range g.Cells {
+		if cell.Row < 0 || cell.Col < 0 || cell.Rowspan < 1 || cell.Colspan < 1 ||
+			cell.Row+cell.Rowspan > len(g.Rows) || cell.Col+cell.Colspan > len(g.Columns) {
+			return false
+		}
+		if g.Rect(cell) != cells[i].Rect {
+			return false
+		}
+	}
+	return true
+}
+
+// Types of normalised cell values.
+const (
+	ValueNumber     = "number"
+	ValueCurrency   = "currency"
+	ValuePercentage = "percentage"
+	ValueDate       = "date"
+)
+
+// CellValue is the typed value of a table cell. Numbers, amounts and percentages (as fractions) are in Number,
+// amounts also carry the ISO 4217 Currency code, dates are ISO dates in Date.
+type CellValue struct {
+	Type     string   `json:"type"`
+	Number   *float64 `json:"number,omitempty"`
+	Currency string   `json:"currency,omitempty"`
+	Date     string   `json:"date,omitempty"`
+}
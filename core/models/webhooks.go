@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outcomes of webhook deliveries. Pending deliveries are sent, or retried, when they are due.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint notified of events. The secret signs the deliveries, it is only shown when the webhook is
+// created.
+type Webhook struct {
+	Id        int64     `json:"id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookDelivery is an event sent to a webhook, with the response to its last attempt.
+type WebhookDelivery struct {
+	Id             int64           `json:"id"`
+	WebhookId      int64           `json:"webhookId"`
+	Url            string          `json:"url"`
+	Secret         string          `json:"-"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	WordEditText = "edit"
+	WordMerge    = "merge"
+	WordSplit    = "split"
+	WordAdd      = "add"
+	WordDelete   = "delete"
+)
+
+// PageWord is a word of a page as shown to annotators, Index is its position in the word list of the page.
+type PageWord struct {
+	Rect
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// WordEdit is a correction of the words of a page: Before lists the words that were at Index and After the words
+// put in their place. Merged words are listed in Before in their original order.
+type WordEdit struct {
+	Id         int64      `json:"id"`
+	DocumentId int64      `json:"documentId"`
+	PageNum    int        `json:"pageNum"`
+	Operation  string     `json:"operation"`
+	Index      int        `json:"index"`
+	Before     []WordData `json:"before"`
+	After      []WordData `json:"after"`
+	Author     string     `json:"author"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CorrectedPage is a page whose words were corrected, with the words as they were extracted.
+type CorrectedPage struct {
+	Page
+	OriginalWords []WordData
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// StatusChange is a recorded move of a page between workflow states.
+type StatusChange struct {
+	Id         int64     `json:"id"`
+	DocumentId int64     `json:"documentId"`
+	PageNum    int       `json:"pageNum"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	User       string    `json:"user"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Transition is a status change offered to the current user.
+type Transition struct {
+	To             string `json:"to"`
+	Name           string `json:"name"`
+	RequiresReason bool   `json:"requiresReason"`
+	Shortcut       string `json:"shortcut,omitempty"`
+}
+
+// ReviewItem is a page submitted for review.
+type ReviewItem struct {
+	DocumentId   int64     `json:"documentId"`
+	DocumentName string    `json:"documentName"`
+	PageNum      int       `json:"pageNum"`
+	SubmittedBy  string    `json:"submittedBy"`
+	SubmittedAt  time.Time `json:"submittedAt"`
+}
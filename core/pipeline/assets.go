@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"smart-docs/core/mistral"
+	"strings"
+)
+
+// PageImagePath is the rendered image of a page, which all coordinates refer to.
+func PageImagePath(docId int64, pageNum int) string {
+	return fmt.Sprintf("./data/images/%d/%d.jpg", docId, pageNum)
+}
+
+// AssetDir is the directory holding images extracted from the pages of a document.
+func AssetDir(docId int64) string {
+	return fmt.Sprintf("./data/images/%d/assets", docId)
+}
+
+// AssetUrl is the authenticated URL under which an extracted image is served.
+func AssetUrl(docId int64, name string) string {
+	return fmt.Sprintf("/document/%d/assets/%s", docId, name)
+}
+
+// illustrationAssetName names the crop of the illustration found at the given reading order position of a page.
+func illustrationAssetName(pageNum int, index int) string {
+	return fmt.Sprintf("%d-%d.jpg", pageNum, index)
+}
+
+func storeAsset(docId int64, name string, data []byte) (string, error) {
+	err := os.MkdirAll(AssetDir(docId), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(filepath.Join(AssetDir(docId), name), data, 0644)
+	if err != nil {
+		return "", err
+	}
+	return AssetUrl(docId, name), nil
+}
+
+// removePageAssets drops images extracted from a page, so re-parsing does not leave stale files behind.
+func removePageAssets(docId int64, pageNum int) {
+	files, err := filepath.Glob(filepath.Join(AssetDir(docId), fmt.Sprintf("%d-*", pageNum)))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
+}
+
+var markdownImageRe = regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
+
+// storeMistralImages saves images returned by Mistral OCR as assets and points the markdown references to them.
+func storeMistralImages(docId int64, pageNum int, page mistral.Page) (string, error) {
+	markdown := page.Markdown
+	for _, match := range markdownImageRe.FindAllStringSubmatch(markdown, -1) {
+		if match[1] != match[2] {
+			continue
+		}
+		for _, img := range page.Images {
+			if !strings.Contains(img.ID, match[1]) {
+				continue
+			}
+			encoded := img.ImageBase64
+			if i := strings.Index(encoded, ","); strings.HasPrefix(encoded, "data:") && i >= 0 {
+				encoded = encoded[i+1:]
+			}
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", fmt.Errorf("cannot decode image %s: %w", img.ID, err)
+			}
+			url, err := storeAsset(docId, fmt.Sprintf("%d-%s", pageNum, filepath.Base(img.ID)), data)
+			if err != nil {
+				return "", fmt.Errorf("cannot store image %s: %w", img.ID, err)
+			}
+			markdown = strings.Replace(markdown, match[0], fmt.Sprintf("![%s](%s)", match[1], url), 1)
+			break
+		}
+	}
+	return markdown, nil
+}
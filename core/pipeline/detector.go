@@ -22,6 +22,11 @@ var (
 	tablePredictorUrl = util.Getenv("TABLE_DETECTOR_URL", "http://localhost:10002")
 )
 
+// DetectorUrls returns the layout and table detectors used to process documents.
+func DetectorUrls() (string, string) {
+	return docPredictorUrl, tablePredictorUrl
+}
+
 type PredictionsResponse struct {
 	Predictions []PredictionResponse `json:"predictions"`
 }
@@ -51,6 +56,11 @@ func GetPageDimensions(page *models.Page) error {
 }
 
 func RunDetectionOnPage(docId int64, page int) ([]models.Prediction, error) {
+	return RunDetectionWith(docId, page, docPredictorUrl, tablePredictorUrl)
+}
+
+// RunDetectionWith runs the given layout and table detectors on a page, e.g. to evaluate a model before switching to it.
+func RunDetectionWith(docId int64, page int, docUrl string, tableUrl string) ([]models.Prediction, error) {
 
 	imageFile, err := os.Open(fmt.Sprintf("./data/images/%d/%d.jpg", docId, page))
 	if err != nil {
@@ -72,7 +82,7 @@ func RunDetectionOnPage(docId int64, page int) ([]models.Prediction, error) {
 	}
 
 	predictions := make([]models.Prediction, 0)
-	docPredictions := runPrediction(imageData, docPredictorUrl)
+	docPredictions := runPrediction(imageData, docUrl)
 	for _, p := range docPredictions {
 		prediction := models.Prediction{
 			Score: p.Score,
@@ -89,7 +99,7 @@ func RunDetectionOnPage(docId int64, page int) ([]models.Prediction, error) {
 			if err != nil {
 				log.Println(fmt.Sprintf("Error detecting segments: \n%+v", err))
 			} else {
-				tablePredictions := runPrediction(cropped, tablePredictorUrl)
+				tablePredictions := runPrediction(cropped, tableUrl)
 				if len(tablePredictions) == 0 {
 					prediction.Label = "paragraph"
 				}
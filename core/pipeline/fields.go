@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"strings"
+)
+
+// fieldExtractor is the author of extracted fields.
+const fieldExtractor = "layout"
+
+// Words further apart than this many word heights belong to different phrases of a line, as form columns do.
+const phraseGap = 1.5
+
+// A value below its key may start this many word heights before or after the key.
+const belowIndent = 2
+
+// phrase is a run of words of a line close enough to each other to be read together.
+type phrase struct {
+	words []models.WordData
+	used  bool
+}
+
+// ExtractFields pairs keys and values on a page from its current words and annotations and stores them in place of
+// the fields extracted before. Fields edited by annotators are kept.
+func ExtractFields(docId int64, pageNum int) ([]models.Field, error) {
+	predictions, err := LoadPredictions(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	words, err := db.GetPdfPageText(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	fields := PairFields(AnalyzeLayout(docId, pageNum, words, predictions))
+	err = db.ReplaceExtractedFields(docId, pageNum, fields)
+	if err != nil {
+		return nil, err
+	}
+	return db.ListFields(docId, pageNum)
+}
+
+// updateFields extracts the fields of a page after it was rendered again, failures only affect the fields.
+func updateFields(docId int64, pageNum int) {
+	_, err := ExtractFields(docId, pageNum)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error extracting fields: \n%+v", err))
+	}
+}
+
+// PairFields finds key-value pairs in the blocks of a page. Keys are phrases ending with a colon, their value follows
+// on the same line up to the next key, or starts right below them within the same block. Rows of two column tables are pairs as well.
+func PairFields(blocks []Block) []models.Field {
+	var fields []models.Field
+	for _, block := range blocks {
+		switch {
+		case len(block.Cells) > 0:
+			fields = append(fields, tableFields(block.Cells)...)
+		case block.Image == "":
+			fields = append(fields, textFields(block.Words)...)
+		}
+	}
+	return fields
+}
+
+func textFields(words []models.WordData) []models.Field {
+	lines := splitLines(words)
+	phrases := make([][]*phrase, len(lines))
+	for l, line := range lines {
+		phrases[l] = splitPhrases(line)
+	}
+
+	var fields []models.Field
+	for l := range phrases {
+		for p, current := range phrases[l] {
+			if current.used || !hasKey(current) {
+				continue
+			}
+			current.used = true
+			// A phrase may hold several keys, every value ends at the next key
+			for start := 0; start < len(current.words); {
+				k := start + slices.IndexFunc(current.words[start:], isKey)
+				end := len(current.words)
+				if next := slices.IndexFunc(current.words[k+1:], isKey); next >= 0 {
+					end = k + 1 + next
+				}
+				key, value := current.words[start:k+1], current.words[k+1:end]
+				start = end
+				last := end == len(current.words)
+				if len(value) == 0 && last && p+1 < len(phrases[l]) && !hasKey(phrases[l][p+1]) {
+					next := phrases[l][p+1]
+					next.used = true
+					value = next.words
+				}
+				if len(value) == 0 && last && l+1 < len(phrases) {
+					if below := phraseBelow(key, phrases[l+1]); below != nil {
+						below.used = true
+						value = below.words
+					}
+				}
+				if len(value) == 0 {
+					continue
+				}
+				fields = append(fields, newField(
+					strings.TrimSuffix(joinWords(key), ":"), bounds(key),
+					joinWords(value), bounds(value),
+				))
+			}
+		}
+	}
+	return fields
+}
+
+// tableFields pairs the cells of a row, a cell ending with a colon with the cell next to it, and the two cells of
+// every row when the table has two columns.
+func tableFields(cells []TableCell) []models.Field {
+	columns := 0
+	for _, cell := range cells {
+		columns = max(columns, cell.Col+max(cell.Colspan, 1))
+	}
+	var fields []models.Field
+	for i, cell := range cells {
+		if i+1 >= len(cells) || cells[i+1].Row != cell.Row || cell.Text == "" || cells[i+1].Text == "" {
+			continue
+		}
+		if strings.HasSuffix(cell.Text, ":") || (columns == 2 && cell.Col == 0) {
+			value := cells[i+1]
+			fields = append(fields, newField(strings.TrimSuffix(cell.Text, ":"), cell.Rect, value.Text, value.Rect))
+		}
+	}
+	return fields
+}
+
+func newField(key string, keyBox models.Rect, value string, valueBox models.Rect) models.Field {
+	return models.Field{
+		Key:      strings.TrimSpace(key),
+		Value:    value,
+		KeyBox:   keyBox,
+		ValueBox: valueBox,
+		Source:   models.SourceModel,
+		Author:   fieldExtractor,
+	}
+}
+
+// splitLines groups words whose vertical centres fall within each other's line, top to bottom and left to right.
+func splitLines(words []models.WordData) [][]models.WordData {
+	sorted := slices.Clone(words)
+	slices.SortStableFunc(sorted, func(a, b models.WordData) int {
+		return cmp.Compare(a.CenterY(), b.CenterY())
+	})
+	var lines [][]models.WordData
+	var top, bottom float32
+	for _, word := range sorted {
+		if len(lines) > 0 && word.CenterY() >= top && word.CenterY() <= bottom {
+			lines[len(lines)-1] = append(lines[len(lines)-1], word)
+			continue
+		}
+		lines = append(lines, []models.WordData{word})
+		top, bottom = word.Y0, word.Y1
+	}
+	for _, line := range lines {
+		slices.SortStableFunc(line, func(a, b models.WordData) int {
+			return cmp.Compare(a.X0, b.X0)
+		})
+	}
+	return lines
+}
+
+func splitPhrases(line []models.WordData) []*phrase {
+	var phrases []*phrase
+	for i, word := range line {
+		if i == 0 || word.X0-line[i-1].X1 > phraseGap*max(word.Height(), line[i-1].Height()) {
+			phrases = append(phrases, &phrase{})
+		}
+		current := phrases[len(phrases)-1]
+		current.words = append(current.words, word)
+	}
+	return phrases
+}
+
+// phraseBelow returns the unused phrase of the next line starting under the key.
+func phraseBelow(key []models.WordData, line []*phrase) *phrase {
+	box := bounds(key)
+	indent := belowIndent * box.Height()
+	for _, candidate := range line {
+		start := candidate.words[0].X0
+		if !candidate.used && !hasKey(candidate) && start >= box.X0-indent && start <= box.X0+indent {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func isKey(word models.WordData) bool {
+	return len(word.Text) > 1 && strings.HasSuffix(word.Text, ":")
+}
+
+func hasKey(p *phrase) bool {
+	return slices.ContainsFunc(p.words, isKey)
+}
+
+func joinWords(words []models.WordData) string {
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+func bounds(words []models.WordData) models.Rect {
+	rect := words[0].Rect
+	for _, word := range words[1:] {
+		rect.X0 = min(rect.X0, word.X0)
+		rect.Y0 = min(rect.Y0, word.Y0)
+		rect.X1 = max(rect.X1, word.X1)
+		rect.Y1 = max(rect.Y1, word.Y1)
+	}
+	return rect
+}
@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func word(text string, x0, y0, x1, y1 float32) models.WordData {
+	return models.WordData{Rect: models.Rect{X0: x0, Y0: y0, X1: x1, Y1: y1}, Text: text}
+}
+
+func pairs(fields []models.Field) []string {
+	var result []string
+	for _, field := range fields {
+		result = append(result, field.Key+"="+field.Value)
+	}
+	return result
+}
+
+func texts(words []models.WordData) []string {
+	var result []string
+	for _, word := range words {
+		result = append(result, word.Text)
+	}
+	return result
+}
+
+func TestPairFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []Block
+		want   []string
+	}{
+		{
+			"value on the same line",
+			[]Block{{Words: []models.WordData{word("Invoice", 0, 0, 40, 10), word("number:", 45, 0, 90, 10), word("12345", 95, 0, 130, 10)}}},
+			[]string{"Invoice number=12345"},
+		},
+		{
+			"several keys on a line",
+			[]Block{{Words: []models.WordData{
+				word("Invoice", 0, 0, 40, 10), word("number:", 45, 0, 90, 10), word("12345", 95, 0, 130, 10),
+				word("Date:", 135, 0, 165, 10), word("2024", 170, 0, 200, 10),
+			}}},
+			[]string{"Invoice number=12345", "Date=2024"},
+		},
+		{
+			"key without value before another key",
+			[]Block{{Words: []models.WordData{word("Name:", 0, 0, 40, 10), word("Date:", 45, 0, 75, 10), word("2024", 80, 0, 110, 10)}}},
+			[]string{"Date=2024"},
+		},
+		{
+			"value in the next phrase",
+			[]Block{{Words: []models.WordData{word("Total:", 0, 0, 40, 10), word("100", 100, 0, 120, 10), word("EUR", 125, 0, 150, 10)}}},
+			[]string{"Total=100 EUR"},
+		},
+		{
+			"next phrase is a key",
+			[]Block{{Words: []models.WordData{word("Name:", 0, 0, 40, 10), word("Date:", 100, 0, 130, 10), word("2024", 135, 0, 165, 10)}}},
+			[]string{"Date=2024"},
+		},
+		{
+			"value below the key",
+			[]Block{{Words: []models.WordData{word("Address:", 0, 0, 50, 10), word("Main", 5, 15, 35, 25), word("Street", 40, 15, 80, 25)}}},
+			[]string{"Address=Main Street"},
+		},
+		{
+			"value below a later key of the line",
+			[]Block{{Words: []models.WordData{
+				word("Number:", 0, 0, 50, 10), word("7", 55, 0, 60, 10), word("Address:", 65, 0, 115, 10),
+				word("Main", 65, 15, 95, 25),
+			}}},
+			[]string{"Number=7", "Address=Main"},
+		},
+		{
+			"key without value",
+			[]Block{{Words: []models.WordData{word("Notes:", 0, 0, 40, 10), word("Paid", 200, 15, 230, 25)}}},
+			nil,
+		},
+		{
+			"two column table",
+			[]Block{{Cells: []TableCell{
+				{Row: 0, Col: 0, Text: "Name"}, {Row: 0, Col: 1, Text: "Ana"},
+				{Row: 1, Col: 0, Text: "City"}, {Row: 1, Col: 1, Text: "Lisbon"},
+			}}},
+			[]string{"Name=Ana", "City=Lisbon"},
+		},
+		{
+			"images are skipped",
+			[]Block{{Image: "figure.png", Words: []models.WordData{word("Total:", 0, 0, 40, 10), word("100", 45, 0, 65, 10)}}},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pairs(PairFields(test.blocks)); !slices.Equal(got, test.want) {
+				t.Errorf("PairFields() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPairFieldsBoxes(t *testing.T) {
+	fields := PairFields([]Block{{Words: []models.WordData{
+		word("Invoice", 0, 0, 40, 10), word("number:", 45, 0, 90, 10), word("12345", 95, 0, 130, 10),
+		word("Date:", 135, 0, 165, 10), word("2024", 170, 0, 200, 10),
+	}}})
+	want := [][2]models.Rect{
+		{{X0: 0, Y0: 0, X1: 90, Y1: 10}, {X0: 95, Y0: 0, X1: 130, Y1: 10}},
+		{{X0: 135, Y0: 0, X1: 165, Y1: 10}, {X0: 170, Y0: 0, X1: 200, Y1: 10}},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("PairFields() = %d fields, want %d", len(fields), len(want))
+	}
+	for i, field := range fields {
+		if field.KeyBox != want[i][0] || field.ValueBox != want[i][1] {
+			t.Errorf("field %d boxes = %+v, %+v, want %+v, %+v", i, field.KeyBox, field.ValueBox, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []models.WordData
+		want  [][]string
+	}{
+		{"empty", nil, nil},
+		{
+			"one line out of order",
+			[]models.WordData{word("b", 20, 0, 30, 10), word("a", 0, 0, 10, 10)},
+			[][]string{{"a", "b"}},
+		},
+		{
+			"slightly shifted words on a line",
+			[]models.WordData{word("a", 0, 0, 10, 10), word("b", 20, 3, 30, 13)},
+			[][]string{{"a", "b"}},
+		},
+		{
+			"lines top to bottom",
+			[]models.WordData{word("c", 0, 30, 10, 40), word("a", 0, 0, 10, 10), word("b", 20, 15, 30, 25)},
+			[][]string{{"a"}, {"b"}, {"c"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]string
+			for _, line := range splitLines(test.words) {
+				got = append(got, texts(line))
+			}
+			if !slices.EqualFunc(got, test.want, slices.Equal[[]string]) {
+				t.Errorf("splitLines() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPhraseBelow(t *testing.T) {
+	key := []models.WordData{word("Address:", 100, 0, 150, 10)}
+	tests := []struct {
+		name string
+		line []*phrase
+		want string
+	}{
+		{"aligned", []*phrase{{words: []models.WordData{word("Main", 100, 15, 130, 25)}}}, "Main"},
+		{"indented", []*phrase{{words: []models.WordData{word("Main", 115, 15, 145, 25)}}}, "Main"},
+		{"too far right", []*phrase{{words: []models.WordData{word("Main", 125, 15, 155, 25)}}}, ""},
+		{"too far left", []*phrase{{words: []models.WordData{word("Main", 75, 15, 105, 25)}}}, ""},
+		{"used", []*phrase{{words: []models.WordData{word("Main", 100, 15, 130, 25)}, used: true}}, ""},
+		{"a key", []*phrase{{words: []models.WordData{word("City:", 100, 15, 130, 25)}}}, ""},
+		{
+			"second phrase",
+			[]*phrase{{words: []models.WordData{word("Name", 0, 15, 30, 25)}}, {words: []models.WordData{word("Main", 100, 15, 130, 25)}}},
+			"Main",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ""
+			if below := phraseBelow(key, test.line); below != nil {
+				got = joinWords(below.words)
+			}
+			if got != test.want {
+				t.Errorf("phraseBelow() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTableFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []TableCell
+		want  []string
+	}{
+		{
+			"two columns",
+			[]TableCell{{Row: 0, Col: 0, Text: "Name"}, {Row: 0, Col: 1, Text: "Ana"}, {Row: 1, Col: 0, Text: "City"}, {Row: 1, Col: 1, Text: "Lisbon"}},
+			[]string{"Name=Ana", "City=Lisbon"},
+		},
+		{
+			"empty cells",
+			[]TableCell{{Row: 0, Col: 0, Text: "Name"}, {Row: 0, Col: 1, Text: ""}, {Row: 1, Col: 0, Text: ""}, {Row: 1, Col: 1, Text: "Lisbon"}},
+			nil,
+		},
+		{
+			"keys with a colon in wider tables",
+			[]TableCell{{Row: 0, Col: 0, Text: "Name:"}, {Row: 0, Col: 1, Text: "Ana"}, {Row: 0, Col: 2, Text: "City:"}, {Row: 0, Col: 3, Text: "Lisbon"}},
+			[]string{"Name=Ana", "City=Lisbon"},
+		},
+		{
+			"wider tables without colons",
+			[]TableCell{{Row: 0, Col: 0, Text: "Item"}, {Row: 0, Col: 1, Text: "Qty"}, {Row: 0, Col: 2, Text: "Price"}},
+			nil,
+		},
+		{
+			"spanning cells widen the table",
+			[]TableCell{{Row: 0, Col: 0, Text: "Name"}, {Row: 0, Col: 1, Colspan: 2, Text: "Ana"}},
+			nil,
+		},
+		{
+			"key at the end of a row",
+			[]TableCell{{Row: 0, Col: 0, Text: "Item"}, {Row: 0, Col: 1, Text: "Qty"}, {Row: 0, Col: 2, Text: "Total:"}, {Row: 1, Col: 0, Text: "100"}},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pairs(tableFields(test.cells)); !slices.Equal(got, test.want) {
+				t.Errorf("tableFields() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"cmp"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+)
+
+const (
+	// Boxes overlapping at least this much are considered the same box when diffing versions.
+	diffMinIoU = 0.5
+	// Edges shifted less than a pixel are not reported as moves.
+	diffMoveTolerance = 1
+)
+
+// RestoreAnnotation makes an earlier annotation of a page current again. The restore is stored as a new version by the author.
+func RestoreAnnotation(docId int64, pageNum int, annotationId int64, author string) ([]models.Prediction, error) {
+	annotation, err := db.GetAnnotation(docId, pageNum, annotationId)
+	if err != nil {
+		return nil, err
+	}
+	predictions := clonePredictions(annotation.Predictions)
+	err = ApplyPredictions(docId, pageNum, &predictions, author)
+	return predictions, err
+}
+
+// DiffPredictions pairs boxes of two prediction sets by overlap and reports boxes added, removed, moved or relabelled,
+// and changes of cells of tables present in both sets.
+func DiffPredictions(from []models.Prediction, to []models.Prediction) models.AnnotationDiff {
+	diff := models.AnnotationDiff{
+		Added:      []models.IndexedPrediction{},
+		Removed:    []models.IndexedPrediction{},
+		Moved:      []models.BoxChange{},
+		Relabelled: []models.BoxChange{},
+		Tables:     []models.TableChange{},
+	}
+
+	type pair struct {
+		from int
+		to   int
+		iou  float32
+	}
+	var pairs []pair
+	for i, before := range from {
+		for j, after := range to {
+			if iou := IoU(before.Rect, after.Rect); iou >= diffMinIoU || before.Rect == after.Rect {
+				pairs = append(pairs, pair{from: i, to: j, iou: iou})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		return cmp.Compare(b.iou, a.iou)
+	})
+
+	matchedFrom := make([]bool, len(from))
+	matchedTo := make([]bool, len(to))
+	for _, p := range pairs {
+		if matchedFrom[p.from] || matchedTo[p.to] {
+			continue
+		}
+		matchedFrom[p.from] = true
+		matchedTo[p.to] = true
+
+		before, after := from[p.from], to[p.to]
+		change := models.BoxChange{FromIndex: p.from, ToIndex: p.to, Before: before, After: after}
+		if moved(before.Rect, after.Rect) {
+			diff.Moved = append(diff.Moved, change)
+		}
+		if before.Label != after.Label {
+			diff.Relabelled = append(diff.Relabelled, change)
+		}
+		if before.Label == "table" && after.Label == "table" {
+			cells := DiffPredictions(before.Table, after.Table)
+			if !cells.Empty() {
+				diff.Tables = append(diff.Tables, models.TableChange{FromIndex: p.from, ToIndex: p.to, Cells: cells})
+			}
+		}
+	}
+
+	for i, before := range from {
+		if !matchedFrom[i] {
+			diff.Removed = append(diff.Removed, models.IndexedPrediction{Index: i, Prediction: before})
+		}
+	}
+	for j, after := range to {
+		if !matchedTo[j] {
+			diff.Added = append(diff.Added, models.IndexedPrediction{Index: j, Prediction: after})
+		}
+	}
+	return diff
+}
+
+// History returns the annotations of a page, newest first, each with its changes against the one before it.
+func History(docId int64, pageNum int) ([]models.AnnotationVersion, error) {
+	annotations, err := db.ListAnnotations(docId, pageNum, "")
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]models.AnnotationVersion, len(annotations))
+	for i, annotation := range annotations {
+		versions[i].Annotation = annotation
+		if i+1 < len(annotations) {
+			versions[i].Changes = DiffPredictions(annotations[i+1].Predictions, annotation.Predictions)
+		} else {
+			versions[i].First = true
+		}
+	}
+	return versions, nil
+}
+
+func moved(a models.Rect, b models.Rect) bool {
+	return absDiff(a.X0, b.X0) >= diffMoveTolerance ||
+		absDiff(a.X1, b.X1) >= diffMoveTolerance ||
+		absDiff(a.Y0, b.Y0) >= diffMoveTolerance ||
+		absDiff(a.Y1, b.Y1) >= diffMoveTolerance
+}
@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"smart-docs/core/models"
+	"strings"
+)
+
+// Block is a segment of a page together with the words assigned to it.
+type Block struct {
+	models.Prediction
+	Order int
+	Text  string
+	Words []models.WordData
+	Cells []TableCell
+	Image string
+}
+
+// TableCell is a cell of the logical table grid. Its coordinates are relative to the page, not to the table.
+type TableCell struct {
+	models.Rect
+	Label   string
+	Row     int
+	Col     int
+	Rowspan int
+	Colspan int
+	Header  bool
+	Text    string
+	Value   *models.CellValue
+	Words   []models.WordData
+}
+
+// AnalyzeLayout assigns words to the predictions and resolves table grids the same way rendering does.
+// It works on a copy of the predictions, so stored annotations are left untouched and no assets are written.
+func AnalyzeLayout(docId int64, pageNum int, words []models.WordData, predictions []models.Prediction) []Block {
+	copied := clonePredictions(predictions)
+	segments := assignWords(&words, &copied, labelSchema(docId))
+
+	blocks := make([]Block, len(segments))
+	for i := range segments {
+		segment := &segments[i]
+		block := Block{
+			Order: i,
+			Text:  strings.TrimSpace(segment.content),
+			Words: segment.words,
+		}
+		switch segment.renderer {
+		case models.RenderTable:
+			block.Cells = tableCells(segment.ParseTable(), segment.X0, segment.Y0)
+		case models.RenderImage:
+			name := illustrationAssetName(pageNum, i)
+			if _, err := os.Stat(filepath.Join(AssetDir(docId), name)); err == nil {
+				block.Image = AssetUrl(docId, name)
+			}
+		}
+		block.Prediction = *segment.Prediction
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// tableCells lists the cells of a table grid in page coordinates.
+func tableCells(table [][]Cell, offsetX float32, offsetY float32) []TableCell {
+	var cells []TableCell
+	for r, row := range table {
+		for _, cell := range row {
+			cells = append(cells, TableCell{
+				Rect: models.Rect{
+					X0: cell.X0 + offsetX,
+					Y0: cell.Y0 + offsetY,
+					X1: cell.X1 + offsetX,
+					Y1: cell.Y1 + offsetY,
+				},
+				Label:   cell.Label,
+				Row:     r,
+				Col:     cell.Col,
+				Rowspan: cell.Rowspan,
+				Colspan: cell.Colspan,
+				Header:  cell.Header,
+				Text:    strings.TrimSpace(cell.content),
+				Value:   cell.Value,
+				Words:   cell.words,
+			})
+		}
+	}
+	return cells
+}
+
+func clonePredictions(predictions []models.Prediction) []models.Prediction {
+	cloned := make([]models.Prediction, len(predictions))
+	for i, prediction := range predictions {
+		cloned[i] = prediction
+		if prediction.Table != nil {
+			cloned[i].Table = clonePredictions(prediction.Table)
+		}
+	}
+	return cloned
+}
+
+// AnalyzeTable lays the words of the page out on the table at the given index of the predictions, like AnalyzeLayout.
+// It returns false when there is no table at the index.
+func AnalyzeTable(docId int64, words []models.WordData, predictions []models.Prediction, index int) (models.TableGrid, []TableCell, bool) {
+	if index < 0 || index >= len(predictions) {
+		return models.TableGrid{}, nil, false
+	}
+	copied := clonePredictions(predictions)
+	segments := assignWords(&words, &copied, labelSchema(docId))
+	for i := range segments {
+		segment := &segments[i]
+		if segment.Prediction != &copied[index] || segment.renderer != models.RenderTable {
+			continue
+		}
+		cells := tableCells(segment.ParseTable(), segment.X0, segment.Y0)
+		return *segment.Grid, cells, true
+	}
+	return models.TableGrid{}, nil, false
+}
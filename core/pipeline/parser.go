@@ -2,7 +2,6 @@ package pipeline
 
 import (
 	"cmp"
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -17,6 +16,9 @@ const minOverlap = 0.5
 
 type Segment struct {
 	*models.Prediction
+	renderer string
+	// locale of typed table cells, empty to leave them untyped
+	locale  string
 	content string
 	words   []models.WordData
 }
@@ -25,7 +27,7 @@ func (s *Segment) realign() {
 	if len(s.words) == 0 {
 		return
 	}
-	if s.Prediction.Label == "table" || s.Prediction.Label == "illustration" {
+	if s.renderer == models.RenderTable || s.renderer == models.RenderImage {
 		return
 	}
 	var x0 float32 = 99999.0
@@ -53,47 +55,95 @@ func (s *Segment) realign() {
 	s.Prediction.Y1 = y1
 }
 
-func extractAndEncodeImage(docId int64, pageNum int, prediction *models.Prediction) (string, error) {
-	imgFile, err := os.Open(fmt.Sprintf("./data/images/%d/%d.jpg", docId, pageNum))
+func extractIllustration(docId int64, pageNum int, index int, prediction *models.Prediction) (string, error) {
+	cropped, err := CropPage(docId, pageNum, prediction.Rect)
 	if err != nil {
-		return "", fmt.Errorf("cannot open image: %v", err)
+		return "", err
+	}
+	return storeAsset(docId, illustrationAssetName(pageNum, index), cropped)
+}
+
+// CropPage returns the given region of a page image encoded as jpeg.
+func CropPage(docId int64, pageNum int, rect models.Rect) ([]byte, error) {
+	imgFile, err := os.Open(PageImagePath(docId, pageNum))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open image: %v", err)
 	}
 	defer imgFile.Close()
 
 	img, err := jpeg.Decode(imgFile)
 	if err != nil {
-		return "", fmt.Errorf("cannot decode image: %v", err)
+		return nil, fmt.Errorf("cannot decode image: %v", err)
 	}
 
-	// Create a cropped image
-	croppedImg := img.(interface {
-		SubImage(r image.Rectangle) image.Image
-	}).SubImage(image.Rect(
-		int(prediction.X0),
-		int(prediction.Y0),
-		int(prediction.X1),
-		int(prediction.Y1),
+	cropped, err := cropImage(img, image.Rect(
+		int(rect.X0),
+		int(rect.Y0),
+		int(rect.X1),
+		int(rect.Y1),
 	))
-
-	// Encode to JPEG in memory
-	buf := new(strings.Builder)
-	b64Encoder := base64.NewEncoder(base64.StdEncoding, buf)
-	err = jpeg.Encode(b64Encoder, croppedImg, nil)
 	if err != nil {
-		return "", fmt.Errorf("cannot encode image: %v", err)
+		return nil, fmt.Errorf("cannot encode image: %v", err)
+	}
+	return cropped, nil
+}
+
+// ParseHtmlAndAdjustDetection assigns words to the predicted segments and renders the page both as html and markdown.
+func ParseHtmlAndAdjustDetection(words *[]models.WordData, predictions *[]models.Prediction, docId int64, pageNum int) (string, string) {
+	segments := assignWords(words, predictions, labelSchema(docId))
+
+	removePageAssets(docId, pageNum)
+
+	var html = ""
+	var md = ""
+	for s, _ := range segments {
+		segment := segments[s]
+		text := strings.TrimSpace(segment.content)
+		switch segment.renderer {
+		case models.RenderTable:
+			table := segment.ParseTable()
+			html += renderTableHtml(table)
+			md += renderTableMarkdown(table) + "\n"
+		case models.RenderParagraph:
+			html += fmt.Sprintf("<p>%s</p>", segment.content)
+			md += text + "\n\n"
+		case models.RenderHeading:
+			html += fmt.Sprintf("<h5>%s</h5>", segment.content)
+			md += fmt.Sprintf("##### %s\n\n", text)
+		case models.RenderImage:
+			if url, err := extractIllustration(docId, pageNum, s, segment.Prediction); err == nil {
+				html += fmt.Sprintf("<img src=\"%s\" alt=\"Illustration\"/>", url)
+				md += fmt.Sprintf("![Illustration](%s)\n\n", url)
+			} else {
+				log.Printf("Failed to extract illustration: %v", err)
+				html += "<pre>Failed to extract illustration</pre>"
+			}
+		default:
+			html += fmt.Sprintf("<span>%s</span>", segment.content)
+			md += text + "\n\n"
+		}
 	}
-	b64Encoder.Close()
+	return html, strings.TrimSpace(md)
+}
 
-	return buf.String(), nil
+// AlignToWords returns a copy of detector output with text boxes shrunk to the words they contain, as they are when
+// a page is rendered, so it can be compared with human annotations.
+func AlignToWords(docId int64, words []models.WordData, predictions []models.Prediction) []models.Prediction {
+	aligned := clonePredictions(predictions)
+	assignWords(&words, &aligned, labelSchema(docId))
+	return aligned
 }
 
-func ParseHtmlAndAdjustDetection(words *[]models.WordData, predictions *[]models.Prediction, docId int64, pageNum int) string {
+// assignWords distributes words among the predictions, shrinks text segments to their words and sorts them in reading order.
+func assignWords(words *[]models.WordData, predictions *[]models.Prediction, schema models.LabelSchema) []Segment {
 	segments := make([]Segment, len(*predictions))
 
 	for i := range *predictions {
 		segments[i] = Segment{
 			content:    "",
 			Prediction: &(*predictions)[i],
+			renderer:   schema.Renderer((*predictions)[i].Label),
+			locale:     schema.Locale,
 			words:      make([]models.WordData, 0),
 		}
 	}
@@ -117,42 +167,7 @@ func ParseHtmlAndAdjustDetection(words *[]models.WordData, predictions *[]models
 	}
 	slices.SortFunc(segments, yCmp)
 
-	// TODO: Extract different renderers
-	var html = ""
-	for s, _ := range segments {
-		segment := segments[s]
-		switch segment.Label {
-		case "table":
-			table := segment.ParseTable()
-			var b strings.Builder
-			b.WriteString("<table>")
-			for _, row := range table {
-				b.WriteString("<tr>")
-				for _, cell := range row {
-					b.WriteString(fmt.Sprintf("<td colspan=\"%d\" rowspan=\"%d\">", cell.Colspan, cell.Rowspan))
-					b.WriteString(cell.content)
-					b.WriteString("</td>")
-				}
-				b.WriteString("</tr>")
-			}
-			b.WriteString("</table>")
-			html += b.String()
-		case "paragraph":
-			html += fmt.Sprintf("<p>%s</p>", segment.content)
-		case "header":
-			html += fmt.Sprintf("<h5>%s</h5>", segment.content)
-		case "illustration":
-			if b64Image, err := extractAndEncodeImage(docId, pageNum, segment.Prediction); err == nil {
-				html += fmt.Sprintf("<img src=\"data:image/jpeg;base64,%s\" alt=\"Illustration\"/>", b64Image)
-			} else {
-				log.Printf("Failed to extract illustration: %v", err)
-				html += "<pre>Failed to extract illustration</pre>"
-			}
-		default:
-			html += fmt.Sprintf("<span>%s</span>", segment.content)
-		}
-	}
-	return html
+	return segments
 }
 
 func lookupBestSegment(word models.WordData, segments *[]Segment) *Segment {
@@ -183,6 +198,16 @@ func Intersection(word models.Rect, s models.Rect) float32 {
 	return overlap / Area(word)
 }
 
+// IoU is the intersection over union of two boxes.
+func IoU(a models.Rect, b models.Rect) float32 {
+	overlap := overlapArea(a, b)
+	union := Area(a) + Area(b) - overlap
+	if union <= 0 {
+		return 0
+	}
+	return overlap / union
+}
+
 func overlapArea(w models.Rect, p models.Rect) float32 {
 	xOverlap := max(0.0, min(w.X1, p.X1)-max(w.X0, p.X0))
 	yOverlap := max(0.0, min(w.Y1, p.Y1)-max(w.Y0, p.Y0))
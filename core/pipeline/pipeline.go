@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	"image/jpeg"
 	"log"
@@ -12,16 +13,18 @@ import (
 	"smart-docs/core/mistral"
 	"smart-docs/core/models"
 	"smart-docs/core/pipeline/markdown"
+	"smart-docs/core/webhooks"
 
 	"github.com/llgcode/draw2d/draw2dimg"
 	"golang.org/x/image/colornames"
 )
 
 func ProcessPdf(docId int64, shouldRunOcr bool, mode string) {
+	defer finishDocument(docId)
 	var pageCount int
 	var words [][]models.WordData
 	var ocrWords [][]models.WordData
-	var markdownPages []string
+	var mistralPages []mistral.Page
 	var err error
 
 	if mode == "mistral" {
@@ -44,13 +47,13 @@ func ProcessPdf(docId int64, shouldRunOcr bool, mode string) {
 			return
 		}
 
-		markdownPages, err = client.ParseFile(fileId)
+		mistralPages, err = client.ParseFile(fileId)
 		if err != nil {
 			log.Printf("Error parsing file with Mistral: \n%+v", err)
 			return
 		}
 
-		pageCount = len(markdownPages)
+		pageCount = len(mistralPages)
 		words = make([][]models.WordData, pageCount)
 		ocrWords = make([][]models.WordData, pageCount)
 	}
@@ -76,6 +79,7 @@ func ProcessPdf(docId int64, shouldRunOcr bool, mode string) {
 	}
 
 	var pages = make([]models.Page, pageCount)
+	var detected = make([][]models.Prediction, pageCount)
 
 	for p := range pages {
 		log.Printf("Annotating page: %d", p)
@@ -109,15 +113,20 @@ func ProcessPdf(docId int64, shouldRunOcr bool, mode string) {
 				log.Printf("Error detecting segments: \n%+v", err)
 				return
 			}
+			detected[p] = clonePredictions(predictions)
 			if shouldRunOcr {
-				page.Html = ParseHtmlAndAdjustDetection(&ocrWords[p], &predictions, docId, p)
+				page.Html, page.Md = ParseHtmlAndAdjustDetection(&ocrWords[p], &predictions, docId, p)
 			} else {
-				page.Html = ParseHtmlAndAdjustDetection(&words[p], &predictions, docId, p)
+				page.Html, page.Md = ParseHtmlAndAdjustDetection(&words[p], &predictions, docId, p)
 			}
 			DrawBoundingBoxes(docId, p, &predictions, "prediction")
 		} else if mode == "mistral" {
 			predictions = []models.Prediction{}
-			page.Md = markdownPages[p]
+			page.Md, err = storeMistralImages(docId, p, mistralPages[p])
+			if err != nil {
+				log.Printf("Error storing images: \n%+v", err)
+				return
+			}
 			html, err := markdown.ConvertMarkdownToHTML(page.Md)
 			if err != nil {
 				log.Printf("Error converting to html: \n%+v", err)
@@ -134,6 +143,13 @@ func ProcessPdf(docId int64, shouldRunOcr bool, mode string) {
 		return
 	}
 
+	for p, predictions := range detected {
+		if predictions != nil {
+			storeModelAnnotation(docId, p, predictions)
+			updateFields(docId, p)
+		}
+	}
+
 	err = db.UpdateDocumentStatus(docId, "DONE")
 	if err != nil {
 		log.Printf("Error while calling core.db: \n%+v", err)
@@ -155,35 +171,18 @@ func DrawBoundingBoxes(docId int64, page int, predictions *[]models.Prediction,
 	draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
 	gc := draw2dimg.NewGraphicContext(rgba)
 	gc.SetLineWidth(1)
+	schema := labelSchema(docId)
 	for _, prediction := range *predictions {
-		switch prediction.Label {
-		case "table":
-			gc.SetStrokeColor(colornames.Gray)
-		case "paragraph":
-			gc.SetStrokeColor(colornames.Blue)
-		case "header":
-			gc.SetStrokeColor(colornames.Red)
-		case "illustration":
-			gc.SetStrokeColor(colornames.Yellow)
-		default:
-			gc.SetStrokeColor(colornames.Green)
-		}
+		gc.SetStrokeColor(parseColor(schema.Color(prediction.Label)))
 		drawBox(gc, prediction.X0, prediction.Y0, prediction.X1, prediction.Y1)
-		if prediction.Label == "table" {
-			for _, cellPrediction := range prediction.Table {
-				switch cellPrediction.Label {
-				case "cell":
-					gc.SetStrokeColor(colornames.Blue)
-				case "header":
-					gc.SetStrokeColor(colornames.Red)
-				}
-				drawBox(gc,
-					cellPrediction.X0+prediction.X0,
-					cellPrediction.Y0+prediction.Y0,
-					cellPrediction.X1+prediction.X0,
-					cellPrediction.Y1+prediction.Y0,
-				)
-			}
+		for _, cellPrediction := range prediction.Table {
+			gc.SetStrokeColor(parseColor(schema.Color(cellPrediction.Label)))
+			drawBox(gc,
+				cellPrediction.X0+prediction.X0,
+				cellPrediction.Y0+prediction.Y0,
+				cellPrediction.X1+prediction.X0,
+				cellPrediction.Y1+prediction.Y0,
+			)
 		}
 	}
 	outFile, err := os.Create(fmt.Sprintf("./data/images/%d/%d.%s.jpg", docId, page, suffix))
@@ -195,6 +194,25 @@ func DrawBoundingBoxes(docId int64, page int, predictions *[]models.Prediction,
 	jpeg.Encode(outFile, rgba, nil)
 }
 
+// parseColor reads a "#rrggbb" colour of the label schema.
+func parseColor(hex string) color.Color {
+	var r, g, b uint8
+	_, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	if err != nil {
+		return colornames.Green
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
+
+// labelSchema returns the labels of the project of a document, an empty schema renders every box as plain text.
+func labelSchema(docId int64) models.LabelSchema {
+	schema, err := db.DocumentLabelSchema(docId)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error loading label schema: \n%+v", err))
+	}
+	return schema
+}
+
 func drawBox(gc *draw2dimg.GraphicContext, x0 float32, y0 float32, x1 float32, y1 float32) {
 	gc.BeginPath()
 	gc.MoveTo(float64(x0), float64(y0))
@@ -205,20 +223,108 @@ func drawBox(gc *draw2dimg.GraphicContext, x0 float32, y0 float32, x1 float32, y
 	gc.Stroke()
 }
 
-func RetryAnnotations(docId int64) {
+// ApplyPredictions stores annotations of a page made by the author and re-renders its html, markdown and overlay image from them.
+// The detector output kept for the page is left untouched.
+func ApplyPredictions(docId int64, pageNum int, predictions *[]models.Prediction, author string) error {
+	words, err := db.GetPdfPageText(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	html, md := ParseHtmlAndAdjustDetection(&words, predictions, docId, pageNum)
+	err = db.UpdatePredictionsAndText(docId, pageNum, predictions, &html, &md)
+	DrawBoundingBoxes(docId, pageNum, predictions, "prediction")
+	if err != nil {
+		return err
+	}
+	err = db.StoreAnnotation(docId, pageNum, models.SourceHuman, author, *predictions)
+	if err != nil {
+		return err
+	}
+	updateFields(docId, pageNum)
+	webhooks.Publish(webhooks.EventAnnotationChanged, webhooks.PageEvent{DocumentId: docId, PageNum: pageNum, User: author})
+	return nil
+}
+
+// Rerender renders the html, markdown and overlay image of a page again from its current words and annotations,
+// after the words were corrected by the author.
+func Rerender(docId int64, pageNum int, author string) error {
+	predictions, err := LoadPredictions(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	words, err := db.GetPdfPageText(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	html, md := ParseHtmlAndAdjustDetection(&words, &predictions, docId, pageNum)
+	err = db.UpdatePredictionsAndText(docId, pageNum, &predictions, &html, &md)
+	if err != nil {
+		return err
+	}
+	DrawBoundingBoxes(docId, pageNum, &predictions, "prediction")
+	updateFields(docId, pageNum)
+	webhooks.Publish(webhooks.EventAnnotationChanged, webhooks.PageEvent{DocumentId: docId, PageNum: pageNum, User: author})
+	return nil
+}
+
+// LoadPredictions returns the current annotations of a page.
+func LoadPredictions(docId int64, pageNum int) ([]models.Prediction, error) {
+	serialisedPredictions, err := db.GetPredictions(docId, pageNum)
+	if err != nil {
+		return nil, err
+	}
+	var predictions []models.Prediction
+	if serialisedPredictions != "" {
+		err = json.Unmarshal([]byte(serialisedPredictions), &predictions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return predictions, nil
+}
+
+// storeModelAnnotation keeps the raw detector output of a page, before it is adjusted to the words on the page.
+func storeModelAnnotation(docId int64, pageNum int, predictions []models.Prediction) {
+	err := db.StoreAnnotation(docId, pageNum, models.SourceModel, docPredictorUrl, predictions)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error storing detector output: \n%+v", err))
+	}
+}
+
+// finishDocument marks a document still processing as failed, its processing stopped on an error, and notifies
+// webhooks whether the document was processed.
+func finishDocument(docId int64) {
+	status, event := "", webhooks.EventDocumentProcessed
+	doc, err := db.LoadDocument(docId)
+	if err == nil {
+		status = doc.Status
+	}
+	if status != "DONE" {
+		event = webhooks.EventDocumentFailed
+		err = db.UpdateDocumentStatus(docId, "FAILED")
+		if err != nil {
+			log.Println(fmt.Sprintf("Failed to update document status: \n%+v", err))
+		}
+	}
+	webhooks.PublishDocument(event, docId)
+}
+
+// RetryAnnotations runs the detection again on the pages of a document which are not validated yet, in the background.
+func RetryAnnotations(docId int64) error {
 	pagesToProcess, err := db.GetNonValidatedPages(docId)
 	if err != nil {
-		return
+		return err
 	}
 	err = db.UpdateDocumentStatus(docId, "PROCESSING")
 	if err != nil {
-		log.Println(fmt.Sprintf("Failed to update document status: \n%+v", err))
-		return
+		return err
 	}
 	go reprocessPages(docId, pagesToProcess)
+	return nil
 }
 
 func reprocessPages(docId int64, pages []int) {
+	defer finishDocument(docId)
 	for _, p := range pages {
 		predictions, err := RunDetectionOnPage(docId, p)
 		DrawBoundingBoxes(docId, p, &predictions, "original")
@@ -226,18 +332,22 @@ func reprocessPages(docId int64, pages []int) {
 			log.Println(fmt.Sprintf("Error detecting segments: \n%+v", err))
 			return
 		}
+		detected := clonePredictions(predictions)
 		words, err := db.GetPdfPageText(docId, p)
 		if err != nil {
 			log.Println(fmt.Sprintf("Could not fetch pdf text: \n%+v", err))
 			return
 		}
-		html := ParseHtmlAndAdjustDetection(&words, &predictions, docId, p)
+		html, md := ParseHtmlAndAdjustDetection(&words, &predictions, docId, p)
 		DrawBoundingBoxes(docId, p, &predictions, "prediction")
-		err = db.UpdatePredictionsAndText(docId, p, &predictions, &html)
+		err = db.UpdatePredictionsAndText(docId, p, &predictions, &html, &md)
 		if err != nil {
 			log.Println(fmt.Sprintf("Error updating document predictions and text: \n%+v", err))
 			return
 		}
+		storeModelAnnotation(docId, p, detected)
+		updateFields(docId, p)
+		webhooks.Publish(webhooks.EventAnnotationChanged, webhooks.PageEvent{DocumentId: docId, PageNum: p, User: models.SourceModel})
 	}
 
 	err := db.UpdateDocumentStatus(docId, "DONE")
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+func renderTableHtml(table [][]Cell) string {
+	var b strings.Builder
+	b.WriteString("<table>")
+	for _, row := range table {
+		b.WriteString("<tr>")
+		for _, cell := range row {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+			b.WriteString(fmt.Sprintf("<%s colspan=\"%d\" rowspan=\"%d\">", tag, cell.Colspan, cell.Rowspan))
+			b.WriteString(cell.content)
+			b.WriteString(fmt.Sprintf("</%s>", tag))
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// renderTableMarkdown renders a GitHub flavoured table, using the first row as header.
+// GitHub tables cannot express spans, so tables with merged cells are embedded as html instead.
+func renderTableMarkdown(table [][]Cell) string {
+	columns := 0
+	for _, row := range table {
+		for _, cell := range row {
+			if cell.Colspan > 1 || cell.Rowspan > 1 {
+				return renderTableHtml(table)
+			}
+		}
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for r, row := range table {
+		b.WriteString("|")
+		for c := range columns {
+			content := ""
+			if c < len(row) {
+				content = markdownCell(row[c].content)
+			}
+			b.WriteString(fmt.Sprintf(" %s |", content))
+		}
+		b.WriteString("\n")
+		if r == 0 {
+			b.WriteString("|")
+			b.WriteString(strings.Repeat(" --- |", columns))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func markdownCell(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.ReplaceAll(content, "|", "\\|")
+	return strings.ReplaceAll(content, "\n", " ")
+}
@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"slices"
 	"smart-docs/core/models"
+	"smart-docs/core/values"
 )
 
 type Cell struct {
@@ -11,16 +12,87 @@ type Cell struct {
 	content     string
 	words       []models.WordData
 	OffsetStart float32
+	Col         int
 	Colspan     int
 	Rowspan     int
+	Header      bool
+	Value       *models.CellValue
 }
 
 const maxOverlap = 0.8
 
+// ParseTable lays the words of a table out on its grid, one slice of cells per grid row in column order.
+// The grid is derived from the cell boxes and kept on the prediction, unless the stored one still matches them.
 func (s *Segment) ParseTable() [][]Cell {
+	grid, boxes := ResolveGrid(*s.Prediction)
+	s.Prediction.Grid = &grid
+	s.Prediction.Table = boxes
+
+	cells := make([]Cell, len(grid.Cells))
+	for i, gridCell := range grid.Cells {
+		cells[i] = Cell{
+			Prediction: &s.Prediction.Table[i],
+			Col:        gridCell.Col,
+			Colspan:    gridCell.Colspan,
+			Rowspan:    gridCell.Rowspan,
+			Header:     gridCell.Row < grid.HeaderRows,
+		}
+	}
+	for _, word := range s.words {
+		cell := lookupBestCell(word, &cells, s.X0, s.Y0)
+		if cell != nil {
+			cell.content = cell.content + " " + word.Text
+			cell.words = append(cell.words, word)
+		}
+	}
+
+	table := make([][]Cell, len(grid.Rows))
+	for i, gridCell := range grid.Cells {
+		if gridCell.Text != nil {
+			cells[i].content = " " + *gridCell.Text
+		}
+		grid.Cells[i].Value = values.Parse(cells[i].content, s.locale)
+		cells[i].Value = grid.Cells[i].Value
+		table[gridCell.Row] = append(table[gridCell.Row], cells[i])
+	}
+	for _, row := range table {
+		slices.SortFunc(row, func(a, b Cell) int {
+			return cmp.Compare(a.Col, b.Col)
+		})
+	}
+	return table
+}
+
+// ResolveGrid returns the grid of a table with the cell boxes matching it: the stored grid when the boxes were not
+// redrawn since it was made, otherwise a grid derived from the boxes.
+func ResolveGrid(table models.Prediction) (models.TableGrid, []models.Prediction) {
+	if table.Grid != nil && table.Grid.Matches(table.Table) {
+		grid := models.TableGrid{
+			Rows:       slices.Clone(table.Grid.Rows),
+			Columns:    slices.Clone(table.Grid.Columns),
+			HeaderRows: table.Grid.HeaderRows,
+			Cells:      slices.Clone(table.Grid.Cells),
+		}
+		// Labels may be changed without redrawing cells
+		for i := range grid.Cells {
+			grid.Cells[i].Label = table.Table[i].Label
+		}
+		return grid, clonePredictions(table.Table)
+	}
+	grid, boxes := deriveGrid(clonePredictions(table.Table))
+	if table.Grid != nil {
+		grid.HeaderRows = min(table.Grid.HeaderRows, len(grid.Rows))
+	}
+	return grid, boxes
+}
+
+// deriveGrid snaps cell boxes to common row and column lines and places them on the grid those lines form.
+// Cells overlapping others are dropped, the returned boxes are the ones of the grid cells.
+func deriveGrid(boxes []models.Prediction) (models.TableGrid, []models.Prediction) {
+	grid := models.TableGrid{Rows: []models.Band{}, Columns: []models.Band{}, Cells: []models.GridCell{}}
 
 	var cells []Cell
-	for _, p := range s.Prediction.Table {
+	for _, p := range boxes {
 		if p.X0 > p.X1 || p.Y0 > p.Y1 || p.X0 < 0 || p.Y0 < 0 {
 			// Skip invalid cells
 			continue
@@ -29,6 +101,9 @@ func (s *Segment) ParseTable() [][]Cell {
 			Prediction: &p,
 		})
 	}
+	if len(cells) == 0 {
+		return grid, []models.Prediction{}
+	}
 
 	var overlappingCells []int
 	for index := range cells {
@@ -73,15 +148,6 @@ func (s *Segment) ParseTable() [][]Cell {
 		}
 	}
 
-	// Assign content
-	for _, word := range s.words {
-		segment := lookupBestCell(word, &cells, s.X0, s.Y0)
-		if segment != nil {
-			segment.content = segment.content + " " + word.Text
-			segment.words = append(segment.words, word)
-		}
-	}
-
 	yCmp := func(a, b Cell) int {
 		return cmp.Compare(a.Y0, b.Y0)
 	}
@@ -214,19 +280,85 @@ func (s *Segment) ParseTable() [][]Cell {
 	}
 	// END: HANDLE ROW SPANS
 
-	s.Prediction.Table = []models.Prediction{}
-	//s.Prediction.Table = make([]models.Prediction, len(cells))
+	var kept []models.Prediction
 	for r, _ := range table {
 		row := table[r]
 		for c, _ := range row {
 			cell := row[c]
 			if isValidCell(cell, r, c, table) {
-				s.Prediction.Table = append(s.Prediction.Table, *cell.Prediction)
+				kept = append(kept, *cell.Prediction)
 			}
 		}
 	}
+	return placeOnGrid(kept)
+}
 
-	return table
+// placeOnGrid turns the distinct edges of snapped cells into row and column bands and places the cells on them.
+func placeOnGrid(cells []models.Prediction) (models.TableGrid, []models.Prediction) {
+	var xs, ys []float32
+	for _, cell := range cells {
+		xs = append(xs, cell.X0, cell.X1)
+		ys = append(ys, cell.Y0, cell.Y1)
+	}
+	grid := models.TableGrid{Rows: bands(ys), Columns: bands(xs), Cells: []models.GridCell{}}
+	xs, ys = edges(grid.Columns), edges(grid.Rows)
+
+	placed := []models.Prediction{}
+	occupied := map[[2]int]bool{}
+	for _, cell := range cells {
+		col := min(slices.Index(xs, cell.X0), len(grid.Columns)-1)
+		row := min(slices.Index(ys, cell.Y0), len(grid.Rows)-1)
+		if col < 0 || row < 0 {
+			continue
+		}
+		gridCell := models.GridCell{
+			Row:     row,
+			Col:     col,
+			Rowspan: max(slices.Index(ys, cell.Y1)-row, 1),
+			Colspan: max(slices.Index(xs, cell.X1)-col, 1),
+			Label:   cell.Label,
+		}
+		free := true
+		for r := row; r < row+gridCell.Rowspan; r++ {
+			for c := col; c < col+gridCell.Colspan; c++ {
+				free = free && !occupied[[2]int{r, c}]
+			}
+		}
+		if !free {
+			continue
+		}
+		for r := row; r < row+gridCell.Rowspan; r++ {
+			for c := col; c < col+gridCell.Colspan; c++ {
+				occupied[[2]int{r, c}] = true
+			}
+		}
+		cell.Rect = grid.Rect(gridCell)
+		grid.Cells = append(grid.Cells, gridCell)
+		placed = append(placed, cell)
+	}
+	return grid, placed
+}
+
+// bands returns the bands between consecutive distinct values.
+func bands(values []float32) []models.Band {
+	slices.Sort(values)
+	values = slices.Compact(values)
+	result := []models.Band{}
+	for i := 1; i < len(values); i++ {
+		result = append(result, models.Band{Start: values[i-1], End: values[i]})
+	}
+	return result
+}
+
+func edges(bands []models.Band) []float32 {
+	if len(bands) == 0 {
+		return nil
+	}
+	result := []float32{bands[0].Start}
+	for _, band := range bands {
+		result = append(result, band.End)
+	}
+	return result
 }
 
 func isValidCell(cell Cell, testedRow int, testedCol int, table [][]Cell) bool {
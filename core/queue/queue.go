@@ -0,0 +1,268 @@
+package queue
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PriorityAge puts pages of the oldest documents first.
+	PriorityAge = "age"
+	// PriorityConfidence puts pages the detector was least sure about first.
+	PriorityConfidence = "confidence"
+	// PriorityRarity puts pages with the least annotated labels first.
+	PriorityRarity = "rarity"
+)
+
+// ErrAnonymous is returned to users without a name when others sign in with personal keys, they would all share the
+// same pages.
+var ErrAnonymous = errors.New("annotators have to sign in with their personal key")
+
+var (
+	// Comma separated priorities, later ones break ties of earlier ones, e.g. "rarity,confidence,age"
+	priorities    = parsePriorities(util.Getenv("ANNOTATION_PRIORITY", PriorityAge))
+	leaseDuration = time.Duration(leaseMinutes()) * time.Minute
+)
+
+func parsePriorities(value string) []string {
+	var result []string
+	for _, priority := range strings.Split(value, ",") {
+		priority = strings.TrimSpace(priority)
+		switch priority {
+		case PriorityAge, PriorityConfidence, PriorityRarity:
+			result = append(result, priority)
+		case "":
+		default:
+			log.Println(fmt.Sprintf("Unknown annotation priority %q ignored", priority))
+		}
+	}
+	if !slices.Contains(result, PriorityAge) {
+		result = append(result, PriorityAge)
+	}
+	return result
+}
+
+// Leased tells whether pages are leased to the user and annotated as their own copy on agreement pages. Leases are
+// only taken when users sign in with personal keys: without USERS everybody is anonymous and shares the pages, with
+// USERS anonymous users are refused.
+func Leased(user string) (bool, error) {
+	switch {
+	case user != models.AnonymousUser:
+		return true, nil
+	case auth.HasUsers():
+		return false, ErrAnonymous
+	default:
+		return false, nil
+	}
+}
+
+func leaseMinutes() int {
+	minutes, err := strconv.Atoi(util.Getenv("ANNOTATION_LEASE_MINUTES", "30"))
+	if err != nil || minutes <= 0 {
+		return 30
+	}
+	return minutes
+}
+
+// Pending lists pages waiting for annotation in the order they are handed out.
+func Pending() ([]models.QueueItem, error) {
+	items, err := db.QueueCandidates()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := db.LabelCounts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Confidence = meanScore(items[i].Predictions)
+		items[i].Rarity = rarity(items[i].Predictions, counts)
+	}
+	slices.SortStableFunc(items, compareItems)
+	return items, nil
+}
+
+// Claim leases the next page to the user. Pages the user already holds come first, then pages assigned to them,
+// then unassigned pages by priority. Pages leased to others or assigned to others are skipped.
+// The skipped page, if any, is released and not handed out again by this call. Without leases the next free page is
+// returned as is.
+func Claim(user string, skipPageId int64) (models.QueueItem, error) {
+	leased, err := Leased(user)
+	if err != nil {
+		return models.QueueItem{}, err
+	}
+	items, err := Pending()
+	if err != nil {
+		return models.QueueItem{}, err
+	}
+	slices.SortStableFunc(items, func(a, b models.QueueItem) int {
+		return cmp.Compare(ownership(a, user), ownership(b, user))
+	})
+	for _, item := range items {
+		if item.PageId == skipPageId {
+			if item.LeasedBy == user {
+				err = db.ReleasePage(item.DocumentId, item.PageNum, user)
+				if err != nil {
+					return item, err
+				}
+			}
+			continue
+		}
+		if ownership(item, user) > 2 {
+			continue
+		}
+		if !leased {
+			return item, nil
+		}
+		if item.RequiredAnnotators > 0 {
+			return item, db.ClaimAnnotationSet(item.PageId, user)
+		}
+		claimed, err := db.ClaimPage(item.PageId, user, time.Now().Add(leaseDuration))
+		if err != nil {
+			return item, err
+		}
+		if claimed {
+			return item, nil
+		}
+	}
+	return models.QueueItem{}, sql.ErrNoRows
+}
+
+// Lock leases a page opened directly to the user, or reports who is annotating it.
+// Pages selected for agreement are annotated by several users at once and never locked, neither are pages without leases.
+func Lock(pageId int64, docId int64, pageNum int, user string) (models.Lease, bool, error) {
+	leased, err := Leased(user)
+	if err != nil || !leased {
+		return models.Lease{}, err == nil, err
+	}
+	required, err := db.RequiredAnnotators(docId, pageNum)
+	if err != nil || required > 0 {
+		return models.Lease{}, true, err
+	}
+	claimed, err := db.ClaimPage(pageId, user, time.Now().Add(leaseDuration))
+	if err != nil || claimed {
+		return models.Lease{}, claimed, err
+	}
+	lease, err := db.GetLease(docId, pageNum)
+	if err == sql.ErrNoRows {
+		return lease, true, nil
+	}
+	return lease, false, err
+}
+
+// CheckLease fails when another annotator holds the page, or when an anonymous user edits a page of the queue while
+// others sign in with personal keys.
+func CheckLease(docId int64, pageNum int, user string) error {
+	if user == models.AnonymousUser && auth.HasUsers() {
+		status, err := db.GetPageStatus(docId, pageNum)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if status == "TRAINING" {
+			return ErrAnonymous
+		}
+	}
+	lease, err := db.GetLease(docId, pageNum)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.Holder != user {
+		return fmt.Errorf("page is being annotated by %s until %s", lease.Holder, lease.ExpiresAt.Local().Format("15:04"))
+	}
+	return nil
+}
+
+// SaveAnnotationSet stores the annotations of a page selected for agreement as the copy of the user. Anonymous users
+// are refused, their copies could not be told apart.
+func SaveAnnotationSet(docId int64, pageNum int, user string, predictions []models.Prediction) error {
+	leased, err := Leased(user)
+	if err != nil {
+		return err
+	}
+	if !leased {
+		return ErrAnonymous
+	}
+	return db.SaveAnnotationSet(docId, pageNum, user, predictions)
+}
+
+// ownership ranks how a page relates to the user: held by them, assigned to them, free, taken by someone else.
+func ownership(item models.QueueItem, user string) int {
+	if item.RequiredAnnotators > 0 {
+		switch {
+		case slices.Contains(item.Finished, user):
+			return 3
+		case slices.Contains(item.Annotators, user):
+			return 0
+		case len(item.Annotators) >= item.RequiredAnnotators:
+			return 3
+		default:
+			return 2
+		}
+	}
+	switch {
+	case item.LeasedBy == user:
+		return 0
+	case item.LeasedBy != "":
+		return 3
+	case item.Assignee == user:
+		return 1
+	case item.Assignee != "":
+		return 3
+	default:
+		return 2
+	}
+}
+
+func compareItems(a models.QueueItem, b models.QueueItem) int {
+	for _, priority := range priorities {
+		var c int
+		switch priority {
+		case PriorityAge:
+			c = a.UploadDate.Compare(b.UploadDate)
+		case PriorityConfidence:
+			c = cmp.Compare(a.Confidence, b.Confidence)
+		case PriorityRarity:
+			c = cmp.Compare(a.Rarity, b.Rarity)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.PageNum, b.PageNum)
+}
+
+// meanScore is the average detector score of the page boxes, pages without boxes come last.
+func meanScore(predictions []models.Prediction) float32 {
+	if len(predictions) == 0 {
+		return 1
+	}
+	var total float32
+	for _, prediction := range predictions {
+		total += prediction.Score
+	}
+	return total / float32(len(predictions))
+}
+
+// rarity is how often the rarest label of the page is annotated, pages without boxes come last.
+func rarity(predictions []models.Prediction, counts map[string]int) int {
+	result := math.MaxInt
+	for _, prediction := range predictions {
+		result = min(result, counts[prediction.Label])
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestLeased(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		users  string
+		user   string
+		want   bool
+		err    error
+	}{
+		{"unsecured", "", "", models.AnonymousUser, false, nil},
+		{"shared key", "shared", "", models.AnonymousUser, false, nil},
+		{"anonymous with personal keys", "shared", "ana:a1", models.AnonymousUser, false, ErrAnonymous},
+		{"signed in", "", "ana:a1", "ana", true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("API_KEY", test.apiKey)
+			t.Setenv("USERS", test.users)
+			got, err := Leased(test.user)
+			if got != test.want || !errors.Is(err, test.err) {
+				t.Errorf("Leased(%q) = %v, %v, want %v, %v", test.user, got, err, test.want, test.err)
+			}
+		})
+	}
+}
+
+func TestAnonymousUsersShareUnleasedPages(t *testing.T) {
+	for _, apiKey := range []string{"", "shared"} {
+		t.Setenv("API_KEY", apiKey)
+		t.Setenv("USERS", "")
+		lease, ok, err := Lock(1, 1, 0, models.AnonymousUser)
+		if err != nil || !ok || lease.Holder != "" {
+			t.Errorf("Lock() with API_KEY %q = %+v, %v, %v, want no lease", apiKey, lease, ok, err)
+		}
+	}
+}
+
+func TestAnonymousUsersCannotHoldPages(t *testing.T) {
+	t.Setenv("USERS", "ana:a1")
+	if _, err := Claim(models.AnonymousUser, 0); !errors.Is(err, ErrAnonymous) {
+		t.Errorf("Claim() error = %v, want %v", err, ErrAnonymous)
+	}
+	if _, _, err := Lock(1, 1, 0, models.AnonymousUser); !errors.Is(err, ErrAnonymous) {
+		t.Errorf("Lock() error = %v, want %v", err, ErrAnonymous)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	tests := []struct {
+		name string
+		item models.QueueItem
+		want int
+	}{
+		{"held by the user", models.QueueItem{LeasedBy: "ana"}, 0},
+		{"assigned to the user", models.QueueItem{Assignee: "ana"}, 1},
+		{"free", models.QueueItem{}, 2},
+		{"held by someone else", models.QueueItem{LeasedBy: "bob", Assignee: "ana"}, 3},
+		{"assigned to someone else", models.QueueItem{Assignee: "bob"}, 3},
+		{"agreement page taken by the user", models.QueueItem{RequiredAnnotators: 2, Annotators: []string{"ana"}}, 0},
+		{"agreement page with a free copy", models.QueueItem{RequiredAnnotators: 2, Annotators: []string{"bob"}}, 2},
+		{"agreement page with every copy taken", models.QueueItem{RequiredAnnotators: 2, Annotators: []string{"bob", "eve"}}, 3},
+		{"agreement page finished by the user", models.QueueItem{RequiredAnnotators: 2, Annotators: []string{"ana"}, Finished: []string{"ana"}}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ownership(test.item, "ana"); got != test.want {
+				t.Errorf("ownership() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePriorities(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{PriorityAge}},
+		{"age", []string{PriorityAge}},
+		{"rarity, confidence", []string{PriorityRarity, PriorityConfidence, PriorityAge}},
+		{"confidence,unknown,age,rarity", []string{PriorityConfidence, PriorityAge, PriorityRarity}},
+	}
+	for _, test := range tests {
+		if got := parsePriorities(test.value); !slices.Equal(got, test.want) {
+			t.Errorf("parsePriorities(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
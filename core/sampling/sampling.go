@@ -0,0 +1,199 @@
+package sampling
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"smart-docs/core/agreement"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/util"
+	"smart-docs/core/workflow"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The user recorded as moving sampled pages to the training queue.
+const samplerUser = "active-learning"
+
+// Policy decides which predicted pages are worth annotating. Pages are sampled when any of the enabled criteria holds.
+type Policy struct {
+	// Pages moved to the training queue per day, sampling is disabled when 0
+	DailyBudget int
+	// Pages whose mean box score is below this are sampled
+	MinConfidence float32
+	// Pages with a label annotated fewer times than this on training and validation pages are sampled, 0 disables it
+	RareLabelCount int
+	// A second detector run on each page, pages where it agrees less than MinAgreement with the first one are sampled
+	SecondDetectorUrl string
+	MinAgreement      float64
+}
+
+func PolicyFromEnv() Policy {
+	return Policy{
+		DailyBudget:       envInt("SAMPLING_DAILY_BUDGET", 0),
+		MinConfidence:     float32(envFloat("SAMPLING_MIN_CONFIDENCE", 0.6)),
+		RareLabelCount:    envInt("SAMPLING_RARE_LABEL_COUNT", 20),
+		SecondDetectorUrl: util.Getenv("SAMPLING_SECOND_DETECTOR_URL", ""),
+		MinAgreement:      envFloat("SAMPLING_MIN_AGREEMENT", 0.7),
+	}
+}
+
+var sweepLock sync.Mutex
+
+// Sweep evaluates pages that arrived in PREDICTION since the last sweep and moves the most informative ones
+// to TRAINING until the daily budget is used up. Pages over the budget are picked up by later sweeps.
+func Sweep(policy Policy) {
+	if policy.DailyBudget <= 0 {
+		return
+	}
+	sweepLock.Lock()
+	defer sweepLock.Unlock()
+
+	err := evaluate(policy)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error evaluating pages for sampling: \n%+v", err))
+		return
+	}
+	err = selectPages(policy)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error sampling pages: \n%+v", err))
+	}
+}
+
+// Run sweeps now and then every interval.
+func Run(policy Policy, interval time.Duration) {
+	if policy.DailyBudget <= 0 {
+		return
+	}
+	for {
+		Sweep(policy)
+		time.Sleep(interval)
+	}
+}
+
+func evaluate(policy Policy) error {
+	candidates, err := db.UnsampledPages()
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+	counts, err := db.LabelCounts()
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		reasons, confidence := policy.reasons(candidate, counts)
+		err = db.StoreSamplingResult(candidate.PageId, strings.Join(reasons, "; "), confidence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func selectPages(policy Policy) error {
+	now := time.Now()
+	selected, err := db.CountSelectedSince(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return err
+	}
+	budget := policy.DailyBudget - selected
+	if budget <= 0 {
+		return nil
+	}
+	pages, err := db.SampledPages()
+	if err != nil {
+		return err
+	}
+	for _, page := range pages[:min(budget, len(pages))] {
+		err = workflow.Change(page.DocumentId, page.PageNum, "TRAINING", samplerUser, false, page.Reasons)
+		if errors.Is(err, workflow.ErrNotAllowed) || errors.Is(err, workflow.ErrStatusChanged) || errors.Is(err, sql.ErrNoRows) {
+			// Moved by hand or deleted since it was evaluated
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = db.MarkSampled(page.PageId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reasons lists why a page is worth annotating, empty when it is not, together with its mean box score.
+func (p Policy) reasons(candidate models.SamplingCandidate, labelCounts map[string]int) ([]string, float32) {
+	var reasons []string
+	confidence := float32(1)
+	if len(candidate.Predictions) > 0 {
+		var total float32
+		for _, prediction := range candidate.Predictions {
+			total += prediction.Score
+		}
+		confidence = total / float32(len(candidate.Predictions))
+		if confidence < p.MinConfidence {
+			reasons = append(reasons, fmt.Sprintf("low confidence %.2f", confidence))
+		}
+	}
+
+	if p.RareLabelCount > 0 {
+		var rare []string
+		for _, prediction := range candidate.Predictions {
+			if labelCounts[prediction.Label] < p.RareLabelCount && !slices.Contains(rare, prediction.Label) {
+				rare = append(rare, prediction.Label)
+			}
+		}
+		if len(rare) > 0 {
+			slices.Sort(rare)
+			reasons = append(reasons, "rare labels "+strings.Join(rare, ", "))
+		}
+	}
+
+	if p.SecondDetectorUrl != "" {
+		_, tableUrl := pipeline.DetectorUrls()
+		second, err := pipeline.RunDetectionWith(candidate.DocumentId, candidate.PageNum, p.SecondDetectorUrl, tableUrl)
+		if err != nil {
+			log.Println(fmt.Sprintf("Error running second detector: \n%+v", err))
+		} else {
+			// Boxes matched with the same label by both detectors
+			result := agreement.Compare(detectorOutput(candidate), second)
+			if score := result.Boxes * result.Labels; score < p.MinAgreement {
+				reasons = append(reasons, fmt.Sprintf("detectors agree %.0f%%", score*100))
+			}
+		}
+	}
+	return reasons, confidence
+}
+
+// detectorOutput prefers the raw output kept for the page over predictions adjusted to its words.
+func detectorOutput(candidate models.SamplingCandidate) []models.Prediction {
+	annotations, err := db.ListAnnotations(candidate.DocumentId, candidate.PageNum, models.SourceModel)
+	if err != nil || len(annotations) == 0 {
+		return candidate.Predictions
+	}
+	return annotations[0].Predictions
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(util.Getenv(key, strconv.Itoa(fallback)))
+	if err != nil {
+		log.Println(fmt.Sprintf("Invalid %s, using %d", key, fallback))
+		return fallback
+	}
+	return value
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(util.Getenv(key, strconv.FormatFloat(fallback, 'f', -1, 64)), 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("Invalid %s, using %v", key, fallback))
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-docs/core/agreement"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/workflow"
+	"strconv"
+	"strings"
+)
+
+var annotatorColors = []string{"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#42d4f4", "#f032e6", "#9a6324"}
+
+type adjudicationVariant struct {
+	Index      int
+	Annotator  string
+	Color      string
+	Prediction models.Prediction
+	Checked    bool
+}
+
+type adjudicationCluster struct {
+	Index    int
+	Variants []adjudicationVariant
+	Drop     bool
+}
+
+// SelectForAgreement asks for a page to be annotated independently by the "annotators" form value, 2 by default.
+func (s *Server) SelectForAgreement(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	if !auth.IsReviewer(auth.CurrentUser(r)) {
+		http.Error(w, "Only reviewers can select pages for agreement", http.StatusForbidden)
+		return
+	}
+	required := 2
+	if value := r.FormValue("annotators"); value != "" {
+		var err error
+		required, err = strconv.Atoi(value)
+		if err != nil || required < 2 {
+			http.Error(w, "At least 2 annotators are required", http.StatusBadRequest)
+			return
+		}
+	}
+	err := db.SelectForAgreement(docId, pageNum, required)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Annotators work on their own copies, nobody holds the whole page anymore
+	err = db.ReleasePage(docId, pageNum, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) UnselectForAgreement(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	if !auth.IsReviewer(auth.CurrentUser(r)) {
+		http.Error(w, "Only reviewers can select pages for agreement", http.StatusForbidden)
+		return
+	}
+	err := db.UnselectForAgreement(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListAnnotationSets(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	sets, err := db.ListAnnotationSets(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, sets)
+}
+
+func (s *Server) ShowAgreement(w http.ResponseWriter, r *http.Request) {
+	pages, err := db.AgreementPages()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report := agreement.Report(pages)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJson(w, http.StatusOK, report)
+		return
+	}
+	err = tmpl.ExecuteTemplate(w, "agreement.go.html", report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// AdjudicatePage shows the annotation sets of a page on top of each other, grouped by object, for a reviewer to pick from.
+func (s *Server) AdjudicatePage(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	var page models.PageView
+	err := db.LoadPage(docId, pageNum, &page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sets, err := db.ListAnnotationSets(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	colors := map[string]string{}
+	for i, set := range sets {
+		colors[set.Annotator] = annotatorColors[i%len(annotatorColors)]
+	}
+	var clusters []adjudicationCluster
+	for c, cluster := range agreement.Clusters(sets) {
+		view := adjudicationCluster{Index: c, Drop: cluster.Choice < 0}
+		for v, variant := range cluster.Variants {
+			view.Variants = append(view.Variants, adjudicationVariant{
+				Index:      v,
+				Annotator:  variant.Annotator,
+				Color:      colors[variant.Annotator],
+				Prediction: variant.Prediction,
+				Checked:    v == cluster.Choice,
+			})
+		}
+		clusters = append(clusters, view)
+	}
+
+	data := struct {
+		Page      models.PageView
+		Sets      []models.AnnotationSet
+		Colors    map[string]string
+		Clusters  []adjudicationCluster
+		Version   string
+		CanReview bool
+	}{
+		Page:      page,
+		Sets:      sets,
+		Colors:    colors,
+		Clusters:  clusters,
+		Version:   setsVersion(sets),
+		CanReview: auth.IsReviewer(auth.CurrentUser(r)),
+	}
+	err = tmpl.ExecuteTemplate(w, "adjudicate.go.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// SaveAdjudication merges the variants picked for each object ("cluster{n}" form values, -1 to drop) into the final annotation
+// of the page and approves it to VALIDATION. Only pages in review with every annotation set saved can be adjudicated.
+func (s *Server) SaveAdjudication(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	user := auth.CurrentUser(r)
+	if !auth.IsReviewer(user) {
+		http.Error(w, "Only reviewers can adjudicate pages", http.StatusForbidden)
+		return
+	}
+	version := r.FormValue("version")
+	sets, ok := adjudicableSets(w, docId, pageNum, user, version)
+	if !ok {
+		return
+	}
+
+	clusters := agreement.Clusters(sets)
+	choices := make([]int, len(clusters))
+	for c := range clusters {
+		var err error
+		choices[c], err = strconv.Atoi(r.FormValue(fmt.Sprintf("cluster%d", c)))
+		if err != nil {
+			choices[c] = -1
+		}
+	}
+	predictions := agreement.Merge(clusters, choices)
+	// Checked again right before the page is overwritten, so that a page approved or annotated meanwhile keeps its boxes
+	if _, ok = adjudicableSets(w, docId, pageNum, user, version); !ok {
+		return
+	}
+	err := pipeline.ApplyPredictions(docId, pageNum, &predictions, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = workflow.Change(docId, pageNum, "VALIDATION", user, true, "adjudicated")
+	if errors.Is(err, workflow.ErrNotAllowed) || errors.Is(err, workflow.ErrStatusChanged) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/agreement", http.StatusSeeOther)
+}
+
+// adjudicableSets loads the annotation sets of a page the user may adjudicate: every annotator has saved their copy, none
+// changed since the version the reviewer opened, and the page is in review and was not submitted by the user.
+// Otherwise the request is answered.
+func adjudicableSets(w http.ResponseWriter, docId int64, pageNum int, user string, version string) ([]models.AnnotationSet, bool) {
+	required, err := db.RequiredAnnotators(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if required == 0 {
+		http.Error(w, "Page is not selected for agreement", http.StatusConflict)
+		return nil, false
+	}
+	sets, err := db.ListAnnotationSets(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(sets) < required {
+		http.Error(w, fmt.Sprintf("Waiting for %d more annotations", required-len(sets)), http.StatusConflict)
+		return nil, false
+	}
+	if version != setsVersion(sets) {
+		http.Error(w, "Annotations changed since the page was opened, reload it", http.StatusConflict)
+		return nil, false
+	}
+	// The page is approved once adjudicated, it has to be in review and the reviewer cannot be who submitted it
+	err = workflow.Check(docId, pageNum, "VALIDATION", user, true, "adjudicated")
+	if errors.Is(err, workflow.ErrNotAllowed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return sets, true
+}
+
+// setsVersion changes whenever an annotation set of the page is added or saved.
+func setsVersion(sets []models.AnnotationSet) string {
+	var latest int64
+	for _, set := range sets {
+		latest = max(latest, set.UpdatedAt.UnixNano())
+	}
+	return fmt.Sprintf("%d-%d", len(sets), latest)
+}
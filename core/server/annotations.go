@@ -0,0 +1,143 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/queue"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAnnotations returns the detector output and human corrections stored for a page, newest first.
+// The "source" query parameter narrows the list to "model" or "human" annotations.
+func (s *Server) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	source := r.URL.Query().Get("source")
+	if source != "" && source != models.SourceModel && source != models.SourceHuman {
+		http.Error(w, "Invalid source", http.StatusBadRequest)
+		return
+	}
+	annotations, err := db.ListAnnotations(docId, pageNum, source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, annotations)
+}
+
+func (s *Server) GetAnnotation(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	annotationId, err := strconv.ParseInt(chi.URLParam(r, "annotationId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	annotation, err := db.GetAnnotation(docId, pageNum, annotationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, annotation)
+}
+
+// DiffAnnotations compares the annotations given by the "from" and "to" query parameters.
+func (s *Server) DiffAnnotations(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	fromId, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid from annotation", http.StatusBadRequest)
+		return
+	}
+	toId, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid to annotation", http.StatusBadRequest)
+		return
+	}
+	from, err := db.GetAnnotation(docId, pageNum, fromId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	to, err := db.GetAnnotation(docId, pageNum, toId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, http.StatusOK, pipeline.DiffPredictions(from.Predictions, to.Predictions))
+}
+
+// RestoreAnnotation makes an earlier annotation current. The annotation page is reloaded when restored from its history panel.
+func (s *Server) RestoreAnnotation(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	annotationId, err := strconv.ParseInt(chi.URLParam(r, "annotationId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = queue.CheckLease(docId, pageNum, auth.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	predictions, err := pipeline.RestoreAnnotation(docId, pageNum, annotationId, auth.CurrentUser(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	writeJson(w, http.StatusOK, predictions)
+}
+
+func (s *Server) AnnotationHistory(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	versions, err := pipeline.History(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		DocumentId int64
+		PageNum    int
+		Versions   []models.AnnotationVersion
+	}{
+		DocumentId: docId,
+		PageNum:    pageNum,
+		Versions:   versions,
+	}
+	err = tmpl.ExecuteTemplate(w, "annotation-history.go.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
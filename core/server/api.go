@@ -0,0 +1,621 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/export"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/validation"
+	"smart-docs/core/workflow"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiPrefix is where the versioned json api is mounted, next to the routes of the web interface.
+const apiPrefix = "/api/v1"
+
+// apiSpec is the OpenAPI document of the api, every route must be described in it.
+//
+//go:embed openapi.json
+var apiSpec []byte
+
+// Document listings return this many documents unless the client asks for fewer, or more up to maxApiLimit.
+const (
+	defaultApiLimit = 20
+	maxApiLimit     = 100
+)
+
+// apiError is the body of every failed api request.
+type apiError struct {
+	Status   int                  `json:"status"`
+	Error    string               `json:"error"`
+	Problems []validation.Problem `json:"problems,omitempty"`
+}
+
+type apiDocument struct {
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Mode       string    `json:"mode"`
+	Project    string    `json:"project"`
+	UploadDate time.Time `json:"uploadDate"`
+	PageCount  int       `json:"pageCount"`
+	InProgress int       `json:"inProgress"`
+	Validated  int       `json:"validated"`
+}
+
+type apiDocumentList struct {
+	Documents []apiDocument `json:"documents"`
+	Limit     int           `json:"limit"`
+	Offset    int           `json:"offset"`
+}
+
+// apiPage is a page of a document. Listings leave out the rendered content.
+type apiPage struct {
+	DocumentId int64  `json:"documentId"`
+	PageNum    int    `json:"pageNum"`
+	Status     string `json:"status"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Html       string `json:"html,omitempty"`
+	Markdown   string `json:"markdown,omitempty"`
+	// Transitions are the status changes available to the current user
+	Transitions []models.Transition `json:"transitions,omitempty"`
+}
+
+type apiStatusChange struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (s *Server) apiRoutes() http.Handler {
+	r := s.apiRouter()
+	err := checkApiSpec(r)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// apiRouter routes the operations of the OpenAPI document to their handlers.
+func (s *Server) apiRouter() chi.Router {
+	r := chi.NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusNotFound, "not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	})
+
+	r.Get("/openapi.json", s.ApiSpec)
+	r.Get("/documents", s.ApiListDocuments)
+	r.Post("/documents", s.ApiCreateDocument)
+	r.Get("/documents/{documentId}", s.ApiGetDocument)
+	r.Delete("/documents/{documentId}", s.ApiDeleteDocument)
+	r.Post("/documents/{documentId}/retry", s.ApiRetryDocument)
+	r.Get("/documents/{documentId}/export/{format}", s.ApiExportDocument)
+	r.Get("/documents/{documentId}/pages", s.ApiListPages)
+	r.Get("/documents/{documentId}/pages/{pageNum}", s.ApiGetPage)
+	r.Get("/documents/{documentId}/pages/{pageNum}/predictions", s.ApiGetPredictions)
+	r.Put("/documents/{documentId}/pages/{pageNum}/predictions", s.ApiSetPredictions)
+	r.Post("/documents/{documentId}/pages/{pageNum}/status", s.ApiChangeStatus)
+	r.Get("/documents/{documentId}/pages/{pageNum}/transitions", s.ApiListTransitions)
+	r.Get("/exports/{format}", s.ApiExportDataset)
+	r.Get("/webhooks", s.ApiListWebhooks)
+	r.Post("/webhooks", s.ApiCreateWebhook)
+	r.Get("/webhooks/{webhookId}", s.ApiGetWebhook)
+	r.Delete("/webhooks/{webhookId}", s.ApiDeleteWebhook)
+	r.Get("/webhooks/{webhookId}/deliveries", s.ApiListDeliveries)
+	r.Get("/webhooks/{webhookId}/deliveries/{deliveryId}", s.ApiGetDelivery)
+	r.Post("/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", s.ApiRedeliver)
+	return r
+}
+
+// checkApiSpec compares the routes of the api with the operations of its OpenAPI document, so that neither is changed
+// without the other.
+func checkApiSpec(routes chi.Routes) error {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err := json.Unmarshal(apiSpec, &spec)
+	if err != nil {
+		return fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+	var problems []string
+	err = chi.Walk(routes, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		operation := method + " " + route
+		if !documented[operation] {
+			problems = append(problems, operation+" is not documented")
+		}
+		delete(documented, operation)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for operation := range documented {
+		problems = append(problems, operation+" has no route")
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("OpenAPI document is out of sync with the api: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// ApiSpec serves the OpenAPI document of the api.
+func (s *Server) ApiSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(apiSpec)
+}
+
+// ApiListDocuments lists documents, the most recent first, filtered by "status", "mode", "project" and by upload date
+// with "from" and "to". Dates are RFC 3339 times or days, a day given as "to" is included.
+func (s *Server) ApiListDocuments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.DocumentFilter{
+		Status:  query.Get("status"),
+		Mode:    query.Get("mode"),
+		Project: query.Get("project"),
+		Limit:   defaultApiLimit,
+	}
+	var err error
+	if query.Has("limit") {
+		filter.Limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || filter.Limit < 1 || filter.Limit > maxApiLimit {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxApiLimit))
+			return
+		}
+	}
+	if query.Has("offset") {
+		filter.Offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || filter.Offset < 0 {
+			writeApiError(w, http.StatusBadRequest, "offset must be a positive number")
+			return
+		}
+	}
+	if query.Has("from") {
+		filter.From, err = parseApiDate(query.Get("from"), false)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if query.Has("to") {
+		filter.To, err = parseApiDate(query.Get("to"), true)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	documents, err := db.FilterDocuments(filter)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	list := apiDocumentList{Documents: make([]apiDocument, len(documents)), Limit: filter.Limit, Offset: filter.Offset}
+	for i, doc := range documents {
+		list.Documents[i] = newApiDocument(doc)
+	}
+	writeJson(w, http.StatusOK, list)
+}
+
+// ApiCreateDocument uploads a pdf from a multipart form, like the upload of the web interface. The document is
+// processed in the background, its status is "PROCESSING" until it is done.
+func (s *Server) ApiCreateDocument(w http.ResponseWriter, r *http.Request) {
+	doc, err := s.storeUpload(r)
+	if errors.Is(err, errBadUpload) {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/documents/%d", apiPrefix, doc.Id))
+	writeJson(w, http.StatusCreated, newApiDocument(doc))
+}
+
+func (s *Server) ApiGetDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := apiDocumentParam(w, r)
+	if !ok {
+		return
+	}
+	writeJson(w, http.StatusOK, newApiDocument(doc))
+}
+
+func (s *Server) ApiDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := apiDocumentParam(w, r)
+	if !ok {
+		return
+	}
+	err := db.DeleteDocument(doc.Id)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApiRetryDocument runs the detector again on the pages of a document which are not reviewed or validated yet.
+func (s *Server) ApiRetryDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := apiDocumentParam(w, r)
+	if !ok {
+		return
+	}
+	err := pipeline.RetryAnnotations(doc.Id)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	doc, err = db.LoadDocument(doc.Id)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/documents/%d", apiPrefix, doc.Id))
+	writeJson(w, http.StatusAccepted, newApiDocument(doc))
+}
+
+// ApiExportDocument exports a document as "json", "markdown", "html", "tables" (json), "csv" (tables) or "bundle".
+func (s *Server) ApiExportDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := apiDocumentParam(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	var err error
+	contentType := "application/json"
+	switch chi.URLParam(r, "format") {
+	case "json":
+		err = export.WriteDocumentJson(&buf, doc.Id)
+	case "markdown":
+		contentType = "text/markdown; charset=utf-8"
+		var content string
+		content, err = db.GetDocMarkdown(doc.Id)
+		buf.WriteString(content)
+	case "html":
+		contentType = "text/html; charset=utf-8"
+		var content string
+		content, err = db.GetPdfDocText(doc.Id)
+		buf.WriteString(content)
+	case "tables":
+		err = export.WriteTablesJson(&buf, doc.Id)
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		err = export.WriteTablesCsv(&buf, doc.Id)
+	case "bundle":
+		contentType = "application/zip"
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.Name+".zip"))
+		err = export.WriteContentBundle(&buf, doc.Id)
+	default:
+		writeApiError(w, http.StatusNotFound, "unknown export format")
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		writeApiError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export document: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Printf("Failed to write export: %v", err)
+	}
+}
+
+// ApiExportDataset downloads the "coco", "yolo", "ocr" or "entities" dataset of a project, given with "project".
+func (s *Server) ApiExportDataset(w http.ResponseWriter, r *http.Request) {
+	format := chi.URLParam(r, "format")
+	if format != "coco" && format != "yolo" && format != "ocr" && format != "entities" {
+		writeApiError(w, http.StatusNotFound, "unknown export format")
+		return
+	}
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		project = db.DefaultProject
+	}
+	_, err := db.LoadLabelSchema(project)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "unknown project")
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	splits, err := yoloSplits(r)
+	if format == "yolo" && err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.zip", format, time.Now().Format("2006-01-02"))))
+	switch format {
+	case "coco":
+		err = export.WriteCocoDataset(w, project)
+	case "yolo":
+		err = export.WriteYoloDataset(w, project, splits, r.URL.Query().Get("dataset") == "tables")
+	case "ocr":
+		err = export.WriteOcrGroundTruth(w, project)
+	case "entities":
+		err = export.WriteEntityDataset(w, project)
+	}
+	if err != nil {
+		// The archive is streamed, the client ends up with a truncated file
+		log.Printf("Failed to export %s dataset: %v", format, err)
+	}
+}
+
+func (s *Server) ApiListPages(w http.ResponseWriter, r *http.Request) {
+	doc, ok := apiDocumentParam(w, r)
+	if !ok {
+		return
+	}
+	pages, err := db.LoadPages(doc.Id)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	list := make([]apiPage, len(pages))
+	for i, page := range pages {
+		list[i] = apiPage{DocumentId: page.DocumentId, PageNum: page.PageNum, Status: page.Status, Width: page.Width, Height: page.Height}
+	}
+	writeJson(w, http.StatusOK, list)
+}
+
+// ApiGetPage returns a page with its rendered html and markdown and the status changes available to the user.
+func (s *Server) ApiGetPage(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := apiPageParams(w, r)
+	if !ok {
+		return
+	}
+	page, ok := loadApiPage(w, r, docId, pageNum)
+	if !ok {
+		return
+	}
+	writeJson(w, http.StatusOK, page)
+}
+
+// ApiGetPredictions returns the annotations of a page, or the copy of the user on pages annotated independently.
+func (s *Server) ApiGetPredictions(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := apiPageParams(w, r)
+	if !ok {
+		return
+	}
+	page, err := db.LoadPageContent(docId, pageNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	required, err := db.RequiredAnnotators(docId, pageNum)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if required > 0 {
+		set, err := db.GetAnnotationSet(docId, pageNum, auth.CurrentUser(r))
+		if err == nil {
+			writeJson(w, http.StatusOK, set.Predictions)
+			return
+		}
+		if err != sql.ErrNoRows {
+			writeApiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if page.Predictions == nil {
+		page.Predictions = []models.Prediction{}
+	}
+	writeJson(w, http.StatusOK, page.Predictions)
+}
+
+// ApiSetPredictions replaces the annotations of a page and renders it again. Annotations are validated like the ones
+// of the annotation tool, "fix" normalises boxes first and rejected payloads are answered with 422 and their problems.
+func (s *Server) ApiSetPredictions(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := apiPageParams(w, r)
+	if !ok {
+		return
+	}
+	var predictions []models.Prediction
+	err := json.NewDecoder(r.Body).Decode(&predictions)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	predictions, problems, err := checkPredictions(r, docId, pageNum, predictions)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		writeJson(w, http.StatusUnprocessableEntity, apiError{Status: http.StatusUnprocessableEntity, Error: "invalid annotations", Problems: problems})
+		return
+	}
+	status, err := savePredictions(r, docId, pageNum, &predictions)
+	if err != nil {
+		writeApiError(w, status, err.Error())
+		return
+	}
+	writeJson(w, http.StatusOK, predictions)
+}
+
+// ApiChangeStatus moves a page through the review workflow, with a reason when the page is sent back.
+func (s *Server) ApiChangeStatus(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := apiPageParams(w, r)
+	if !ok {
+		return
+	}
+	var change apiStatusChange
+	err := json.NewDecoder(r.Body).Decode(&change)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user := auth.CurrentUser(r)
+	err = workflow.Change(docId, pageNum, change.Status, user, auth.IsReviewer(user), strings.TrimSpace(change.Reason))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return
+	case errors.Is(err, workflow.ErrNotAllowed):
+		writeApiError(w, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, workflow.ErrReasonRequired):
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, workflow.ErrStatusChanged):
+		writeApiError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	page, ok := loadApiPage(w, r, docId, pageNum)
+	if !ok {
+		return
+	}
+	writeJson(w, http.StatusOK, page)
+}
+
+func (s *Server) ApiListTransitions(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := apiPageParams(w, r)
+	if !ok {
+		return
+	}
+	if _, _, err := db.GetPageSize(docId, pageNum); errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return
+	}
+	transitions, err := db.ListTransitions(docId, pageNum)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if transitions == nil {
+		transitions = []models.StatusChange{}
+	}
+	writeJson(w, http.StatusOK, transitions)
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, apiError{Status: status, Error: message})
+}
+
+// apiDocumentParam loads the document of the request, the second result is false when the request has been answered.
+func apiDocumentParam(w http.ResponseWriter, r *http.Request) (models.Document, bool) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "document not found")
+		return models.Document{}, false
+	}
+	doc, err := db.LoadDocument(docId)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "document not found")
+		return doc, false
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return doc, false
+	}
+	return doc, true
+}
+
+func apiPageParams(w http.ResponseWriter, r *http.Request) (int64, int, bool) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "document not found")
+		return 0, 0, false
+	}
+	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return 0, 0, false
+	}
+	return docId, pageNum, true
+}
+
+func loadApiPage(w http.ResponseWriter, r *http.Request, docId int64, pageNum int) (apiPage, bool) {
+	page, err := db.LoadPageContent(docId, pageNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "page not found")
+		return apiPage{}, false
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return apiPage{}, false
+	}
+	return apiPage{
+		DocumentId:  page.DocumentId,
+		PageNum:     page.PageNum,
+		Status:      page.Status,
+		Width:       page.Width,
+		Height:      page.Height,
+		Html:        page.Html,
+		Markdown:    page.Md,
+		Transitions: workflow.Allowed(page.Status, auth.IsReviewer(auth.CurrentUser(r))),
+	}, true
+}
+
+func newApiDocument(doc models.Document) apiDocument {
+	return apiDocument{
+		Id:         doc.Id,
+		Name:       doc.Name,
+		Status:     doc.Status,
+		Mode:       doc.Mode,
+		Project:    doc.Project,
+		UploadDate: doc.UploadDate,
+		PageCount:  doc.PageCount,
+		InProgress: doc.InProgress,
+		Validated:  doc.Validated,
+	}
+}
+
+// parseApiDate reads an RFC 3339 time or a day. A day ending a range includes the whole day.
+func parseApiDate(value string, end bool) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or an RFC 3339 time", value)
+	}
+	if end {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, nil
+}
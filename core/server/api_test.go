@@ -0,0 +1,10 @@
+package server
+
+import "testing"
+
+func TestApiSpecMatchesRoutes(t *testing.T) {
+	s := &Server{}
+	if err := checkApiSpec(s.apiRouter()); err != nil {
+		t.Error(err)
+	}
+}
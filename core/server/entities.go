@@ -0,0 +1,93 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/entities"
+	"smart-docs/core/queue"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) ListEntitySpans(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	spans, err := entities.List(docId, pageNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, spans)
+}
+
+// CreateEntitySpan tags the words "start" to "end" (inclusive) of a page with the entity "label".
+func (s *Server) CreateEntitySpan(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Label string `json:"label"`
+		Start int    `json:"start"`
+		End   int    `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user := auth.CurrentUser(r)
+	err := queue.CheckLease(docId, pageNum, user)
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	span, err := entities.Create(docId, pageNum, req.Label, req.Start, req.End, user)
+	switch {
+	case errors.Is(err, entities.ErrInvalidSpan):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.NotFound(w, r)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeJson(w, http.StatusCreated, span)
+	}
+}
+
+func (s *Server) DeleteEntitySpan(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	spanId, err := strconv.ParseInt(chi.URLParam(r, "spanId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = queue.CheckLease(docId, pageNum, auth.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	err = db.DeleteEntitySpan(docId, pageNum, spanId)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/queue"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fieldRequest is the body of field edits. Boxes left out are kept when editing and empty when adding.
+type fieldRequest struct {
+	Key      string       `json:"key"`
+	Value    string       `json:"value"`
+	KeyBox   *models.Rect `json:"keyBox"`
+	ValueBox *models.Rect `json:"valueBox"`
+}
+
+func (s *Server) ListFields(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	if _, _, err := db.GetPageSize(docId, pageNum); errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	fields, err := db.ListFields(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, fields)
+}
+
+// ExtractFields runs the key-value extraction of a page again, keeping fields edited by annotators.
+func (s *Server) ExtractFields(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	err := queue.CheckLease(docId, pageNum, auth.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	fields, err := pipeline.ExtractFields(docId, pageNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, fields)
+}
+
+func (s *Server) AddField(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	field := models.Field{DocumentId: docId, PageNum: pageNum}
+	if !s.readField(w, r, &field) {
+		return
+	}
+	err := db.StoreField(&field)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusCreated, field)
+}
+
+func (s *Server) UpdateField(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	fieldId, err := strconv.ParseInt(chi.URLParam(r, "fieldId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	fields, err := db.ListFields(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	i := slices.IndexFunc(fields, func(field models.Field) bool { return field.Id == fieldId })
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	field := fields[i]
+	if !s.readField(w, r, &field) {
+		return
+	}
+	err = db.UpdateField(&field)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, field)
+}
+
+func (s *Server) DeleteField(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	fieldId, err := strconv.ParseInt(chi.URLParam(r, "fieldId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := auth.CurrentUser(r)
+	err = queue.CheckLease(docId, pageNum, user)
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	err = db.RemoveField(docId, pageNum, fieldId, user)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readField applies the request body to a field as edited by the current user. It answers the request and returns
+// false when the page is leased by someone else or the body is invalid.
+func (s *Server) readField(w http.ResponseWriter, r *http.Request, field *models.Field) bool {
+	user := auth.CurrentUser(r)
+	err := queue.CheckLease(field.DocumentId, field.PageNum, user)
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return false
+	}
+	var req fieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	field.Key = strings.TrimSpace(req.Key)
+	if field.Key == "" {
+		http.Error(w, "A key is required", http.StatusBadRequest)
+		return false
+	}
+	field.Value = req.Value
+	if req.KeyBox != nil {
+		field.KeyBox = *req.KeyBox
+	}
+	if req.ValueBox != nil {
+		field.ValueBox = *req.ValueBox
+	}
+	field.Source = models.SourceHuman
+	field.Author = user
+	return true
+}
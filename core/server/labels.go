@@ -0,0 +1,102 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/values"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) ListProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := db.ListProjects()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, projects)
+}
+
+func (s *Server) GetLabelSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := db.LoadLabelSchema(chi.URLParam(r, "project"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, schema)
+}
+
+// SaveLabelSchema replaces the labels of a project, creating the project when needed.
+// Pages annotated with labels removed from the schema keep them, they are rendered as plain text until re-annotated.
+func (s *Server) SaveLabelSchema(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsReviewer(auth.CurrentUser(r)) {
+		http.Error(w, "Only reviewers can change label schemas", http.StatusForbidden)
+		return
+	}
+	var schema models.LabelSchema
+	err := json.NewDecoder(r.Body).Decode(&schema)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	schema.Project = chi.URLParam(r, "project")
+	err = errors.Join(schema.Check(), values.Check(schema.Locale))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = db.SaveLabelSchema(schema)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, schema)
+}
+
+// GetDocumentLabels returns the label schema of the project the document belongs to, used by the annotation tool.
+func (s *Server) GetDocumentLabels(w http.ResponseWriter, r *http.Request) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	schema, err := db.DocumentLabelSchema(docId)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, schema)
+}
+
+// exportProject returns the project named by the "project" query parameter, the default project when it is missing.
+// It answers with 404 and returns false when the project does not exist.
+func exportProject(w http.ResponseWriter, r *http.Request) (string, bool) {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		project = db.DefaultProject
+	}
+	_, err := db.LoadLabelSchema(project)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Unknown project", http.StatusNotFound)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return project, true
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/queue"
+	"strings"
+)
+
+func (s *Server) ShowQueue(w http.ResponseWriter, r *http.Request) {
+	items, err := queue.Pending()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	reviews, err := db.ReviewQueue()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		Items   []models.QueueItem  `json:"annotation"`
+		Reviews []models.ReviewItem `json:"review"`
+		User    string              `json:"-"`
+	}{
+		Items:   items,
+		Reviews: reviews,
+		User:    auth.CurrentUser(r),
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJson(w, http.StatusOK, data)
+		return
+	}
+	err = tmpl.ExecuteTemplate(w, "queue.go.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ReleasePage gives up the current user's lease so the page goes back to the queue.
+func (s *Server) ReleasePage(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	err := db.ReleasePage(docId, pageNum, auth.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignPage reserves a page for the "assignee" form value. DELETE, or an empty assignee, puts the page back to the shared queue.
+func (s *Server) AssignPage(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	assignee := ""
+	if r.Method == http.MethodPut {
+		assignee = strings.TrimSpace(r.FormValue("assignee"))
+	}
+	err := db.AssignPage(docId, pageNum, assignee)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// leaseErrorStatus answers edits refused by the lease check: 403 for anonymous users of the queue, 409 otherwise.
+func leaseErrorStatus(err error) int {
+	if errors.Is(err, queue.ErrAnonymous) {
+		return http.StatusForbidden
+	}
+	return http.StatusConflict
+}
@@ -1,19 +1,28 @@
 package server
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"smart-docs/cmd/web"
 	"smart-docs/core/auth"
 	"smart-docs/core/db"
+	"smart-docs/core/export"
+	"smart-docs/core/importer"
 	"smart-docs/core/models"
 	"smart-docs/core/pipeline"
+	"smart-docs/core/queue"
+	"smart-docs/core/sampling"
+	"smart-docs/core/webhooks"
 	"strconv"
 	"strings"
 	"time"
@@ -35,20 +44,85 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	r.Get("/health", s.healthHandler)
 	r.Get("/", s.ListDocuments)
+	r.Get("/search", s.SearchContent)
+	r.Get("/export/coco", s.ExportCoco)
+	r.Get("/export/yolo", s.ExportYolo)
+	r.Get("/export/ocr", s.ExportOcr)
+	r.Get("/export/entities", s.ExportEntities)
+	r.Get("/projects", s.ListProjects)
+	r.Get("/projects/{project}/labels", s.GetLabelSchema)
+	r.Put("/projects/{project}/labels", s.SaveLabelSchema)
 	r.Get("/annotate", s.NextPageToAnnotate)
 	r.Get("/annotate/{documentId}/{pageNum}", s.AnnotatePage)
+	r.Delete("/annotate/{documentId}/{pageNum}/lease", s.ReleasePage)
+	r.Get("/queue", s.ShowQueue)
+	r.Get("/agreement", s.ShowAgreement)
+	r.Get("/adjudicate/{documentId}/{pageNum}", s.AdjudicatePage)
+	r.Post("/adjudicate/{documentId}/{pageNum}", s.SaveAdjudication)
 	r.Post("/upload", s.UploadDocument)
+	r.Post("/import", s.ImportAnnotations)
 	r.Get("/document/{documentId}", s.LoadDocument)
 	r.Delete("/document/{documentId}", s.DeleteDocument)
 	r.Get("/document/{documentId}/content", s.LoadContent)
+	r.Get("/document/{documentId}/markdown", s.LoadMarkdown)
+	r.Get("/document/{documentId}/json", s.LoadJson)
+	r.Get("/document/{documentId}/tables", s.LoadTables)
+	r.Get("/document/{documentId}/bundle", s.DownloadBundle)
+	r.Get("/document/{documentId}/assets/{assetName}", s.GetAsset)
+	r.Get("/document/{documentId}/labels", s.GetDocumentLabels)
 	r.Put("/document/{documentId}/retry", s.Retry)
 	r.Patch("/document/{documentId}/{pageNum}/status/{newStatus}", s.UpdateStatus)
+	r.Get("/document/{documentId}/{pageNum}/transitions", s.ListTransitions)
+	r.Post("/document/{documentId}/{pageNum}/agreement", s.SelectForAgreement)
+	r.Delete("/document/{documentId}/{pageNum}/agreement", s.UnselectForAgreement)
+	r.Get("/document/{documentId}/{pageNum}/agreement/sets", s.ListAnnotationSets)
 	r.Get("/document/{documentId}/{pageNum}/predictions", s.GetPredictions)
 	r.Post("/document/{documentId}/{pageNum}/predictions", s.SetPredictions)
+	r.Put("/document/{documentId}/{pageNum}/assignee", s.AssignPage)
+	r.Delete("/document/{documentId}/{pageNum}/assignee", s.AssignPage)
+	r.Get("/document/{documentId}/{pageNum}/annotations", s.ListAnnotations)
+	r.Get("/document/{documentId}/{pageNum}/annotations/diff", s.DiffAnnotations)
+	r.Get("/document/{documentId}/{pageNum}/annotations/{annotationId}", s.GetAnnotation)
+	r.Post("/document/{documentId}/{pageNum}/annotations/{annotationId}/restore", s.RestoreAnnotation)
+	r.Get("/document/{documentId}/{pageNum}/words", s.ListWords)
+	r.Post("/document/{documentId}/{pageNum}/words", s.AddWord)
+	r.Get("/document/{documentId}/{pageNum}/words/edits", s.ListWordEdits)
+	r.Post("/document/{documentId}/{pageNum}/words/merge", s.MergeWords)
+	r.Put("/document/{documentId}/{pageNum}/words/{wordIndex}", s.EditWord)
+	r.Delete("/document/{documentId}/{pageNum}/words/{wordIndex}", s.DeleteWord)
+	r.Post("/document/{documentId}/{pageNum}/words/{wordIndex}/split", s.SplitWord)
+	r.Get("/document/{documentId}/{pageNum}/entities", s.ListEntitySpans)
+	r.Post("/document/{documentId}/{pageNum}/entities", s.CreateEntitySpan)
+	r.Delete("/document/{documentId}/{pageNum}/entities/{spanId}", s.DeleteEntitySpan)
+	r.Get("/document/{documentId}/{pageNum}/fields", s.ListFields)
+	r.Post("/document/{documentId}/{pageNum}/fields", s.AddField)
+	r.Post("/document/{documentId}/{pageNum}/fields/extract", s.ExtractFields)
+	r.Put("/document/{documentId}/{pageNum}/fields/{fieldId}", s.UpdateField)
+	r.Delete("/document/{documentId}/{pageNum}/fields/{fieldId}", s.DeleteField)
+	r.Get("/document/{documentId}/{pageNum}/tables/{tableIndex}", s.GetTable)
+	r.Post("/document/{documentId}/{pageNum}/tables/{tableIndex}/rows", s.InsertTableRow)
+	r.Delete("/document/{documentId}/{pageNum}/tables/{tableIndex}/rows/{row}", s.DeleteTableRow)
+	r.Post("/document/{documentId}/{pageNum}/tables/{tableIndex}/columns", s.InsertTableColumn)
+	r.Delete("/document/{documentId}/{pageNum}/tables/{tableIndex}/columns/{col}", s.DeleteTableColumn)
+	r.Post("/document/{documentId}/{pageNum}/tables/{tableIndex}/merge", s.MergeTableCells)
+	r.Put("/document/{documentId}/{pageNum}/tables/{tableIndex}/header", s.SetTableHeader)
+	r.Put("/document/{documentId}/{pageNum}/tables/{tableIndex}/cells/{row}/{col}", s.SetTableCellText)
+	r.Post("/document/{documentId}/{pageNum}/tables/{tableIndex}/cells/{row}/{col}/split", s.SplitTableCell)
+	r.Get("/annotate/{documentId}/{pageNum}/history", s.AnnotationHistory)
+	r.Mount(apiPrefix, s.apiRoutes())
 
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 		"sub": func(a, b int) int { return a - b },
+		"ratio": func(value float64) string {
+			return fmt.Sprintf("%.1f%%", value*100)
+		},
+		"percent": func(value float32, total int) float32 {
+			if total == 0 {
+				return 0
+			}
+			return value / float32(total) * 100
+		},
 	}
 
 	tmpl = template.Must(template.New("").Funcs(funcMap).ParseFS(web.Files,
@@ -57,10 +131,14 @@ func (s *Server) RegisterRoutes() http.Handler {
 		"templates/document.go.html",
 		"templates/document-loading.go.html",
 		"templates/nothing-to-annotate.go.html",
+		"templates/queue.go.html",
+		"templates/agreement.go.html",
+		"templates/adjudicate.go.html",
 		"templates/login.go.html",
 		"templates/partial/head.go.html",
 		"templates/partial/page-status.go.html",
 		"templates/partial/document-rows.go.html",
+		"templates/partial/annotation-history.go.html",
 	))
 
 	return auth.AuthMiddleware(tmpl, r)
@@ -94,6 +172,7 @@ func (s *Server) LoadDocument(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		webhooks.PublishDocument(webhooks.EventDocumentFailed, docId)
 	}
 
 	if r.Header.Get("hx-current-url") != "" && !strings.Contains(r.Header.Get("hx-current-url"), "document") {
@@ -121,12 +200,30 @@ func (s *Server) LoadDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	search := r.URL.Query().Get("search")
+	if search != "" && !r.URL.Query().Has("page") {
+		matchingPage, err := db.FirstMatchingPage(docId, search)
+		if err == nil {
+			pageNum = matchingPage
+		}
+	}
+
 	var pageView models.PageView
 	err = db.LoadPage(docId, pageNum, &pageView)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	loadWorkflow(r, &pageView)
+
+	if search != "" {
+		pageView.Search = search
+		pageView.Highlights, err = db.PageHighlights(docId, pageNum, search)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
 	err = tmpl.ExecuteTemplate(w, "document.go.html", pageView)
 	if err != nil {
@@ -150,69 +247,262 @@ func (s *Server) DeleteDocument(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoadContent(w http.ResponseWriter, r *http.Request) {
 	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
+	content, err := db.GetPdfDocText(docId)
+	if err != nil {
+		http.Error(w, "Failed to export document content", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8\n")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(content))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) LoadMarkdown(w http.ResponseWriter, r *http.Request) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	newStatus := chi.URLParam(r, "newStatus")
-	if newStatus != "TRAINING" && newStatus != "PREDICTION" && newStatus != "VALIDATION" {
-		http.Error(w, "Invalid status", http.StatusBadRequest)
+	content, err := db.GetDocMarkdown(docId)
+	if err != nil {
+		http.Error(w, "Failed to export document markdown", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(content))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
+}
 
-	var pageView models.PageView
-	err = db.UpdateTrainingStatus(docId, pageNum, newStatus)
+// LoadTables exports the tables of a document with typed cell values, as json or with "format=csv" as csv.
+func (s *Server) LoadTables(w http.ResponseWriter, r *http.Request) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	pageView.Status = newStatus
-	err = tmpl.ExecuteTemplate(w, "page-status.go.html", pageView)
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if r.URL.Query().Get("format") == "csv" {
+		contentType = "text/csv; charset=utf-8"
+		err = export.WriteTablesCsv(&buf, docId)
+	} else {
+		err = export.WriteTablesJson(&buf, docId)
+	}
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to export tables", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) LoadJson(w http.ResponseWriter, r *http.Request) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var buf bytes.Buffer
+	err = export.WriteDocumentJson(&buf, docId)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Failed to export document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func (s *Server) LoadContent(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DownloadBundle(w http.ResponseWriter, r *http.Request) {
 	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	content, err := db.GetPdfDocText(docId)
+	doc, err := db.LoadDocument(docId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var buf bytes.Buffer
+	err = export.WriteContentBundle(&buf, docId)
 	if err != nil {
 		http.Error(w, "Failed to export document content", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8\n")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.Name+".zip"))
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(content))
+	_, err = w.Write(buf.Bytes())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Failed to write bundle: %v", err)
+	}
+}
+
+func (s *Server) GetAsset(w http.ResponseWriter, r *http.Request) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	name := filepath.Base(chi.URLParam(r, "assetName"))
+	http.ServeFile(w, r, filepath.Join(pipeline.AssetDir(docId), name))
+}
+
+func (s *Server) ExportCoco(w http.ResponseWriter, r *http.Request) {
+	project, ok := exportProject(w, r)
+	if !ok {
+		return
+	}
+	// Datasets take longer to pack than the server write timeout allows
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("coco-%s.zip", time.Now().Format("2006-01-02"))))
+	err := export.WriteCocoDataset(w, project)
+	if err != nil {
+		// Headers are already sent while streaming the archive, the client ends up with a truncated file
+		log.Printf("Failed to export COCO dataset: %v", err)
 	}
 }
 
+// ExportOcr downloads the corrected words of a project as OCR ground truth.
+func (s *Server) ExportOcr(w http.ResponseWriter, r *http.Request) {
+	project, ok := exportProject(w, r)
+	if !ok {
+		return
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("ocr-%s.zip", time.Now().Format("2006-01-02"))))
+	err := export.WriteOcrGroundTruth(w, project)
+	if err != nil {
+		log.Printf("Failed to export OCR ground truth: %v", err)
+	}
+}
+
+// ExportEntities downloads the entity spans of a project as a BIO tagged token classification dataset.
+func (s *Server) ExportEntities(w http.ResponseWriter, r *http.Request) {
+	project, ok := exportProject(w, r)
+	if !ok {
+		return
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("entities-%s.zip", time.Now().Format("2006-01-02"))))
+	err := export.WriteEntityDataset(w, project)
+	if err != nil {
+		log.Printf("Failed to export entity dataset: %v", err)
+	}
+}
+
+func (s *Server) ExportYolo(w http.ResponseWriter, r *http.Request) {
+	project, ok := exportProject(w, r)
+	if !ok {
+		return
+	}
+	splits, err := yoloSplits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tables := r.URL.Query().Get("dataset") == "tables"
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("yolo-%s.zip", time.Now().Format("2006-01-02"))))
+	err = export.WriteYoloDataset(w, project, splits, tables)
+	if err != nil {
+		log.Printf("Failed to export YOLO dataset: %v", err)
+	}
+}
+
+// yoloSplits reads the "train" and "val" percentages of a YOLO export, the rest of the pages is used for testing.
+func yoloSplits(r *http.Request) (export.Splits, error) {
+	splits := export.DefaultSplits
+	if r.URL.Query().Has("train") {
+		parsedTrain, err := strconv.Atoi(r.URL.Query().Get("train"))
+		if err != nil {
+			return splits, errors.New("Invalid train split")
+		}
+		splits.Train = parsedTrain
+	}
+	if r.URL.Query().Has("val") {
+		parsedVal, err := strconv.Atoi(r.URL.Query().Get("val"))
+		if err != nil {
+			return splits, errors.New("Invalid val split")
+		}
+		splits.Val = parsedVal
+	}
+	if !splits.Valid() {
+		return splits, errors.New("Splits must be percentages adding up to at most 100")
+	}
+	return splits, nil
+}
+
 func (s *Server) UploadDocument(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+	doc, err := s.storeUpload(r)
+	if errors.Is(err, errBadUpload) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		log.Println(fmt.Sprintf("Failed to parse request form: \n%v", err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Add("HX-Redirect", fmt.Sprintf("/document/%d", doc.Id))
+}
+
+// errBadUpload marks uploads rejected because of the request rather than the server.
+var errBadUpload = errors.New("invalid upload")
+
+// storeUpload stores the pdf of a multipart upload form as a new document of its project and processes it in the
+// background. The form has the "file", the "mode", the "project" and "ocr" set to "on" to run ocr.
+func (s *Server) storeUpload(r *http.Request) (models.Document, error) {
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		log.Println(fmt.Sprintf("Failed to parse request form: \n%v", err))
+		return models.Document{}, fmt.Errorf("%w: %v", errBadUpload, err)
+	}
 	shouldRunOcr := r.FormValue("ocr") == "on"
 	mode := r.FormValue("mode")
+	project := r.FormValue("project")
+	if project == "" {
+		project = db.DefaultProject
+	}
+	if _, err := db.LoadLabelSchema(project); err != nil {
+		return models.Document{}, fmt.Errorf("%w: unknown project", errBadUpload)
+	}
 	file, handler, err := r.FormFile("file")
 	if err != nil {
 		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return models.Document{}, fmt.Errorf("%w: %v", errBadUpload, err)
 	}
 	defer file.Close()
 
@@ -223,38 +513,93 @@ func (s *Server) UploadDocument(w http.ResponseWriter, r *http.Request) {
 		OcrRequired: false,
 		Status:      "PROCESSING",
 		Mode:        mode,
+		Project:     project,
 	}
 	docId, err := db.StoreDocument(&doc)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return doc, err
 	}
 
 	filePath := fmt.Sprintf("./data/files/%d.pdf", docId)
 	dest, err := os.Create(filePath)
 	if err != nil {
 		log.Println(fmt.Sprintf("Failed to create file: \n%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return doc, err
 	}
 	defer dest.Close()
 
 	_, err = io.Copy(dest, file)
 	if err != nil {
 		log.Println(fmt.Sprintf("Failed to populate file: \n%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return doc, err
 	}
 	err = pipeline.EnsureCorrectMime(filePath)
 	if err != nil {
 		log.Println(fmt.Sprintf("Failed to correctly encode file: \n%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return doc, err
+	}
+
+	go func() {
+		pipeline.ProcessPdf(docId, shouldRunOcr, mode)
+		sampling.Sweep(s.sampling)
+	}()
+	return doc, nil
+}
+
+func (s *Server) ImportAnnotations(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := r.FormValue("format")
+	status := r.FormValue("status")
+	if status == "" {
+		status = "TRAINING"
+	}
+	if !slices.Contains(importer.Statuses, status) {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	// Imported pages skip the workflow, only reviewers may approve them on the way in
+	if status == "VALIDATION" && !auth.IsReviewer(auth.CurrentUser(r)) {
+		http.Error(w, "Only reviewers can import pages as VALIDATION", http.StatusForbidden)
 		return
 	}
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
 
-	go pipeline.ProcessPdf(docId, shouldRunOcr, mode)
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	files, err := importer.ReadFiles(handler.Filename, content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := importer.Import(files, format, status, auth.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	w.Header().Add("HX-Redirect", fmt.Sprintf("/document/%d", doc.Id))
+	jsonResp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 func (s *Server) ListDocuments(w http.ResponseWriter, r *http.Request) {
@@ -286,12 +631,20 @@ func (s *Server) ListDocuments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	projects, err := db.ListProjects()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	data := struct {
 		Documents []models.Document
+		Projects  []string
 		Offset    int
 		Search    string
 	}{
 		Documents: documents,
+		Projects:  projects,
 		Offset:    offset,
 		Search:    search,
 	}
@@ -308,9 +661,54 @@ func (s *Server) ListDocuments(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) SearchContent(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	offset := 0
+
+	if r.URL.Query().Has("limit") {
+		parsedLimit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if r.URL.Query().Has("offset") {
+		parsedOffset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		if err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	query := r.URL.Query().Get("q")
+	hits, err := db.SearchPages(query, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Query string             `json:"query"`
+		Hits  []models.SearchHit `json:"hits"`
+	}{
+		Query: query,
+		Hits:  hits,
+	}
+	jsonResp, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) NextPageToAnnotate(w http.ResponseWriter, r *http.Request) {
-	var page models.PageView
-	err := db.NextPageToAnnotate(&page)
+	skip, _ := strconv.ParseInt(r.URL.Query().Get("skip"), 10, 64)
+	page, err := queue.Claim(auth.CurrentUser(r), skip)
 
 	if err == sql.ErrNoRows {
 		err = tmpl.ExecuteTemplate(w, "nothing-to-annotate.go.html", page)
@@ -320,6 +718,10 @@ func (s *Server) NextPageToAnnotate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if errors.Is(err, queue.ErrAnonymous) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -330,15 +732,32 @@ func (s *Server) NextPageToAnnotate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) AnnotatePage(w http.ResponseWriter, r *http.Request) {
-	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
-	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
 
 	var page models.PageView
-	err = db.LoadPage(docId, pageNum, &page)
+	err := db.LoadPage(docId, pageNum, &page)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	loadWorkflow(r, &page)
+	if page.Status == "TRAINING" {
+		lease, ok, err := queue.Lock(page.Id, docId, pageNum, auth.CurrentUser(r))
+		if errors.Is(err, queue.ErrAnonymous) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			page.Lock = &lease
+		}
+	}
 	err = tmpl.ExecuteTemplate(w, "annotate.go.html", page)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -347,12 +766,27 @@ func (s *Server) AnnotatePage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GetPredictions(w http.ResponseWriter, r *http.Request) {
-	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
-	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	required, err := db.RequiredAnnotators(docId, pageNum)
 	if err != nil {
-		http.NotFound(w, r)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if required > 0 {
+		// Annotators of agreement pages work on their own copy, starting from the detector output
+		set, err := db.GetAnnotationSet(docId, pageNum, auth.CurrentUser(r))
+		if err == nil {
+			writeJson(w, http.StatusOK, set.Predictions)
+			return
+		}
+		if err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 	predictions, err := db.GetPredictions(docId, pageNum)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -367,28 +801,23 @@ func (s *Server) GetPredictions(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) SetPredictions(w http.ResponseWriter, r *http.Request) {
-	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
-	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
-	if err != nil {
-		http.NotFound(w, r)
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
 		return
 	}
 	var predictions []models.Prediction
-	err = json.NewDecoder(r.Body).Decode(&predictions)
+	err := json.NewDecoder(r.Body).Decode(&predictions)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	pdfText, err := db.GetPdfPageText(docId, pageNum)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	predictions, ok = s.validatePredictions(w, r, docId, pageNum, predictions)
+	if !ok {
 		return
 	}
-	var htmlText = pipeline.ParseHtmlAndAdjustDetection(&pdfText, &predictions, docId, pageNum)
-	err = db.UpdatePredictionsAndText(docId, pageNum, &predictions, &htmlText)
-	pipeline.DrawBoundingBoxes(docId, pageNum, &predictions, "prediction")
+	status, err := savePredictions(r, docId, pageNum, &predictions)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -407,11 +836,15 @@ func (s *Server) SetPredictions(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) Retry(w http.ResponseWriter, r *http.Request) {
 	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid document id", http.StatusBadRequest)
+		return
+	}
+	err = pipeline.RetryAnnotations(docId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	pipeline.RetryAnnotations(docId)
 
 	doc, err := db.LoadDocument(docId)
 	if err != nil {
@@ -435,6 +868,33 @@ func (s *Server) Retry(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// pageParams reads the document id and page number of the request, the last result is false when the request has been
+// answered with 400.
+func pageParams(w http.ResponseWriter, r *http.Request) (int64, int, bool) {
+	docId, err := strconv.ParseInt(chi.URLParam(r, "documentId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid document id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	pageNum, err := strconv.Atoi(chi.URLParam(r, "pageNum"))
+	if err != nil {
+		http.Error(w, "Invalid page number", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return docId, pageNum, true
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	jsonResp, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonResp)
+}
+
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResp, _ := json.Marshal(s.db.Health())
 	_, _ = w.Write(jsonResp)
@@ -4,19 +4,25 @@ import (
 	"fmt"
 	"net/http"
 	"smart-docs/core/db"
+	"smart-docs/core/sampling"
+	"smart-docs/core/webhooks"
 	"time"
 )
 
 type Server struct {
-	port int
-	db   db.Service
+	port     int
+	db       db.Service
+	sampling sampling.Policy
 }
 
 func NewServer() *http.Server {
 	NewServer := &Server{
-		port: 8080,
-		db:   db.New(),
+		port:     8080,
+		db:       db.New(),
+		sampling: sampling.PolicyFromEnv(),
 	}
+	go sampling.Run(NewServer.sampling, time.Hour)
+	go webhooks.Run(time.Minute)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
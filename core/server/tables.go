@@ -0,0 +1,181 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/queue"
+	"smart-docs/core/tables"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTable returns the grid of the table at {tableIndex} of the page annotations together with the text of its cells.
+func (s *Server) GetTable(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(chi.URLParam(r, "tableIndex"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	table, err := tables.Load(docId, pageNum, index)
+	if errors.Is(err, tables.ErrTableNotFound) || errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, table)
+}
+
+// InsertTableRow adds a row before the row "at", or after the last one when "at" is the row count.
+func (s *Server) InsertTableRow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		At int `json:"at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.editTable(w, r, tables.InsertRow(req.At))
+}
+
+func (s *Server) DeleteTableRow(w http.ResponseWriter, r *http.Request) {
+	row, err := strconv.Atoi(chi.URLParam(r, "row"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.editTable(w, r, tables.DeleteRow(row))
+}
+
+// InsertTableColumn adds a column before the column "at", or after the last one when "at" is the column count.
+func (s *Server) InsertTableColumn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		At int `json:"at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.editTable(w, r, tables.InsertColumn(req.At))
+}
+
+func (s *Server) DeleteTableColumn(w http.ResponseWriter, r *http.Request) {
+	col, err := strconv.Atoi(chi.URLParam(r, "col"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.editTable(w, r, tables.DeleteColumn(col))
+}
+
+// MergeTableCells joins the cells of the area starting at "row" and "col" and spanning "rowspan" rows and "colspan" columns.
+func (s *Server) MergeTableCells(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Row     int `json:"row"`
+		Col     int `json:"col"`
+		Rowspan int `json:"rowspan"`
+		Colspan int `json:"colspan"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.editTable(w, r, tables.MergeCells(req.Row, req.Col, max(req.Rowspan, 1), max(req.Colspan, 1)))
+}
+
+func (s *Server) SplitTableCell(w http.ResponseWriter, r *http.Request) {
+	row, col, ok := cellParams(w, r)
+	if !ok {
+		return
+	}
+	s.editTable(w, r, tables.SplitCell(row, col))
+}
+
+// SetTableCellText replaces the text of a cell by "text", null goes back to the words found in the cell.
+func (s *Server) SetTableCellText(w http.ResponseWriter, r *http.Request) {
+	row, col, ok := cellParams(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Text *string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.editTable(w, r, tables.SetCellText(row, col, req.Text))
+}
+
+// SetTableHeader makes the first "rows" rows of the table its header.
+func (s *Server) SetTableHeader(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rows int `json:"rows"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.editTable(w, r, tables.SetHeaderRows(req.Rows))
+}
+
+// cellParams reads the row and column of a cell from the url, a missing cell is answered with 404.
+func cellParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	row, err := strconv.Atoi(chi.URLParam(r, "row"))
+	if err != nil {
+		http.NotFound(w, r)
+		return 0, 0, false
+	}
+	col, err := strconv.Atoi(chi.URLParam(r, "col"))
+	if err != nil {
+		http.NotFound(w, r)
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+// editTable applies a table operation for the current user and answers with the edited table.
+// Pages leased by another annotator are answered with 409.
+func (s *Server) editTable(w http.ResponseWriter, r *http.Request, operation tables.Operation) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(chi.URLParam(r, "tableIndex"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := auth.CurrentUser(r)
+	err = queue.CheckLease(docId, pageNum, user)
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	if _, _, err := db.GetPageSize(docId, pageNum); errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	table, err := tables.Edit(docId, pageNum, index, user, operation)
+	switch {
+	case errors.Is(err, tables.ErrTableNotFound), errors.Is(err, tables.ErrCellNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, tables.ErrInvalidEdit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeJson(w, http.StatusOK, table)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+	"smart-docs/core/queue"
+	"smart-docs/core/validation"
+	"strconv"
+)
+
+type validationError struct {
+	Error    string               `json:"error"`
+	Problems []validation.Problem `json:"problems"`
+}
+
+// validatePredictions checks an annotation payload with checkPredictions. Rejected payloads are answered with 422
+// listing every problem, the second result is false when the request has been answered.
+func (s *Server) validatePredictions(w http.ResponseWriter, r *http.Request, docId int64, pageNum int, predictions []models.Prediction) ([]models.Prediction, bool) {
+	predictions, problems, err := checkPredictions(r, docId, pageNum, predictions)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(problems) > 0 {
+		writeJson(w, http.StatusUnprocessableEntity, validationError{Error: "invalid annotations", Problems: problems})
+		return nil, false
+	}
+	return predictions, true
+}
+
+// checkPredictions checks an annotation payload against the page size and the label schema of the document.
+// With the "fix" query parameter boxes are clamped and normalised first. It fails with sql.ErrNoRows for missing pages.
+func checkPredictions(r *http.Request, docId int64, pageNum int, predictions []models.Prediction) ([]models.Prediction, []validation.Problem, error) {
+	width, height, err := db.GetPageSize(docId, pageNum)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := db.DocumentLabelSchema(docId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fix, _ := strconv.ParseBool(r.URL.Query().Get("fix")); fix {
+		predictions = validation.Fix(predictions, float32(width), float32(height))
+	}
+	return predictions, validation.Validate(predictions, schema, float32(width), float32(height)), nil
+}
+
+// savePredictions stores the annotations of the current user: as their annotation set on pages selected for agreement,
+// otherwise as the annotations of the page, which must not be leased by someone else. Without leases, agreement pages
+// are annotated directly. Failures come with the status they are answered with.
+func savePredictions(r *http.Request, docId int64, pageNum int, predictions *[]models.Prediction) (int, error) {
+	user := auth.CurrentUser(r)
+	leased, err := queue.Leased(user)
+	if err != nil {
+		return leaseErrorStatus(err), err
+	}
+	required, err := db.RequiredAnnotators(docId, pageNum)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if required > 0 && leased {
+		err = queue.SaveAnnotationSet(docId, pageNum, user, *predictions)
+	} else if err = queue.CheckLease(docId, pageNum, user); err != nil {
+		return leaseErrorStatus(err), err
+	} else {
+		err = pipeline.ApplyPredictions(docId, pageNum, predictions, user)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/webhooks"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Delivery logs list this many deliveries unless the client asks for fewer, or more up to maxApiLimit.
+const defaultDeliveryLimit = 50
+
+// ApiListWebhooks lists the webhooks without their secrets.
+func (s *Server) ApiListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	list, err := db.ListWebhooks()
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	writeJson(w, http.StatusOK, list)
+}
+
+// ApiCreateWebhook registers a webhook for a list of events. A secret is generated unless one is given, it is only
+// returned in this response.
+func (s *Server) ApiCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var webhook models.Webhook
+	err := json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = webhooks.Check(webhook)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook.Secret = strings.TrimSpace(webhook.Secret)
+	if webhook.Secret == "" {
+		webhook.Secret, err = webhooks.NewSecret()
+		if err != nil {
+			writeApiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	webhook.CreatedBy = auth.CurrentUser(r)
+	webhook.CreatedAt = time.Now()
+	_, err = db.StoreWebhook(&webhook)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/webhooks/%d", apiPrefix, webhook.Id))
+	writeJson(w, http.StatusCreated, webhook)
+}
+
+func (s *Server) ApiGetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := webhookParam(w, r)
+	if !ok {
+		return
+	}
+	webhook.Secret = ""
+	writeJson(w, http.StatusOK, webhook)
+}
+
+// ApiDeleteWebhook removes a webhook with its delivery log.
+func (s *Server) ApiDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := webhookParam(w, r)
+	if !ok {
+		return
+	}
+	err := db.DeleteWebhook(webhook.Id)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApiListDeliveries returns the delivery log of a webhook, the newest first, up to "limit" deliveries.
+func (s *Server) ApiListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := webhookParam(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveryLimit
+	if r.URL.Query().Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > maxApiLimit {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxApiLimit))
+			return
+		}
+	}
+	deliveries, err := db.ListDeliveries(webhook.Id, limit)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJson(w, http.StatusOK, deliveries)
+}
+
+func (s *Server) ApiGetDelivery(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := webhookParam(w, r)
+	if !ok {
+		return
+	}
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	delivery, err := db.LoadDelivery(webhook.Id, deliveryId)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJson(w, http.StatusOK, delivery)
+}
+
+// ApiRedeliver sends the payload of a delivery again, as a new delivery.
+func (s *Server) ApiRedeliver(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := webhookParam(w, r)
+	if !ok {
+		return
+	}
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	redeliveryId, err := webhooks.Redeliver(webhook.Id, deliveryId)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	delivery, err := db.LoadDelivery(webhook.Id, redeliveryId)
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/webhooks/%d/deliveries/%d", apiPrefix, webhook.Id, redeliveryId))
+	writeJson(w, http.StatusAccepted, delivery)
+}
+
+// requireAdmin answers with 403 unless the user is an admin, webhooks send data of every project.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.IsAdmin(auth.CurrentUser(r)) {
+		writeApiError(w, http.StatusForbidden, "only admins can manage webhooks")
+		return false
+	}
+	return true
+}
+
+// webhookParam loads the webhook of the request for an admin, the second result is false when the request has been
+// answered.
+func webhookParam(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+	if !requireAdmin(w, r) {
+		return models.Webhook{}, false
+	}
+	webhookId, err := strconv.ParseInt(chi.URLParam(r, "webhookId"), 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "webhook not found")
+		return models.Webhook{}, false
+	}
+	webhook, err := db.LoadWebhook(webhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeApiError(w, http.StatusNotFound, "webhook not found")
+		return webhook, false
+	}
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return webhook, false
+	}
+	return webhook, true
+}
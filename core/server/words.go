@@ -0,0 +1,182 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/corrections"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/queue"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// wordRequest is the body of word corrections. The box is optional when editing a word, all four coordinates are
+// required when it is given or when a word is added.
+type wordRequest struct {
+	Text string   `json:"text"`
+	X0   *float32 `json:"x0"`
+	Y0   *float32 `json:"y0"`
+	X1   *float32 `json:"x1"`
+	Y1   *float32 `json:"y1"`
+}
+
+func (req wordRequest) rect() (*models.Rect, error) {
+	if req.X0 == nil && req.Y0 == nil && req.X1 == nil && req.Y1 == nil {
+		return nil, nil
+	}
+	if req.X0 == nil || req.Y0 == nil || req.X1 == nil || req.Y1 == nil {
+		return nil, errors.New("x0, y0, x1 and y1 are required for the box")
+	}
+	return &models.Rect{X0: *req.X0, Y0: *req.Y0, X1: *req.X1, Y1: *req.Y1}, nil
+}
+
+func (s *Server) ListWords(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	text, err := db.LoadPageText(docId, pageNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, pageWords(text.Words))
+}
+
+// ListWordEdits returns the audit log of word corrections of a page, with the replaced words kept as they were.
+func (s *Server) ListWordEdits(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	edits, err := db.ListWordEdits(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, edits)
+}
+
+func (s *Server) AddWord(w http.ResponseWriter, r *http.Request) {
+	var req wordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rect, err := req.rect()
+	if err == nil && rect == nil {
+		err = errors.New("a box is required for new words")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.correctWords(w, r, corrections.AddWord(models.WordData{Rect: *rect, Text: req.Text}))
+}
+
+func (s *Server) EditWord(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "wordIndex"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var req wordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rect, err := req.rect()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.correctWords(w, r, corrections.EditWord(index, req.Text, rect))
+}
+
+func (s *Server) DeleteWord(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "wordIndex"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.correctWords(w, r, corrections.DeleteWord(index))
+}
+
+// MergeWords joins the neighbouring words listed in "indices" into one, "text" overrides the joined text.
+func (s *Server) MergeWords(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Indices []int  `json:"indices"`
+		Text    string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.correctWords(w, r, corrections.MergeWords(req.Indices, req.Text))
+}
+
+// SplitWord replaces a word by the texts listed in "parts".
+func (s *Server) SplitWord(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "wordIndex"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Parts []string `json:"parts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.correctWords(w, r, corrections.SplitWord(index, req.Parts))
+}
+
+// correctWords applies a word correction for the current user and answers with the corrected words of the page.
+// Pages leased by another annotator and words corrected concurrently are answered with 409.
+func (s *Server) correctWords(w http.ResponseWriter, r *http.Request, operation corrections.Operation) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	user := auth.CurrentUser(r)
+	err := queue.CheckLease(docId, pageNum, user)
+	if err != nil {
+		http.Error(w, err.Error(), leaseErrorStatus(err))
+		return
+	}
+	if _, err := db.LoadPageText(docId, pageNum); errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	words, err := corrections.Correct(docId, pageNum, user, operation)
+	switch {
+	case errors.Is(err, corrections.ErrWordNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, corrections.ErrInvalidEdit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Words were changed in the meantime, reload the page", http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeJson(w, http.StatusOK, pageWords(words))
+	}
+}
+
+func pageWords(words []models.WordData) []models.PageWord {
+	result := make([]models.PageWord, len(words))
+	for i, word := range words {
+		result[i] = models.PageWord{Rect: word.Rect, Index: i, Text: word.Text}
+	}
+	return result
+}
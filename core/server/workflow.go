@@ -0,0 +1,96 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"smart-docs/core/auth"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/workflow"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	newStatus := chi.URLParam(r, "newStatus")
+	user := auth.CurrentUser(r)
+
+	err := workflow.Change(docId, pageNum, newStatus, user, auth.IsReviewer(user), strings.TrimSpace(r.FormValue("reason")))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, workflow.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, workflow.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, workflow.ErrStatusChanged):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var pageView models.PageView
+	err = db.LoadPage(docId, pageNum, &pageView)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loadWorkflow(r, &pageView)
+	err = tmpl.ExecuteTemplate(w, "page-status.go.html", pageView)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) ListTransitions(w http.ResponseWriter, r *http.Request) {
+	docId, pageNum, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	transitions, err := db.ListTransitions(docId, pageNum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, transitions)
+}
+
+// loadWorkflow adds the status changes available to the current user, whether the page is annotated independently
+// and why it was last sent back, if it was.
+func loadWorkflow(r *http.Request, page *models.PageView) {
+	user := auth.CurrentUser(r)
+	page.CanReview = auth.IsReviewer(user)
+	page.Transitions = workflow.Allowed(page.Status, page.CanReview)
+	required, err := db.RequiredAnnotators(page.DocumentId, page.PageNum)
+	if err != nil {
+		log.Println(fmt.Sprintf("Could not load agreement selection: %v", err))
+	}
+	page.RequiredAnnotators = required
+	if page.Status != "TRAINING" {
+		return
+	}
+	change, err := db.LastTransition(page.DocumentId, page.PageNum, "TRAINING")
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(fmt.Sprintf("Could not load page transitions: %v", err))
+		}
+		return
+	}
+	if change.Reason != "" && (change.From == "REVIEW" || change.From == "VALIDATION") {
+		page.Rejection = &change
+	}
+}
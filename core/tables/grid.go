@@ -0,0 +1,261 @@
+package tables
+
+import (
+	"fmt"
+	"slices"
+	"smart-docs/core/models"
+	"strings"
+)
+
+// Operation changes the structure or content of a table grid.
+type Operation func(grid *models.TableGrid) error
+
+// InsertRow adds an empty row before the row at the given index, or after the last row when it equals the row count.
+// The new row takes half of the row it is inserted into, words of that half move to the new cells.
+func InsertRow(at int) Operation {
+	return func(grid *models.TableGrid) error {
+		if at < 0 || at > len(grid.Rows) || len(grid.Rows) == 0 {
+			return fmt.Errorf("%w: cannot insert a row at %d", ErrInvalidEdit, at)
+		}
+		if at < grid.HeaderRows {
+			grid.HeaderRows++
+		}
+		insertRow(grid, at)
+		return nil
+	}
+}
+
+// DeleteRow removes a row with its cells, cells spanning it lose a row.
+func DeleteRow(row int) Operation {
+	return func(grid *models.TableGrid) error {
+		if row < 0 || row >= len(grid.Rows) {
+			return fmt.Errorf("%w: row %d", ErrCellNotFound, row)
+		}
+		if len(grid.Rows) == 1 {
+			return fmt.Errorf("%w: cannot delete the last row", ErrInvalidEdit)
+		}
+		if row < grid.HeaderRows {
+			grid.HeaderRows--
+		}
+		deleteRow(grid, row)
+		return nil
+	}
+}
+
+// InsertColumn adds an empty column before the column at the given index, or after the last one, like InsertRow.
+func InsertColumn(at int) Operation {
+	return func(grid *models.TableGrid) error {
+		if at < 0 || at > len(grid.Columns) || len(grid.Columns) == 0 {
+			return fmt.Errorf("%w: cannot insert a column at %d", ErrInvalidEdit, at)
+		}
+		transpose(grid)
+		insertRow(grid, at)
+		transpose(grid)
+		return nil
+	}
+}
+
+// DeleteColumn removes a column with its cells, cells spanning it lose a column.
+func DeleteColumn(col int) Operation {
+	return func(grid *models.TableGrid) error {
+		if col < 0 || col >= len(grid.Columns) {
+			return fmt.Errorf("%w: column %d", ErrCellNotFound, col)
+		}
+		if len(grid.Columns) == 1 {
+			return fmt.Errorf("%w: cannot delete the last column", ErrInvalidEdit)
+		}
+		transpose(grid)
+		deleteRow(grid, col)
+		transpose(grid)
+		return nil
+	}
+}
+
+// MergeCells joins the cells of an area into one. Cells must lie entirely inside the area, the merged cell keeps the
+// label of the top left one and the texts set on the merged cells.
+func MergeCells(row int, col int, rowspan int, colspan int) Operation {
+	return func(grid *models.TableGrid) error {
+		if row < 0 || col < 0 || rowspan < 1 || colspan < 1 || row+rowspan > len(grid.Rows) || col+colspan > len(grid.Columns) {
+			return fmt.Errorf("%w: area is outside of the table", ErrInvalidEdit)
+		}
+		if rowspan == 1 && colspan == 1 {
+			return fmt.Errorf("%w: area must cover more than one cell", ErrInvalidEdit)
+		}
+		merged := models.GridCell{Row: row, Col: col, Rowspan: rowspan, Colspan: colspan}
+		var texts []string
+		first := -1
+		var kept []models.GridCell
+		for _, cell := range grid.Cells {
+			if !overlaps(cell, merged) {
+				kept = append(kept, cell)
+				continue
+			}
+			if !contains(merged, cell) {
+				return fmt.Errorf("%w: cell at row %d, column %d reaches outside of the area", ErrInvalidEdit, cell.Row, cell.Col)
+			}
+			if first < 0 {
+				first = len(kept)
+			}
+			if merged.Label == "" || (cell.Row == row && cell.Col == col) {
+				merged.Label = cell.Label
+			}
+			if cell.Text != nil && *cell.Text != "" {
+				texts = append(texts, *cell.Text)
+			}
+		}
+		if first < 0 {
+			first = len(kept)
+			merged.Label = defaultLabel(grid)
+		}
+		if len(texts) > 0 {
+			text := strings.Join(texts, " ")
+			merged.Text = &text
+		}
+		grid.Cells = slices.Insert(kept, first, merged)
+		return nil
+	}
+}
+
+// SplitCell breaks a merged cell back into a cell per row and column it covers.
+func SplitCell(row int, col int) Operation {
+	return func(grid *models.TableGrid) error {
+		i := cellAt(grid, row, col)
+		if i < 0 {
+			return fmt.Errorf("%w: no cell at row %d, column %d", ErrCellNotFound, row, col)
+		}
+		cell := grid.Cells[i]
+		if cell.Rowspan == 1 && cell.Colspan == 1 {
+			return fmt.Errorf("%w: cell at row %d, column %d is not merged", ErrInvalidEdit, row, col)
+		}
+		var split []models.GridCell
+		for r := cell.Row; r < cell.Row+cell.Rowspan; r++ {
+			for c := cell.Col; c < cell.Col+cell.Colspan; c++ {
+				part := models.GridCell{Row: r, Col: c, Rowspan: 1, Colspan: 1, Label: cell.Label}
+				if r == cell.Row && c == cell.Col {
+					part.Text = cell.Text
+				}
+				split = append(split, part)
+			}
+		}
+		grid.Cells = slices.Replace(grid.Cells, i, i+1, split...)
+		return nil
+	}
+}
+
+// SetHeaderRows makes the first rows of the table its header.
+func SetHeaderRows(rows int) Operation {
+	return func(grid *models.TableGrid) error {
+		if rows < 0 || rows > len(grid.Rows) {
+			return fmt.Errorf("%w: the table has %d rows", ErrInvalidEdit, len(grid.Rows))
+		}
+		grid.HeaderRows = rows
+		return nil
+	}
+}
+
+// SetCellText replaces the text of the cell covering a row and column, nil goes back to the words found in the cell.
+func SetCellText(row int, col int, text *string) Operation {
+	return func(grid *models.TableGrid) error {
+		i := cellAt(grid, row, col)
+		if i < 0 {
+			return fmt.Errorf("%w: no cell at row %d, column %d", ErrCellNotFound, row, col)
+		}
+		grid.Cells[i].Text = text
+		return nil
+	}
+}
+
+// insertRow splits the row the new one is inserted into in two halves. Cells of that row stay in the half which is not
+// new, cells spanning more rows cover both.
+func insertRow(grid *models.TableGrid, at int) {
+	split := min(at, len(grid.Rows)-1)
+	band := grid.Rows[split]
+	middle := (band.Start + band.End) / 2
+	grid.Rows = slices.Replace(grid.Rows, split, split+1,
+		models.Band{Start: band.Start, End: middle},
+		models.Band{Start: middle, End: band.End},
+	)
+	added := split
+	if at == len(grid.Rows)-1 {
+		added = split + 1
+	}
+
+	for i := range grid.Cells {
+		cell := &grid.Cells[i]
+		switch {
+		case cell.Row > split:
+			cell.Row++
+		case cell.Row+cell.Rowspan <= split:
+		case cell.Row == split && cell.Rowspan == 1:
+			if added == split {
+				cell.Row++
+			}
+		default:
+			cell.Rowspan++
+		}
+	}
+
+	label := defaultLabel(grid)
+	for col := range grid.Columns {
+		if cellAt(grid, added, col) < 0 {
+			grid.Cells = append(grid.Cells, models.GridCell{Row: added, Col: col, Rowspan: 1, Colspan: 1, Label: label})
+		}
+	}
+}
+
+func deleteRow(grid *models.TableGrid, row int) {
+	grid.Rows = slices.Delete(grid.Rows, row, row+1)
+	var kept []models.GridCell
+	for _, cell := range grid.Cells {
+		switch {
+		case cell.Row > row:
+			cell.Row--
+		case cell.Row+cell.Rowspan <= row:
+		case cell.Rowspan == 1:
+			continue
+		default:
+			cell.Rowspan--
+		}
+		kept = append(kept, cell)
+	}
+	grid.Cells = kept
+}
+
+// transpose swaps rows and columns, so column operations can be done as row operations.
+func transpose(grid *models.TableGrid) {
+	grid.Rows, grid.Columns = grid.Columns, grid.Rows
+	for i := range grid.Cells {
+		cell := &grid.Cells[i]
+		cell.Row, cell.Col = cell.Col, cell.Row
+		cell.Rowspan, cell.Colspan = cell.Colspan, cell.Rowspan
+	}
+}
+
+// cellAt returns the index of the cell covering a row and column, -1 when there is none.
+func cellAt(grid *models.TableGrid, row int, col int) int {
+	return slices.IndexFunc(grid.Cells, func(cell models.GridCell) bool {
+		return overlaps(cell, models.GridCell{Row: row, Col: col, Rowspan: 1, Colspan: 1})
+	})
+}
+
+func overlaps(a models.GridCell, b models.GridCell) bool {
+	return a.Row < b.Row+b.Rowspan && b.Row < a.Row+a.Rowspan && a.Col < b.Col+b.Colspan && b.Col < a.Col+a.Colspan
+}
+
+func contains(area models.GridCell, cell models.GridCell) bool {
+	return cell.Row >= area.Row && cell.Row+cell.Rowspan <= area.Row+area.Rowspan &&
+		cell.Col >= area.Col && cell.Col+cell.Colspan <= area.Col+area.Colspan
+}
+
+// defaultLabel is the label given to new cells, the most common one of the table.
+func defaultLabel(grid *models.TableGrid) string {
+	counts := map[string]int{}
+	label := "cell"
+	for _, cell := range grid.Cells {
+		counts[cell.Label]++
+		if counts[cell.Label] > counts[label] {
+			label = cell.Label
+		}
+	}
+	return label
+}
@@ -0,0 +1,191 @@
+package tables
+
+import (
+	"errors"
+	"reflect"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func cell(row, col, rowspan, colspan int, text ...string) models.GridCell {
+	c := models.GridCell{Row: row, Col: col, Rowspan: rowspan, Colspan: colspan, Label: "cell"}
+	if len(text) > 0 {
+		c.Text = &text[0]
+	}
+	return c
+}
+
+// square is a table of 2 rows and 2 columns with a header row.
+func square() models.TableGrid {
+	return models.TableGrid{
+		Rows:       []models.Band{{Start: 0, End: 10}, {Start: 10, End: 20}},
+		Columns:    []models.Band{{Start: 0, End: 50}, {Start: 50, End: 100}},
+		HeaderRows: 1,
+		Cells:      []models.GridCell{cell(0, 0, 1, 1, "a"), cell(0, 1, 1, 1), cell(1, 0, 1, 1, "b"), cell(1, 1, 1, 1)},
+	}
+}
+
+// merged is square with its first column merged into one cell.
+func merged() models.TableGrid {
+	grid := square()
+	grid.Cells = []models.GridCell{cell(0, 0, 2, 1, "a b"), cell(0, 1, 1, 1), cell(1, 1, 1, 1)}
+	return grid
+}
+
+func TestOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		grid      models.TableGrid
+		operation Operation
+		want      models.TableGrid
+	}{
+		{
+			"insert first row",
+			square(),
+			InsertRow(0),
+			models.TableGrid{
+				Rows:       []models.Band{{Start: 0, End: 5}, {Start: 5, End: 10}, {Start: 10, End: 20}},
+				Columns:    square().Columns,
+				HeaderRows: 2,
+				Cells:      []models.GridCell{cell(1, 0, 1, 1, "a"), cell(1, 1, 1, 1), cell(2, 0, 1, 1, "b"), cell(2, 1, 1, 1), cell(0, 0, 1, 1), cell(0, 1, 1, 1)},
+			},
+		},
+		{
+			"insert last row",
+			square(),
+			InsertRow(2),
+			models.TableGrid{
+				Rows:       []models.Band{{Start: 0, End: 10}, {Start: 10, End: 15}, {Start: 15, End: 20}},
+				Columns:    square().Columns,
+				HeaderRows: 1,
+				Cells:      append(square().Cells, cell(2, 0, 1, 1), cell(2, 1, 1, 1)),
+			},
+		},
+		{
+			"insert a row through a merged cell",
+			merged(),
+			InsertRow(1),
+			models.TableGrid{
+				Rows:       []models.Band{{Start: 0, End: 10}, {Start: 10, End: 15}, {Start: 15, End: 20}},
+				Columns:    square().Columns,
+				HeaderRows: 1,
+				Cells:      []models.GridCell{cell(0, 0, 3, 1, "a b"), cell(0, 1, 1, 1), cell(2, 1, 1, 1), cell(1, 1, 1, 1)},
+			},
+		},
+		{
+			"delete the header row",
+			square(),
+			DeleteRow(0),
+			models.TableGrid{
+				Rows:    []models.Band{{Start: 10, End: 20}},
+				Columns: square().Columns,
+				Cells:   []models.GridCell{cell(0, 0, 1, 1, "b"), cell(0, 1, 1, 1)},
+			},
+		},
+		{
+			"delete a row through a merged cell",
+			merged(),
+			DeleteRow(0),
+			models.TableGrid{
+				Rows:    []models.Band{{Start: 10, End: 20}},
+				Columns: square().Columns,
+				Cells:   []models.GridCell{cell(0, 0, 1, 1, "a b"), cell(0, 1, 1, 1)},
+			},
+		},
+		{
+			"insert a column",
+			square(),
+			InsertColumn(1),
+			models.TableGrid{
+				Rows:       square().Rows,
+				Columns:    []models.Band{{Start: 0, End: 50}, {Start: 50, End: 75}, {Start: 75, End: 100}},
+				HeaderRows: 1,
+				Cells:      []models.GridCell{cell(0, 0, 1, 1, "a"), cell(0, 2, 1, 1), cell(1, 0, 1, 1, "b"), cell(1, 2, 1, 1), cell(0, 1, 1, 1), cell(1, 1, 1, 1)},
+			},
+		},
+		{
+			"delete a column",
+			square(),
+			DeleteColumn(0),
+			models.TableGrid{
+				Rows:       square().Rows,
+				Columns:    []models.Band{{Start: 50, End: 100}},
+				HeaderRows: 1,
+				Cells:      []models.GridCell{cell(0, 0, 1, 1), cell(1, 0, 1, 1)},
+			},
+		},
+		{"merge a column", square(), MergeCells(0, 0, 2, 1), merged()},
+		{
+			"merge every cell",
+			square(),
+			MergeCells(0, 0, 2, 2),
+			models.TableGrid{Rows: square().Rows, Columns: square().Columns, HeaderRows: 1, Cells: []models.GridCell{cell(0, 0, 2, 2, "a b")}},
+		},
+		{
+			"split a merged cell",
+			merged(),
+			SplitCell(1, 0),
+			models.TableGrid{
+				Rows:       square().Rows,
+				Columns:    square().Columns,
+				HeaderRows: 1,
+				Cells:      []models.GridCell{cell(0, 0, 1, 1, "a b"), cell(1, 0, 1, 1), cell(0, 1, 1, 1), cell(1, 1, 1, 1)},
+			},
+		},
+		{
+			"set cell text through a merged cell",
+			merged(),
+			SetCellText(1, 0, nil),
+			models.TableGrid{Rows: square().Rows, Columns: square().Columns, HeaderRows: 1, Cells: []models.GridCell{cell(0, 0, 2, 1), cell(0, 1, 1, 1), cell(1, 1, 1, 1)}},
+		},
+		{
+			"set header rows",
+			square(),
+			SetHeaderRows(0),
+			models.TableGrid{Rows: square().Rows, Columns: square().Columns, Cells: square().Cells},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grid := test.grid
+			if err := test.operation(&grid); err != nil {
+				t.Fatalf("operation failed: %v", err)
+			}
+			if !reflect.DeepEqual(grid, test.want) {
+				t.Errorf("grid = %+v, want %+v", grid, test.want)
+			}
+		})
+	}
+}
+
+func TestInvalidOperations(t *testing.T) {
+	oneRow := square()
+	DeleteRow(1)(&oneRow)
+	tests := []struct {
+		name      string
+		grid      models.TableGrid
+		operation Operation
+		want      error
+	}{
+		{"insert a row outside of the table", square(), InsertRow(3), ErrInvalidEdit},
+		{"insert a column before the table", square(), InsertColumn(-1), ErrInvalidEdit},
+		{"delete a missing row", square(), DeleteRow(2), ErrCellNotFound},
+		{"delete the last row", oneRow, DeleteRow(0), ErrInvalidEdit},
+		{"delete a missing column", square(), DeleteColumn(2), ErrCellNotFound},
+		{"merge one cell", square(), MergeCells(1, 1, 1, 1), ErrInvalidEdit},
+		{"merge outside of the table", square(), MergeCells(1, 1, 2, 1), ErrInvalidEdit},
+		{"merge part of a merged cell", merged(), MergeCells(0, 0, 1, 2), ErrInvalidEdit},
+		{"split a missing cell", square(), SplitCell(2, 0), ErrCellNotFound},
+		{"split a cell which is not merged", square(), SplitCell(0, 1), ErrInvalidEdit},
+		{"set too many header rows", square(), SetHeaderRows(3), ErrInvalidEdit},
+		{"set text of a missing cell", square(), SetCellText(0, 2, nil), ErrCellNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grid := test.grid
+			if err := test.operation(&grid); !errors.Is(err, test.want) {
+				t.Errorf("error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
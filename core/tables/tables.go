@@ -0,0 +1,106 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/pipeline"
+)
+
+var (
+	ErrTableNotFound = errors.New("table not found")
+	ErrCellNotFound  = errors.New("cell not found")
+	ErrInvalidEdit   = errors.New("invalid table edit")
+)
+
+// Table is a table of a page with its grid and the content of its cells. Index is the position of the table among the
+// annotations of the page.
+type Table struct {
+	Index int              `json:"index"`
+	Label string           `json:"label"`
+	BBox  models.Rect      `json:"bbox"`
+	Grid  models.TableGrid `json:"grid"`
+	Cells []Cell           `json:"cells"`
+}
+
+// Cell is a cell of the grid with its box in page coordinates and its text.
+type Cell struct {
+	Row     int         `json:"row"`
+	Col     int         `json:"col"`
+	Rowspan int         `json:"rowspan"`
+	Colspan int         `json:"colspan"`
+	Header  bool        `json:"header"`
+	Label   string      `json:"label"`
+	BBox    models.Rect `json:"bbox"`
+	Text    string      `json:"text"`
+	// Value is the typed value of the text, when the project has a locale and the text is not plain text
+	Value *models.CellValue `json:"value,omitempty"`
+}
+
+// Load returns the table at the given index of the page annotations.
+func Load(docId int64, pageNum int, index int) (Table, error) {
+	predictions, err := pipeline.LoadPredictions(docId, pageNum)
+	if err != nil {
+		return Table{}, err
+	}
+	words, err := db.GetPdfPageText(docId, pageNum)
+	if err != nil {
+		return Table{}, err
+	}
+	grid, cells, ok := pipeline.AnalyzeTable(docId, words, predictions, index)
+	if !ok {
+		return Table{}, fmt.Errorf("%w: no table at index %d", ErrTableNotFound, index)
+	}
+	table := Table{
+		Index: index,
+		Label: predictions[index].Label,
+		BBox:  predictions[index].Rect,
+		Grid:  grid,
+		Cells: make([]Cell, len(cells)),
+	}
+	for i, cell := range cells {
+		table.Cells[i] = Cell{
+			Row:     cell.Row,
+			Col:     cell.Col,
+			Rowspan: cell.Rowspan,
+			Colspan: cell.Colspan,
+			Header:  cell.Header,
+			Label:   cell.Label,
+			BBox:    cell.Rect,
+			Text:    cell.Text,
+			Value:   cell.Value,
+		}
+	}
+	return table, nil
+}
+
+// Edit applies an operation to the grid of a table, replaces the cell boxes of the table by the ones of the grid and
+// stores the annotations of the page as made by the author, which renders the page again.
+func Edit(docId int64, pageNum int, index int, author string, operation Operation) (Table, error) {
+	predictions, err := pipeline.LoadPredictions(docId, pageNum)
+	if err != nil {
+		return Table{}, err
+	}
+	schema, err := db.DocumentLabelSchema(docId)
+	if err != nil {
+		return Table{}, err
+	}
+	if index < 0 || index >= len(predictions) || schema.Renderer(predictions[index].Label) != models.RenderTable {
+		return Table{}, fmt.Errorf("%w: no table at index %d", ErrTableNotFound, index)
+	}
+
+	table := &predictions[index]
+	grid, _ := pipeline.ResolveGrid(*table)
+	err = operation(&grid)
+	if err != nil {
+		return Table{}, err
+	}
+	table.Grid = &grid
+	table.Table = grid.Boxes()
+	err = pipeline.ApplyPredictions(docId, pageNum, &predictions, author)
+	if err != nil {
+		return Table{}, err
+	}
+	return Load(docId, pageNum, index)
+}
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"smart-docs/core/models"
+)
+
+// Problem is a single reason an annotation payload is rejected. Box is the index of the box on the page,
+// Cell the index of the cell inside it when the problem is about a nested box.
+type Problem struct {
+	Box     int    `json:"box"`
+	Cell    *int   `json:"cell,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	location := fmt.Sprintf("box %d", p.Box)
+	if p.Cell != nil {
+		location += fmt.Sprintf(" cell %d", *p.Cell)
+	}
+	return location + ": " + p.Message
+}
+
+// Validate lists every problem of the boxes of a page: labels outside of the schema or nested where the schema does not
+// allow them, inverted or empty rectangles, boxes outside of the page, cells outside of their table and scores outside of 0-1.
+// Coordinates of nested boxes are relative to their parent.
+func Validate(predictions []models.Prediction, schema models.LabelSchema, width float32, height float32) []Problem {
+	problems := []Problem{}
+	for i, prediction := range predictions {
+		report := func(field string, format string, args ...any) {
+			problems = append(problems, Problem{Box: i, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+		checkBox(prediction, models.PageParent, schema, width, height, report)
+		for c, cell := range prediction.Table {
+			report := func(field string, format string, args ...any) {
+				problems = append(problems, Problem{Box: i, Cell: &c, Field: field, Message: fmt.Sprintf(format, args...)})
+			}
+			checkBox(cell, prediction.Label, schema, prediction.Width(), prediction.Height(), report)
+			if len(cell.Table) > 0 {
+				report("table", "cells cannot contain boxes")
+			}
+		}
+	}
+	return problems
+}
+
+func checkBox(prediction models.Prediction, parent string, schema models.LabelSchema, width float32, height float32, report func(field string, format string, args ...any)) {
+	if label, ok := schema.Label(prediction.Label); !ok {
+		report("label", "unknown label %q", prediction.Label)
+	} else if !slices.Contains(label.Parents, parent) {
+		report("label", "label %q is not allowed in %q", prediction.Label, parent)
+	}
+	if prediction.X0 > prediction.X1 {
+		report("x0", "x0 %.1f is right of x1 %.1f", prediction.X0, prediction.X1)
+	} else if prediction.X0 == prediction.X1 {
+		report("x1", "box has no width")
+	}
+	if prediction.Y0 > prediction.Y1 {
+		report("y0", "y0 %.1f is below y1 %.1f", prediction.Y0, prediction.Y1)
+	} else if prediction.Y0 == prediction.Y1 {
+		report("y1", "box has no height")
+	}
+	for _, coordinate := range []struct {
+		field string
+		value float32
+		limit float32
+	}{
+		{"x0", prediction.X0, width},
+		{"x1", prediction.X1, width},
+		{"y0", prediction.Y0, height},
+		{"y1", prediction.Y1, height},
+	} {
+		if coordinate.value < 0 || coordinate.value > coordinate.limit {
+			report(coordinate.field, "%s %.1f is outside of 0-%.0f", coordinate.field, coordinate.value, coordinate.limit)
+		}
+	}
+	if prediction.Score < 0 || prediction.Score > 1 {
+		report("score", "score %.2f is outside of 0-1", prediction.Score)
+	}
+}
+
+// Fix normalises boxes so they pass the geometric checks of Validate where possible: corners are swapped so x0 and y0
+// come first, boxes are clamped to the page and cells to their table, scores to 0-1. Labels are left untouched.
+func Fix(predictions []models.Prediction, width float32, height float32) []models.Prediction {
+	fixed := make([]models.Prediction, len(predictions))
+	for i, prediction := range predictions {
+		prediction.Rect = clampRect(prediction.Rect, width, height)
+		prediction.Score = clamp(prediction.Score, 0, 1)
+		if prediction.Table != nil {
+			cells := make([]models.Prediction, len(prediction.Table))
+			for c, cell := range prediction.Table {
+				cell.Rect = clampRect(cell.Rect, prediction.Width(), prediction.Height())
+				cell.Score = clamp(cell.Score, 0, 1)
+				cells[c] = cell
+			}
+			prediction.Table = cells
+		}
+		fixed[i] = prediction
+	}
+	return fixed
+}
+
+func clampRect(r models.Rect, width float32, height float32) models.Rect {
+	x0, x1 := min(r.X0, r.X1), max(r.X0, r.X1)
+	y0, y1 := min(r.Y0, r.Y1), max(r.Y0, r.Y1)
+	return models.Rect{
+		X0: clamp(x0, 0, width),
+		X1: clamp(x1, 0, width),
+		Y0: clamp(y0, 0, height),
+		Y1: clamp(y1, 0, height),
+	}
+}
+
+func clamp(v float32, low float32, high float32) float32 {
+	return min(max(v, low), high)
+}
@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"reflect"
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+var schema = models.LabelSchema{
+	Labels: []models.Label{
+		{Name: "text", Color: "#000000", Parents: []string{models.PageParent}, Renderer: models.RenderParagraph},
+		{Name: "table", Color: "#0000ff", Parents: []string{models.PageParent}, Renderer: models.RenderTable},
+		{Name: "cell", Color: "#00ff00", Parents: []string{"table"}, Renderer: models.RenderCell},
+	},
+}
+
+func box(label string, x0, y0, x1, y1 float32, cells ...models.Prediction) models.Prediction {
+	return models.Prediction{Rect: models.Rect{X0: x0, Y0: y0, X1: x1, Y1: y1}, Score: 1, Label: label, Table: cells}
+}
+
+func TestValidate(t *testing.T) {
+	withScore := func(prediction models.Prediction, score float32) models.Prediction {
+		prediction.Score = score
+		return prediction
+	}
+	tests := []struct {
+		name        string
+		predictions []models.Prediction
+		want        []string
+	}{
+		{"valid boxes", []models.Prediction{box("text", 10, 10, 50, 20), box("table", 0, 30, 100, 80, box("cell", 0, 0, 50, 25))}, nil},
+		{"unknown label", []models.Prediction{box("figure", 10, 10, 50, 20)}, []string{`box 0: unknown label "figure"`}},
+		{"label not allowed on the page", []models.Prediction{box("cell", 10, 10, 50, 20)}, []string{`box 0: label "cell" is not allowed in "page"`}},
+		{"label not allowed in a table", []models.Prediction{box("table", 0, 0, 100, 100, box("text", 0, 0, 10, 10))}, []string{`box 0 cell 0: label "text" is not allowed in "table"`}},
+		{"inverted box", []models.Prediction{box("text", 50, 20, 10, 10)}, []string{"box 0: x0 50.0 is right of x1 10.0", "box 0: y0 20.0 is below y1 10.0"}},
+		{"empty box", []models.Prediction{box("text", 10, 10, 10, 10)}, []string{"box 0: box has no width", "box 0: box has no height"}},
+		{"box outside of the page", []models.Prediction{box("text", -5, 10, 120, 20)}, []string{"box 0: x0 -5.0 is outside of 0-100", "box 0: x1 120.0 is outside of 0-100"}},
+		{"cell outside of its table", []models.Prediction{box("table", 0, 0, 50, 50, box("cell", 0, 0, 60, 10))}, []string{"box 0 cell 0: x1 60.0 is outside of 0-50"}},
+		{"nested cells", []models.Prediction{box("table", 0, 0, 50, 50, box("cell", 0, 0, 10, 10, box("cell", 0, 0, 5, 5)))}, []string{"box 0 cell 0: cells cannot contain boxes"}},
+		{"score outside of 0-1", []models.Prediction{withScore(box("text", 10, 10, 50, 20), 1.5)}, []string{"box 0: score 1.50 is outside of 0-1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range Validate(test.predictions, schema, 100, 100) {
+				got = append(got, problem.String())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Validate() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name        string
+		predictions []models.Prediction
+		want        []models.Prediction
+	}{
+		{"valid box", []models.Prediction{box("text", 10, 10, 50, 20)}, []models.Prediction{box("text", 10, 10, 50, 20)}},
+		{"inverted box", []models.Prediction{box("text", 50, 20, 10, 10)}, []models.Prediction{box("text", 10, 10, 50, 20)}},
+		{"box outside of the page", []models.Prediction{box("text", -5, 90, 120, 130)}, []models.Prediction{box("text", 0, 90, 100, 100)}},
+		{"cell outside of its table", []models.Prediction{box("table", 0, 0, 50, 50, box("cell", 40, -10, 60, 10))}, []models.Prediction{box("table", 0, 0, 50, 50, box("cell", 40, 0, 50, 10))}},
+		{"unknown label", []models.Prediction{box("figure", 10, 10, 50, 20)}, []models.Prediction{box("figure", 10, 10, 50, 20)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Fix(test.predictions, 100, 100)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Fix() = %+v, want %+v", got, test.want)
+			}
+			if problems := Validate(got, schema, 100, 100); test.predictions[0].Label != "figure" && len(problems) > 0 {
+				t.Errorf("Validate(Fix()) = %v, want no problems", problems)
+			}
+		})
+	}
+}
+
+func TestFixClampsScores(t *testing.T) {
+	predictions := []models.Prediction{box("text", 10, 10, 50, 20), box("text", 10, 30, 50, 40)}
+	predictions[0].Score, predictions[1].Score = -0.5, 2
+	fixed := Fix(predictions, 100, 100)
+	if fixed[0].Score != 0 || fixed[1].Score != 1 {
+		t.Errorf("Fix() scores = %v, %v, want 0, 1", fixed[0].Score, fixed[1].Score)
+	}
+	if predictions[0].Score != -0.5 {
+		t.Errorf("Fix() changed its input")
+	}
+}
@@ -0,0 +1,228 @@
+package values
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"smart-docs/core/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale describes how numbers and dates are written.
+type Locale struct {
+	Decimal string
+	// Group separators, the first one is the usual one
+	Groups []string
+	// DayFirst dates are written day, month, year; otherwise month, day, year
+	DayFirst bool
+}
+
+// Locales supported for typed table cells, by language tag.
+var Locales = map[string]Locale{
+	"en":    {Decimal: ".", Groups: []string{",", " "}},
+	"en-GB": {Decimal: ".", Groups: []string{",", " "}, DayFirst: true},
+	"de":    {Decimal: ",", Groups: []string{".", " ", "'"}, DayFirst: true},
+	"fr":    {Decimal: ",", Groups: []string{" ", "."}, DayFirst: true},
+	"es":    {Decimal: ",", Groups: []string{".", " "}, DayFirst: true},
+	"it":    {Decimal: ",", Groups: []string{".", " "}, DayFirst: true},
+	"nl":    {Decimal: ",", Groups: []string{".", " "}, DayFirst: true},
+	"pl":    {Decimal: ",", Groups: []string{" ", "."}, DayFirst: true},
+	"de-CH": {Decimal: ".", Groups: []string{"'", " "}, DayFirst: true},
+}
+
+// currencies maps symbols and codes found in cells to ISO 4217 codes.
+var currencies = map[string]string{
+	"€": "EUR", "EUR": "EUR",
+	"$": "USD", "US$": "USD", "USD": "USD",
+	"£": "GBP", "GBP": "GBP",
+	"¥": "JPY", "JPY": "JPY",
+	"CHF": "CHF", "Fr.": "CHF",
+	"zł": "PLN", "PLN": "PLN",
+	"kr": "SEK", "SEK": "SEK", "NOK": "NOK", "DKK": "DKK",
+	"CAD": "CAD", "AUD": "AUD", "CZK": "CZK", "Kč": "CZK",
+}
+
+// currencySymbols are tried longest first, so "US$" wins over "$".
+var currencySymbols = func() []string {
+	var symbols []string
+	for symbol := range currencies {
+		symbols = append(symbols, symbol)
+	}
+	slices.SortFunc(symbols, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	return symbols
+}()
+
+var (
+	isoDateRe = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	dateRe    = regexp.MustCompile(`^(\d{1,2})[./-](\d{1,2})[./-](\d{2}|\d{4})$`)
+	digitsRe  = regexp.MustCompile(`^\d+$`)
+)
+
+// Check returns an error for locales without number and date conventions, the empty locale disables typed values.
+func Check(locale string) error {
+	if _, ok := Locales[locale]; locale != "" && !ok {
+		return fmt.Errorf("unsupported locale %q", locale)
+	}
+	return nil
+}
+
+// Parse detects the type of the text of a table cell and normalises it: numbers, amounts with their currency,
+// percentages as fractions and dates as ISO dates. It returns nil for plain text, or when the locale is not supported.
+func Parse(text string, locale string) *models.CellValue {
+	conventions, ok := Locales[locale]
+	text = strings.TrimSpace(text)
+	if !ok || text == "" {
+		return nil
+	}
+	if date, ok := parseDate(text, conventions); ok {
+		return &models.CellValue{Type: models.ValueDate, Date: date}
+	}
+
+	negative := false
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		// Accounting notation
+		negative = true
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+	// Signs are written before or after currency symbols
+	text, negative = trimSign(text, negative)
+	valueType := models.ValueNumber
+	currency := ""
+	if trimmed, ok := trimAffix(text, "%"); ok {
+		valueType = models.ValuePercentage
+		text = trimmed
+	} else {
+		for _, symbol := range currencySymbols {
+			if trimmed, ok := trimAffix(text, symbol); ok {
+				valueType = models.ValueCurrency
+				currency = currencies[symbol]
+				text = trimmed
+				break
+			}
+		}
+	}
+	text, negative = trimSign(text, negative)
+
+	number, ok := parseNumber(text, conventions)
+	if !ok {
+		return nil
+	}
+	if negative {
+		number = -number
+	}
+	if valueType == models.ValuePercentage {
+		number, _ = strconv.ParseFloat(strconv.FormatFloat(number/100, 'g', 15, 64), 64)
+	}
+	return &models.CellValue{Type: valueType, Number: &number, Currency: currency}
+}
+
+// trimSign removes a minus sign written before or after the value and flips negative accordingly.
+func trimSign(text string, negative bool) (string, bool) {
+	for _, sign := range []string{"-", "−", "–"} {
+		if trimmed, ok := strings.CutPrefix(text, sign); ok {
+			return strings.TrimSpace(trimmed), !negative
+		}
+		if trimmed, ok := strings.CutSuffix(text, sign); ok {
+			return strings.TrimSpace(trimmed), !negative
+		}
+	}
+	return text, negative
+}
+
+// trimAffix removes a symbol written before or after the value.
+func trimAffix(text string, symbol string) (string, bool) {
+	if trimmed, ok := strings.CutPrefix(text, symbol); ok {
+		return strings.TrimSpace(trimmed), true
+	}
+	if trimmed, ok := strings.CutSuffix(text, symbol); ok {
+		return strings.TrimSpace(trimmed), true
+	}
+	return text, false
+}
+
+// parseNumber reads a number written with the decimal and group separators of the locale. Groups must have three digits.
+func parseNumber(text string, locale Locale) (float64, bool) {
+	// Thin and non-breaking spaces group digits in some locales
+	text = strings.NewReplacer("\u00a0", " ", "\u202f", " ", "\u2009", " ").Replace(text)
+	integer, fraction, hasFraction := strings.Cut(text, locale.Decimal)
+	if hasFraction && !digitsRe.MatchString(fraction) {
+		return 0, false
+	}
+	integer = strings.TrimSpace(integer)
+	if integer == "" {
+		if !hasFraction {
+			// A sign or a symbol alone
+			return 0, false
+		}
+		integer = "0"
+	}
+	if !digitsRe.MatchString(integer) {
+		grouped := false
+		for _, group := range locale.Groups {
+			parts := strings.Split(integer, group)
+			if len(parts) < 2 || !groupsOfThree(parts) {
+				continue
+			}
+			integer = strings.Join(parts, "")
+			grouped = true
+			break
+		}
+		if !grouped {
+			return 0, false
+		}
+	}
+	normalised := integer
+	if hasFraction {
+		normalised += "." + fraction
+	}
+	number, err := strconv.ParseFloat(normalised, 64)
+	return number, err == nil
+}
+
+func groupsOfThree(parts []string) bool {
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if !digitsRe.MatchString(part) || (i > 0 && len(part) != 3) || len(part) > 3 {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDate(text string, locale Locale) (string, bool) {
+	var year, month, day int
+	if match := isoDateRe.FindStringSubmatch(text); match != nil {
+		year, month, day = atoi(match[1]), atoi(match[2]), atoi(match[3])
+	} else if match := dateRe.FindStringSubmatch(text); match != nil {
+		day, month = atoi(match[1]), atoi(match[2])
+		if !locale.DayFirst {
+			day, month = month, day
+		}
+		year = atoi(match[3])
+		if len(match[3]) == 2 {
+			year += 2000
+			if year > time.Now().Year()+20 {
+				year -= 100
+			}
+		}
+	} else {
+		return "", false
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return "", false
+	}
+	return date.Format(time.DateOnly), true
+}
+
+func atoi(digits string) int {
+	value, _ := strconv.Atoi(digits)
+	return value
+}
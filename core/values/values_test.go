@@ -0,0 +1,102 @@
+package values
+
+import (
+	"reflect"
+	"smart-docs/core/models"
+	"strconv"
+	"testing"
+)
+
+func number(value float64) *models.CellValue {
+	return &models.CellValue{Type: models.ValueNumber, Number: &value}
+}
+
+func amount(value float64, currency string) *models.CellValue {
+	return &models.CellValue{Type: models.ValueCurrency, Number: &value, Currency: currency}
+}
+
+func percentage(value float64) *models.CellValue {
+	return &models.CellValue{Type: models.ValuePercentage, Number: &value}
+}
+
+func date(value string) *models.CellValue {
+	return &models.CellValue{Type: models.ValueDate, Date: value}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text   string
+		locale string
+		want   *models.CellValue
+	}{
+		{"42", "en", number(42)},
+		{" 3.14 ", "en", number(3.14)},
+		{".5", "en", number(0.5)},
+		{"1,234,567.89", "en", number(1234567.89)},
+		{"1 234", "en", number(1234)},
+		{"1.234.567,89", "de", number(1234567.89)},
+		{"1'234.50", "de-CH", number(1234.5)},
+		{"1 234,5", "fr", number(1234.5)},
+		{"1,5", "en", nil},
+		{"12,34,567", "en", nil},
+		{"1.2.3", "en", nil},
+		{"-12", "en", number(-12)},
+		{"12-", "en", number(-12)},
+		{"−12", "en", number(-12)},
+		{"(1,200.00)", "en", number(-1200)},
+		{"$1,200.50", "en", amount(1200.5, "USD")},
+		{"US$ 5", "en", amount(5, "USD")},
+		{"1.200,50 €", "de", amount(1200.5, "EUR")},
+		{"-€3", "en", amount(-3, "EUR")},
+		{"(CHF 20)", "de-CH", amount(-20, "CHF")},
+		{"12.5%", "en", percentage(0.125)},
+		{"7 %", "fr", percentage(0.07)},
+		{"-0,5%", "de", percentage(-0.005)},
+		{"2024-02-29", "en", date("2024-02-29")},
+		{"03/04/2024", "en", date("2024-03-04")},
+		{"03/04/2024", "en-GB", date("2024-04-03")},
+		{"31.12.23", "de", date("2023-12-31")},
+		{"2023-02-29", "en", nil},
+		{"31/02/2024", "fr", nil},
+		{"13/01/2024", "en", nil},
+		{"-", "en", nil},
+		{"€", "en", nil},
+		{"%", "en", nil},
+		{"Total", "en", nil},
+		{"", "en", nil},
+		{"12", "", nil},
+		{"12", "xx", nil},
+	}
+	for _, test := range tests {
+		if got := Parse(test.text, test.locale); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q, %q) = %s, want %s", test.text, test.locale, format(got), format(test.want))
+		}
+	}
+}
+
+func format(value *models.CellValue) string {
+	if value == nil {
+		return "nil"
+	}
+	if value.Number != nil {
+		return value.Type + " " + value.Currency + " " + strconv.FormatFloat(*value.Number, 'g', -1, 64)
+	}
+	return value.Type + " " + value.Date
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		locale  string
+		wantErr bool
+	}{
+		{"", false},
+		{"en", false},
+		{"de-CH", false},
+		{"xx", true},
+	}
+	for _, test := range tests {
+		if err := Check(test.locale); (err != nil) != test.wantErr {
+			t.Errorf("Check(%q) = %v, want error %v", test.locale, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Events sent to webhooks.
+const (
+	EventDocumentProcessed = "document.processed"
+	EventDocumentFailed    = "document.failed"
+	EventPageValidated     = "page.validated"
+	EventAnnotationChanged = "annotation.changed"
+)
+
+var Events = []string{EventDocumentProcessed, EventDocumentFailed, EventPageValidated, EventAnnotationChanged}
+
+// Headers of a delivery. The signature is the hex HMAC-SHA256 of "{timestamp}.{body}" with the secret of the webhook.
+const (
+	EventHeader     = "X-Smart-Docs-Event"
+	DeliveryHeader  = "X-Smart-Docs-Delivery"
+	TimestampHeader = "X-Smart-Docs-Timestamp"
+	SignatureHeader = "X-Smart-Docs-Signature"
+)
+
+// A delivery is given up after this many attempts, waiting twice as long after each failure up to maxRetryDelay.
+const (
+	maxAttempts     = 8
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+)
+
+// Deliveries sent per round, the rest waits for the next one.
+const batchSize = 50
+
+// Webhooks sent to at the same time, so a slow endpoint does not hold back the others.
+const workers = 8
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Event is the body of a delivery.
+type Event struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// DocumentEvent is the data of document events.
+type DocumentEvent struct {
+	DocumentId int64  `json:"documentId"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Project    string `json:"project"`
+	PageCount  int    `json:"pageCount"`
+}
+
+// PageEvent is the data of page events, with who validated the page or changed its annotations, "model" when the
+// page was detected again.
+type PageEvent struct {
+	DocumentId int64  `json:"documentId"`
+	PageNum    int    `json:"pageNum"`
+	User       string `json:"user"`
+}
+
+// client refuses to connect to internal addresses, whatever the url of a webhook resolves to when it is sent.
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialControl}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// wake starts a delivery round without waiting for the next one, when events were published.
+var wake = make(chan struct{}, 1)
+
+// Check validates the url and the events of a webhook. Urls of the server itself and of private networks are refused,
+// webhooks must not reach services which are not public. The addresses are checked again when deliveries are sent,
+// host names may resolve differently by then.
+func Check(webhook models.Webhook) error {
+	target, err := url.Parse(webhook.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must not point to the server itself", ErrInvalidWebhook)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: host %q cannot be resolved", ErrInvalidWebhook, host)
+	}
+	for _, address := range addresses {
+		if !public(address) {
+			return fmt.Errorf("%w: url must not point to a loopback, private or link-local address", ErrInvalidWebhook)
+		}
+	}
+	if len(webhook.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, event := range webhook.Events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	return nil
+}
+
+// public tells whether deliveries may be sent to an address: loopback, private, link-local, multicast and unspecified
+// addresses are refused.
+func public(address netip.Addr) bool {
+	address = address.Unmap()
+	return address.IsValid() && !address.IsLoopback() && !address.IsPrivate() && !address.IsLinkLocalUnicast() &&
+		!address.IsLinkLocalMulticast() && !address.IsInterfaceLocalMulticast() && !address.IsMulticast() &&
+		!address.IsUnspecified()
+}
+
+// dialControl refuses connections to addresses which are not public, once host names are resolved.
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhook, addrPort.Addr())
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for signing deliveries.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns the signature header of a delivery body sent at the given unix time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues a delivery of the event for every webhook subscribed to it. Failures are logged, they do not affect
+// what triggered the event.
+func Publish(event string, data any) {
+	payload, err := json.Marshal(Event{Event: event, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		log.Println(fmt.Sprintf("Error serialising webhook event: \n%+v", err))
+		return
+	}
+	queued, err := db.QueueDeliveries(event, payload)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error queueing webhook deliveries: \n%+v", err))
+		return
+	}
+	if queued > 0 {
+		notify()
+	}
+}
+
+// Redeliver queues the payload of a delivery again as a new delivery, sql.ErrNoRows when it does not exist.
+func Redeliver(webhookId int64, deliveryId int64) (int64, error) {
+	redeliveryId, err := db.RedeliverDelivery(webhookId, deliveryId)
+	if err != nil {
+		return 0, err
+	}
+	notify()
+	return redeliveryId, nil
+}
+
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// PublishDocument publishes a document event with the current state of the document.
+func PublishDocument(event string, docId int64) {
+	doc, err := db.LoadDocument(docId)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error loading document for webhooks: \n%+v", err))
+		return
+	}
+	Publish(event, DocumentEvent{
+		DocumentId: doc.Id,
+		Name:       doc.Name,
+		Status:     doc.Status,
+		Project:    doc.Project,
+		PageCount:  doc.PageCount,
+	})
+}
+
+// Run sends pending deliveries as soon as events are published and retries failed ones every interval.
+func Run(interval time.Duration) {
+	for {
+		Deliver()
+		select {
+		case <-wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+var deliverLock sync.Mutex
+
+// Deliver sends the deliveries which are due. Webhooks are sent to concurrently by up to workers senders, the
+// deliveries of a webhook one after the other in the order they were queued.
+func Deliver() {
+	deliverLock.Lock()
+	defer deliverLock.Unlock()
+	for {
+		deliveries, err := db.DueDeliveries(batchSize)
+		if err != nil {
+			log.Println(fmt.Sprintf("Error loading webhook deliveries: \n%+v", err))
+			return
+		}
+		groups := groupByWebhook(deliveries)
+		queue := make(chan []models.WebhookDelivery)
+		var failed atomic.Bool
+		var wg sync.WaitGroup
+		for range min(workers, len(groups)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for pending := range queue {
+					for _, delivery := range pending {
+						err := attempt(delivery)
+						if err != nil {
+							log.Println(fmt.Sprintf("Error recording webhook delivery: \n%+v", err))
+							failed.Store(true)
+							break
+						}
+					}
+				}
+			}()
+		}
+		for _, group := range groups {
+			queue <- group
+		}
+		close(queue)
+		wg.Wait()
+
+		// Deliveries not recorded would be sent again right away
+		if failed.Load() || len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// groupByWebhook splits deliveries by webhook, in the order of their first delivery, keeping the order of each webhook.
+func groupByWebhook(deliveries []models.WebhookDelivery) [][]models.WebhookDelivery {
+	var groups [][]models.WebhookDelivery
+	index := map[int64]int{}
+	for _, delivery := range deliveries {
+		i, ok := index[delivery.WebhookId]
+		if !ok {
+			i = len(groups)
+			index[delivery.WebhookId] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], delivery)
+	}
+	return groups
+}
+
+// attempt sends a delivery and records the outcome, retries are scheduled until the delivery is given up.
+func attempt(delivery models.WebhookDelivery) error {
+	responseStatus, err := send(delivery)
+	status := models.DeliveryDelivered
+	message := ""
+	next := time.Now()
+	if err != nil {
+		message = err.Error()
+		status = models.DeliveryPending
+		next = next.Add(retryDelay(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= maxAttempts {
+			status = models.DeliveryFailed
+		}
+	}
+	return db.RecordAttempt(delivery.Id, status, responseStatus, message, next)
+}
+
+// send posts the payload of a delivery, only 2xx responses are successful.
+func send(delivery models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+	request, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "smart-docs-webhooks")
+	request.Header.Set(EventHeader, delivery.Event)
+	request.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("endpoint answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"smart-docs/core/models"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"page.validated"}`)
+	tests := []struct {
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{"secret", 1700000000, "sha256=dd22ab7435a4dd0b1a8c3ede899c0f999f76e12d2858cb61d95ba1b4bda69950"},
+		{"other", 1700000000, "sha256=14e24f2c2cea3ef76af9f503ede35462c6756f49c5c82886604300fdf60a6dc0"},
+	}
+	for _, test := range tests {
+		if got := Sign(test.secret, test.timestamp, body); got != test.want {
+			t.Errorf("Sign(%q, %d) = %s, want %s", test.secret, test.timestamp, got, test.want)
+		}
+	}
+	if Sign("secret", 1700000001, body) == tests[0].want {
+		t.Errorf("Sign() does not depend on the timestamp")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	events := []string{EventPageValidated}
+	tests := []struct {
+		name    string
+		webhook models.Webhook
+		wantErr bool
+	}{
+		{"public address", models.Webhook{Url: "https://93.184.215.14/hooks", Events: events}, false},
+		{"public address with a port", models.Webhook{Url: "http://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:8443/hooks", Events: events}, false},
+		{"relative url", models.Webhook{Url: "/hooks", Events: events}, true},
+		{"other scheme", models.Webhook{Url: "ftp://93.184.215.14/hooks", Events: events}, true},
+		{"localhost", models.Webhook{Url: "http://localhost:8080/hooks", Events: events}, true},
+		{"localhost subdomain", models.Webhook{Url: "http://api.localhost./hooks", Events: events}, true},
+		{"loopback", models.Webhook{Url: "http://127.0.0.1/hooks", Events: events}, true},
+		{"ipv6 loopback", models.Webhook{Url: "http://[::1]/hooks", Events: events}, true},
+		{"private network", models.Webhook{Url: "http://10.0.0.5/hooks", Events: events}, true},
+		{"link-local", models.Webhook{Url: "http://169.254.169.254/latest/meta-data", Events: events}, true},
+		{"mapped private network", models.Webhook{Url: "http://[::ffff:192.168.1.1]/hooks", Events: events}, true},
+		{"unspecified", models.Webhook{Url: "http://0.0.0.0/hooks", Events: events}, true},
+		{"no events", models.Webhook{Url: "https://93.184.215.14/hooks"}, true},
+		{"unknown event", models.Webhook{Url: "https://93.184.215.14/hooks", Events: []string{"page.deleted"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(test.webhook)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Check() = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidWebhook) {
+				t.Errorf("Check() = %v, want %v", err, ErrInvalidWebhook)
+			}
+		})
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.215.14:443", false},
+		{"127.0.0.1:80", true},
+		{"172.16.0.1:80", true},
+		{"[fe80::1]:80", true},
+		{"[fd00::1]:80", true},
+	}
+	for _, test := range tests {
+		if err := dialControl("tcp", test.address, nil); (err != nil) != test.wantErr {
+			t.Errorf("dialControl(%q) = %v, want error %v", test.address, err, test.wantErr)
+		}
+	}
+}
+
+func TestPublic(t *testing.T) {
+	if public(netip.Addr{}) {
+		t.Errorf("public() accepts the zero address")
+	}
+}
+
+func TestGroupByWebhook(t *testing.T) {
+	delivery := func(id int64, webhookId int64) models.WebhookDelivery {
+		return models.WebhookDelivery{Id: id, WebhookId: webhookId}
+	}
+	deliveries := []models.WebhookDelivery{delivery(1, 7), delivery(2, 3), delivery(3, 7), delivery(4, 9), delivery(5, 3)}
+	want := [][]models.WebhookDelivery{
+		{delivery(1, 7), delivery(3, 7)},
+		{delivery(2, 3), delivery(5, 3)},
+		{delivery(4, 9)},
+	}
+	if got := groupByWebhook(deliveries); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByWebhook() = %v, want %v", got, want)
+	}
+	if got := groupByWebhook(nil); len(got) != 0 {
+		t.Errorf("groupByWebhook(nil) = %v, want no groups", got)
+	}
+}
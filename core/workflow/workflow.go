@@ -0,0 +1,181 @@
+package workflow
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"smart-docs/core/db"
+	"smart-docs/core/models"
+	"smart-docs/core/webhooks"
+	"strings"
+)
+
+var (
+	ErrNotAllowed     = errors.New("status change not allowed")
+	ErrReasonRequired = errors.New("a reason is required")
+	ErrStatusChanged  = errors.New("page status was changed by someone else")
+)
+
+type rule struct {
+	from           string
+	to             string
+	name           string
+	shortcut       string
+	reviewerOnly   bool
+	requiresReason bool
+}
+
+// Pages are predicted by the detector, corrected by annotators in TRAINING, approved by reviewers and then used for
+// training as VALIDATION. Reviewers send pages back to TRAINING with a reason.
+var rules = []rule{
+	{from: "PREDICTION", to: "TRAINING", name: "Annotate", shortcut: "T"},
+	{from: "TRAINING", to: "PREDICTION", name: "Back to predicted"},
+	{from: "TRAINING", to: "REVIEW", name: "Submit for review", shortcut: "R"},
+	{from: "REVIEW", to: "VALIDATION", name: "Approve", shortcut: "D", reviewerOnly: true},
+	{from: "REVIEW", to: "TRAINING", name: "Reject", reviewerOnly: true, requiresReason: true},
+	{from: "VALIDATION", to: "TRAINING", name: "Reopen", reviewerOnly: true, requiresReason: true},
+}
+
+func findRule(from string, to string) (rule, bool) {
+	for _, r := range rules {
+		if r.from == from && r.to == to {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// Allowed lists status changes available from a status to a user with or without the reviewer role.
+func Allowed(status string, reviewer bool) []models.Transition {
+	transitions := []models.Transition{}
+	for _, r := range rules {
+		if r.from != status || (r.reviewerOnly && !reviewer) {
+			continue
+		}
+		transitions = append(transitions, models.Transition{
+			To:             r.to,
+			Name:           r.name,
+			RequiresReason: r.requiresReason,
+			Shortcut:       r.shortcut,
+		})
+	}
+	return transitions
+}
+
+// Change moves a page to a new status when the workflow allows it to the user.
+// A rejected page goes back to the queue of the annotator who submitted it.
+func Change(docId int64, pageNum int, to string, user string, reviewer bool, reason string) error {
+	from, err := db.GetPageStatus(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	err = check(docId, pageNum, from, to, user, reviewer, reason)
+	if err != nil {
+		return err
+	}
+	err = db.ChangeStatus(docId, pageNum, from, to, user, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStatusChanged
+	}
+	if err != nil {
+		return err
+	}
+	afterChange(docId, pageNum, from, to, user)
+	return nil
+}
+
+// Check fails like Change would, without moving the page, for changes made along with other edits of the page.
+func Check(docId int64, pageNum int, to string, user string, reviewer bool, reason string) error {
+	from, err := db.GetPageStatus(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	return check(docId, pageNum, from, to, user, reviewer, reason)
+}
+
+func check(docId int64, pageNum int, from string, to string, user string, reviewer bool, reason string) error {
+	r, ok := findRule(from, to)
+	if !ok {
+		return fmt.Errorf("%w: %s to %s", ErrNotAllowed, from, to)
+	}
+	if r.reviewerOnly && !reviewer {
+		return fmt.Errorf("%w: only reviewers can %s pages", ErrNotAllowed, strings.ToLower(r.name))
+	}
+	if r.requiresReason && reason == "" {
+		return ErrReasonRequired
+	}
+	if from == "REVIEW" && to == "VALIDATION" {
+		submission, err := db.LastTransition(docId, pageNum, "REVIEW")
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && ownSubmission(submission.User, user) {
+			return fmt.Errorf("%w: pages cannot be approved by who submitted them", ErrNotAllowed)
+		}
+	}
+	if from == "TRAINING" && to == "REVIEW" {
+		return checkAnnotationSets(docId, pageNum)
+	}
+	return nil
+}
+
+// ownSubmission tells whether the user approves a page they submitted. Anonymous users cannot be told apart, their
+// submissions are not theirs.
+func ownSubmission(submitter string, user string) bool {
+	return submitter == user && user != models.AnonymousUser
+}
+
+// Set moves a page to a status without checking the workflow, used when annotations are imported.
+func Set(docId int64, pageNum int, to string, user string, reason string) error {
+	from, err := db.GetPageStatus(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	err = db.ChangeStatus(docId, pageNum, from, to, user, reason)
+	if err != nil {
+		return err
+	}
+	afterChange(docId, pageNum, from, to, user)
+	return nil
+}
+
+// checkAnnotationSets fails until every annotator of an agreement page has saved their annotation.
+func checkAnnotationSets(docId int64, pageNum int) error {
+	required, err := db.RequiredAnnotators(docId, pageNum)
+	if err != nil || required == 0 {
+		return err
+	}
+	sets, err := db.ListAnnotationSets(docId, pageNum)
+	if err != nil {
+		return err
+	}
+	if len(sets) < required {
+		return fmt.Errorf("%w: waiting for %d more annotations", ErrNotAllowed, required-len(sets))
+	}
+	return nil
+}
+
+func afterChange(docId int64, pageNum int, from string, to string, user string) {
+	if from == "TRAINING" {
+		err := db.ReleasePage(docId, pageNum, "")
+		if err != nil {
+			log.Println(fmt.Sprintf("Could not release page: %v", err))
+		}
+	}
+	if from == "REVIEW" && to == "TRAINING" {
+		submission, err := db.LastTransition(docId, pageNum, "REVIEW")
+		if err == nil {
+			err = db.AssignPage(docId, pageNum, submission.User)
+		}
+		if err != nil {
+			log.Println(fmt.Sprintf("Could not assign rejected page: %v", err))
+		}
+	}
+	if to == "VALIDATION" {
+		webhooks.Publish(webhooks.EventPageValidated, webhooks.PageEvent{DocumentId: docId, PageNum: pageNum, User: user})
+	}
+}
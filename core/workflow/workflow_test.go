@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"slices"
+	"smart-docs/core/models"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		status   string
+		reviewer bool
+		want     []string
+	}{
+		{"PREDICTION", false, []string{"TRAINING"}},
+		{"TRAINING", false, []string{"PREDICTION", "REVIEW"}},
+		{"REVIEW", false, nil},
+		{"REVIEW", true, []string{"VALIDATION", "TRAINING"}},
+		{"VALIDATION", false, nil},
+		{"VALIDATION", true, []string{"TRAINING"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, transition := range Allowed(test.status, test.reviewer) {
+			got = append(got, transition.To)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("Allowed(%q, %v) = %v, want %v", test.status, test.reviewer, got, test.want)
+		}
+	}
+}
+
+func TestOwnSubmission(t *testing.T) {
+	tests := []struct {
+		submitter string
+		user      string
+		want      bool
+	}{
+		{"ana", "ana", true},
+		{"ana", "bob", false},
+		{models.AnonymousUser, "bob", false},
+		{models.AnonymousUser, models.AnonymousUser, false},
+	}
+	for _, test := range tests {
+		if got := ownSubmission(test.submitter, test.user); got != test.want {
+			t.Errorf("ownSubmission(%q, %q) = %v, want %v", test.submitter, test.user, got, test.want)
+		}
+	}
+}
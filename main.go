@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"smart-docs/core/db"
+	"smart-docs/core/evaluation"
+	"smart-docs/core/importer"
 	"smart-docs/core/server"
 
 	"github.com/joho/godotenv"
@@ -25,6 +28,22 @@ func main() {
 		}
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		db.New()
+		if err := importer.RunCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		db.New()
+		if err := evaluation.RunCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Evaluation failed: %v", err)
+		}
+		return
+	}
+
 	newServer := server.NewServer()
 	err := newServer.ListenAndServe()
 	if err != nil {